@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Outcomes of audited requests
+const (
+	OutcomeSuccess = "success"
+	// OutcomeDenied is the outcome of requests which were not authenticated or not authorized
+	OutcomeDenied  = "denied"
+	OutcomeFailure = "failure"
+)
+
+// Event records who performed an action, what it targeted, and how it turned out
+type Event struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// User is the user or service account who made the request; it is empty if the request was not authenticated
+	User           string `json:"user,omitempty"`
+	ServiceAccount bool   `json:"service_account,omitempty"`
+	Authenticator  string `json:"authenticator,omitempty"`
+	TokenID        string `json:"token_id,omitempty"`
+	// Action describes the request, e.g. 'DELETE /repository/:owner/:name' or 'registry.push'
+	Action string `json:"action"`
+	// Target is the path of the request, e.g. '/repository/carbon/api'
+	Target     string `json:"target"`
+	Owner      string `json:"owner,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Status     int    `json:"status"`
+	Outcome    string `json:"outcome"`
+	SourceIP   string `json:"source_ip"`
+}
+
+// NewEvent returns an event with a random id which occurred at t
+func NewEvent(t time.Time) (Event, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return Event{}, err
+	}
+
+	return Event{ID: hex.EncodeToString(b), Time: t.UTC()}, nil
+}
+
+// OutcomeOf returns the outcome of a request which received the http status
+func OutcomeOf(status int) string {
+	switch {
+	case status < 400:
+		return OutcomeSuccess
+	case status == 401 || status == 403:
+		return OutcomeDenied
+	default:
+		return OutcomeFailure
+	}
+}
+
+// Filter selects the events which occurred between Start and End.
+// Empty fields match every event.
+type Filter struct {
+	User       string
+	Owner      string
+	Repository string
+	Start      time.Time
+	End        time.Time
+	// Limit is the maximum number of events to return
+	Limit int
+}
+
+// Matches returns true if the event was selected by the filter
+func (f Filter) Matches(e Event) bool {
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+
+	if f.Owner != "" && e.Owner != f.Owner {
+		return false
+	}
+
+	if f.Repository != "" && e.Repository != f.Repository {
+		return false
+	}
+
+	return !e.Time.Before(f.Start) && !e.Time.After(f.End)
+}
+
+// Sink stores audit events
+type Sink interface {
+	Record(event Event) error
+	// Query returns up to filter.Limit events selected by the filter, newest first
+	Query(filter Filter) ([]Event, error)
+}
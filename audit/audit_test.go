@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutcomeOf(t *testing.T) {
+	cases := map[int]string{
+		200: OutcomeSuccess,
+		202: OutcomeSuccess,
+		401: OutcomeDenied,
+		403: OutcomeDenied,
+		404: OutcomeFailure,
+		500: OutcomeFailure,
+	}
+
+	for status, expected := range cases {
+		if v, want := OutcomeOf(status), expected; v != want {
+			t.Errorf("case %d: outcome was '%v', expected '%v'", status, v, want)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	now := time.Unix(1500000000, 0).UTC()
+	event := Event{
+		Time:       now,
+		User:       "john.doe",
+		Owner:      "carbon",
+		Repository: "carbon/api",
+	}
+
+	filter := Filter{Start: now.Add(-time.Hour), End: now}
+	cases := []struct {
+		Name     string
+		Filter   Filter
+		Expected bool
+	}{
+		{"time range", filter, true},
+		{"user", Filter{User: "john.doe", Start: filter.Start, End: filter.End}, true},
+		{"other user", Filter{User: "jane.doe", Start: filter.Start, End: filter.End}, false},
+		{"owner", Filter{Owner: "carbon", Start: filter.Start, End: filter.End}, true},
+		{"other owner", Filter{Owner: "other", Start: filter.Start, End: filter.End}, false},
+		{"repository", Filter{Repository: "carbon/api", Start: filter.Start, End: filter.End}, true},
+		{"other repository", Filter{Repository: "carbon/web", Start: filter.Start, End: filter.End}, false},
+		{"before start", Filter{Start: now.Add(time.Second), End: now.Add(time.Hour)}, false},
+		{"after end", Filter{Start: now.Add(-time.Hour), End: now.Add(-time.Second)}, false},
+	}
+
+	for _, c := range cases {
+		if v, want := c.Filter.Matches(event), c.Expected; v != want {
+			t.Errorf("case %s: result was %v, expected %v", c.Name, v, want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"fmt"
+	"log"
+)
+
+const DefaultBufferSize = 1000
+
+// BufferedSink records events in the underlying sink in the background, so requests do not wait for it.
+// Events which do not fit in the buffer are not recorded.
+type BufferedSink struct {
+	Sink
+	events chan Event
+}
+
+func NewBufferedSink(sink Sink, size int) *BufferedSink {
+	return &BufferedSink{
+		Sink:   sink,
+		events: make(chan Event, size),
+	}
+}
+
+// Record adds the event to the buffer; it returns an error if the buffer is full
+func (b *BufferedSink) Record(event Event) error {
+	select {
+	case b.events <- event:
+		return nil
+	default:
+		return fmt.Errorf("Audit buffer is full")
+	}
+}
+
+// Run records the buffered events in the underlying sink; it does not return
+func (b *BufferedSink) Run() {
+	for event := range b.events {
+		if err := b.Sink.Record(event); err != nil {
+			log.Printf("[ERROR] Failed to record audit event for %s by '%s': %v", event.Target, event.User, err)
+		}
+	}
+}
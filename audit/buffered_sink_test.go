@@ -0,0 +1,43 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/audit"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferedSinkRecordsInBackground(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSink := mock.NewMockSink(ctrl)
+	target := audit.NewBufferedSink(mockSink, 1)
+
+	recorded := make(chan audit.Event)
+	mockSink.EXPECT().
+		Record(gomock.Any()).
+		Do(func(event audit.Event) { recorded <- event }).
+		Return(nil)
+
+	// events are buffered until Run records them
+	if err := target.Record(audit.Event{ID: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := target.Record(audit.Event{ID: "second"}); err == nil {
+		t.Fatal("Error expected when the buffer is full")
+	}
+
+	go target.Run()
+
+	select {
+	case event := <-recorded:
+		assert.Equal(t, "first", event.ID)
+	case <-time.After(time.Second):
+		t.Fatal("Event was not recorded")
+	}
+}
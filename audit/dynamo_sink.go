@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const (
+	dateFormat = "2006-01-02"
+	// timeFormat has a fixed width so event keys sort by time
+	timeFormat = "2006-01-02T15:04:05.000000000Z"
+)
+
+// DynamoSink stores each event as an item keyed by the 'Date' (UTC) it occurred on
+// and an 'Event' key made of its time and id, so the events of a day can be queried by time.
+// Empty attributes are omitted since dynamodb does not allow empty strings.
+type DynamoSink struct {
+	table    string
+	dynamodb dynamodbiface.DynamoDBAPI
+}
+
+func NewDynamoSink(table string, dynamodb dynamodbiface.DynamoDBAPI) *DynamoSink {
+	return &DynamoSink{
+		table:    table,
+		dynamodb: dynamodb,
+	}
+}
+
+func (d *DynamoSink) Record(event Event) error {
+	item := map[string]*dynamodb.AttributeValue{
+		"Date": {
+			S: aws.String(event.Time.UTC().Format(dateFormat)),
+		},
+		"Event": {
+			S: aws.String(eventKey(event.Time, event.ID)),
+		},
+		"Status": {
+			N: aws.String(strconv.Itoa(event.Status)),
+		},
+		"ServiceAccount": {
+			BOOL: aws.Bool(event.ServiceAccount),
+		},
+	}
+
+	attributes := map[string]string{
+		"User":          event.User,
+		"Authenticator": event.Authenticator,
+		"TokenID":       event.TokenID,
+		"Action":        event.Action,
+		"Target":        event.Target,
+		"Owner":         event.Owner,
+		"Repository":    event.Repository,
+		"Outcome":       event.Outcome,
+		"SourceIP":      event.SourceIP,
+	}
+
+	for name, value := range attributes {
+		if value != "" {
+			item[name] = &dynamodb.AttributeValue{S: aws.String(value)}
+		}
+	}
+
+	input := &dynamodb.PutItemInput{}
+	input.SetTableName(d.table)
+	input.SetItem(item)
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := d.dynamodb.PutItem(input); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Query queries each day in the filter's time range, starting with the most recent
+func (d *DynamoSink) Query(filter Filter) ([]Event, error) {
+	events := []Event{}
+	start := filter.Start.UTC()
+	end := filter.End.UTC()
+
+	for day := end.Truncate(time.Hour * 24); !day.Before(start.Truncate(time.Hour * 24)); day = day.Add(-time.Hour * 24) {
+		input := d.newQueryInput(day, filter)
+		if err := input.Validate(); err != nil {
+			return nil, err
+		}
+
+		fn := func(output *dynamodb.QueryOutput, lastPage bool) bool {
+			for _, item := range output.Items {
+				events = append(events, itemToEvent(item))
+				if filter.Limit > 0 && len(events) >= filter.Limit {
+					return false
+				}
+			}
+
+			return !lastPage
+		}
+
+		if err := d.dynamodb.QueryPages(input, fn); err != nil {
+			return nil, err
+		}
+
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+func (d *DynamoSink) newQueryInput(day time.Time, filter Filter) *dynamodb.QueryInput {
+	// 'Date', 'User', and 'Owner' are reserved words in dynamodb expressions
+	names := map[string]*string{
+		"#Date":  aws.String("Date"),
+		"#Event": aws.String("Event"),
+	}
+
+	values := map[string]*dynamodb.AttributeValue{
+		":date":  {S: aws.String(day.Format(dateFormat))},
+		":start": {S: aws.String(eventKey(filter.Start, ""))},
+		// the separator sorts after every key at the end time
+		":end": {S: aws.String(eventKey(filter.End, "~"))},
+	}
+
+	conditions := []string{}
+	attributes := [][2]string{{"User", filter.User}, {"Owner", filter.Owner}, {"Repository", filter.Repository}}
+	for _, attribute := range attributes {
+		name, value := attribute[0], attribute[1]
+		if value != "" {
+			names["#"+name] = aws.String(name)
+			values[":"+strings.ToLower(name)] = &dynamodb.AttributeValue{S: aws.String(value)}
+			conditions = append(conditions, "#"+name+" = :"+strings.ToLower(name))
+		}
+	}
+
+	input := &dynamodb.QueryInput{}
+	input.SetTableName(d.table)
+	input.SetKeyConditionExpression("#Date = :date AND #Event BETWEEN :start AND :end")
+	input.SetExpressionAttributeNames(names)
+	input.SetExpressionAttributeValues(values)
+	input.SetScanIndexForward(false)
+	if len(conditions) > 0 {
+		input.SetFilterExpression(strings.Join(conditions, " AND "))
+	}
+
+	return input
+}
+
+// eventKey returns the key of an event, e.g. '2018-01-01T00:00:00.000000000Z/<id>'
+func eventKey(t time.Time, id string) string {
+	return t.UTC().Format(timeFormat) + "/" + id
+}
+
+func itemToEvent(item map[string]*dynamodb.AttributeValue) Event {
+	event := Event{
+		User:           aws.StringValue(stringAttribute(item, "User")),
+		ServiceAccount: item["ServiceAccount"] != nil && aws.BoolValue(item["ServiceAccount"].BOOL),
+		Authenticator:  aws.StringValue(stringAttribute(item, "Authenticator")),
+		TokenID:        aws.StringValue(stringAttribute(item, "TokenID")),
+		Action:         aws.StringValue(stringAttribute(item, "Action")),
+		Target:         aws.StringValue(stringAttribute(item, "Target")),
+		Owner:          aws.StringValue(stringAttribute(item, "Owner")),
+		Repository:     aws.StringValue(stringAttribute(item, "Repository")),
+		Outcome:        aws.StringValue(stringAttribute(item, "Outcome")),
+		SourceIP:       aws.StringValue(stringAttribute(item, "SourceIP")),
+	}
+
+	if v, ok := item["Event"]; ok {
+		if parts := strings.SplitN(aws.StringValue(v.S), "/", 2); len(parts) == 2 {
+			event.Time, _ = time.Parse(timeFormat, parts[0])
+			event.ID = parts[1]
+		}
+	}
+
+	if v, ok := item["Status"]; ok {
+		event.Status, _ = strconv.Atoi(aws.StringValue(v.N))
+	}
+
+	return event
+}
+
+func stringAttribute(item map[string]*dynamodb.AttributeValue, name string) *string {
+	if v, ok := item[name]; ok {
+		return v.S
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/audit"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamoRecord(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := audit.NewDynamoSink("table", mockDynamoDB)
+
+	event := audit.Event{
+		ID:       "id",
+		Time:     time.Date(2018, 1, 2, 3, 4, 5, 6, time.UTC),
+		User:     "john.doe",
+		Action:   "registry.push",
+		Target:   "/v2/carbon/api/manifests/latest",
+		Status:   201,
+		Outcome:  audit.OutcomeSuccess,
+		SourceIP: "10.0.0.1",
+	}
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "2018-01-02", aws.StringValue(input.Item["Date"].S))
+		assert.Equal(t, "2018-01-02T03:04:05.000000006Z/id", aws.StringValue(input.Item["Event"].S))
+		assert.Equal(t, "john.doe", aws.StringValue(input.Item["User"].S))
+		assert.Equal(t, "201", aws.StringValue(input.Item["Status"].N))
+
+		// dynamodb does not allow empty strings
+		assert.NotContains(t, input.Item, "Owner")
+		assert.NotContains(t, input.Item, "TokenID")
+	}
+
+	mockDynamoDB.EXPECT().
+		PutItem(gomock.Any()).
+		Do(validatePutItemInput).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	if err := target.Record(event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamoQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := audit.NewDynamoSink("table", mockDynamoDB)
+
+	filter := audit.Filter{
+		User:  "john.doe",
+		Start: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+		End:   time.Date(2018, 1, 2, 12, 0, 0, 0, time.UTC),
+		Limit: 2,
+	}
+
+	dates := []string{}
+	validateQueryInput := func(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.False(t, aws.BoolValue(input.ScanIndexForward))
+		assert.Equal(t, "#User = :user", aws.StringValue(input.FilterExpression))
+		assert.Equal(t, "john.doe", aws.StringValue(input.ExpressionAttributeValues[":user"].S))
+		assert.Equal(t, "2018-01-01T12:00:00.000000000Z/", aws.StringValue(input.ExpressionAttributeValues[":start"].S))
+		assert.Equal(t, "2018-01-02T12:00:00.000000000Z/~", aws.StringValue(input.ExpressionAttributeValues[":end"].S))
+
+		date := aws.StringValue(input.ExpressionAttributeValues[":date"].S)
+		dates = append(dates, date)
+
+		item := map[string]*dynamodb.AttributeValue{
+			"Date":    {S: aws.String(date)},
+			"Event":   {S: aws.String(date + "T01:00:00.000000000Z/id")},
+			"User":    {S: aws.String("john.doe")},
+			"Status":  {N: aws.String("200")},
+			"Outcome": {S: aws.String(audit.OutcomeSuccess)},
+		}
+
+		fn(&dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{item}}, true)
+	}
+
+	mockDynamoDB.EXPECT().
+		QueryPages(gomock.Any(), gomock.Any()).
+		Do(validateQueryInput).
+		Return(nil).
+		Times(2)
+
+	events, err := target.Query(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the most recent day is queried first
+	assert.Equal(t, []string{"2018-01-02", "2018-01-01"}, dates)
+
+	expected := []audit.Event{
+		{ID: "id", Time: time.Date(2018, 1, 2, 1, 0, 0, 0, time.UTC), User: "john.doe", Status: 200, Outcome: audit.OutcomeSuccess},
+		{ID: "id", Time: time.Date(2018, 1, 1, 1, 0, 0, 0, time.UTC), User: "john.doe", Status: 200, Outcome: audit.OutcomeSuccess},
+	}
+
+	assert.Equal(t, expected, events)
+}
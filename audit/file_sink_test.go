@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := NewFileSink(filepath.Join(dir, "audit.log"))
+
+	// the file does not exist until the first event is recorded
+	events, err := target.Query(Filter{End: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, events, 0)
+
+	now := time.Unix(1500000000, 0).UTC()
+	recorded := []Event{
+		{ID: "1", Time: now.Add(-time.Minute * 2), User: "john.doe", Action: "POST /token", Outcome: OutcomeSuccess},
+		{ID: "2", Time: now.Add(-time.Minute), User: "jane.doe", Action: "DELETE /repository/:owner/:name", Repository: "carbon/api"},
+		{ID: "3", Time: now, User: "john.doe", Action: "registry.push", Repository: "carbon/api"},
+	}
+
+	for _, event := range recorded {
+		if err := target.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err = target.Query(Filter{User: "john.doe", Start: now.Add(-time.Hour), End: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// events are returned newest first
+	assert.Equal(t, []Event{recorded[2], recorded[0]}, events)
+
+	events, err = target.Query(Filter{Start: now.Add(-time.Hour), End: now, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []Event{recorded[2]}, events)
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Admins lists the users and groups with the admin role.
+// Admins can manage account access and lockouts, delete the repositories and images of any owner,
+// and manage the members of any owner.
+// Each entry is qualified by the authenticator the user or group must come from, e.g. 'ldap:john.doe',
+// so a user of one authenticator cannot become an admin by sharing a name with an admin of another.
+type Admins struct {
+	Users  []string
+	Groups []string
+}
+
+// NewAdmins returns an error if any entry is not of the form '<authenticator>:<name>'
+func NewAdmins(users, groups []string) (*Admins, error) {
+	for _, entry := range append(append([]string{}, users...), groups...) {
+		if err := validateAdminEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Admins{
+		Users:  users,
+		Groups: groups,
+	}, nil
+}
+
+// IsAdmin returns true if the principal's username or one of its groups is listed as an admin
+// for the authenticator which accepted its credentials.
+// No one is an admin if a is nil.
+func (a *Admins) IsAdmin(p *Principal) bool {
+	if a == nil {
+		return false
+	}
+
+	if p.Username != "" && contains(a.Users, adminEntry(p.Authenticator, p.Username)) {
+		return true
+	}
+
+	for _, group := range p.Groups {
+		if contains(a.Groups, adminEntry(p.Authenticator, group)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func adminEntry(authenticator, name string) string {
+	return authenticator + ":" + name
+}
+
+func validateAdminEntry(entry string) error {
+	parts := strings.SplitN(entry, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return fmt.Errorf("Admin '%s' must be qualified by its authenticator, e.g. '%s'", entry, adminEntry(AuthenticatorLDAP, entry))
+	}
+
+	switch parts[0] {
+	case AuthenticatorToken, AuthenticatorLDAP, AuthenticatorAuth0, AuthenticatorHtpasswd,
+		AuthenticatorOIDC, AuthenticatorClientCert, AuthenticatorAWSIAM:
+		return nil
+	default:
+		return fmt.Errorf("Admin '%s' has an unknown authenticator '%s'", entry, parts[0])
+	}
+}
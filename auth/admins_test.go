@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestAdminsIsAdmin(t *testing.T) {
+	admins, err := NewAdmins([]string{"ldap:admin", "token:admin"}, []string{"auth0:admins"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name      string
+		Admins    *Admins
+		Principal *Principal
+		Expected  bool
+	}{
+		{"user", admins, &Principal{Username: "admin", Authenticator: AuthenticatorLDAP}, true},
+		{"token", admins, &Principal{Username: "admin", Authenticator: AuthenticatorToken}, true},
+		{"group", admins, &Principal{Username: "user", Authenticator: AuthenticatorAuth0, Groups: []string{"users", "admins"}}, true},
+		{"neither", admins, &Principal{Username: "user", Authenticator: AuthenticatorLDAP, Groups: []string{"users"}}, false},
+		{"user from other authenticator", admins, &Principal{Username: "admin", Authenticator: AuthenticatorHtpasswd}, false},
+		{"group from other authenticator", admins, &Principal{Username: "user", Authenticator: AuthenticatorOIDC, Groups: []string{"admins"}}, false},
+		{"anonymous", admins, &Principal{Authenticator: AuthenticatorAnonymous}, false},
+		{"no admins", nil, &Principal{Username: "admin", Authenticator: AuthenticatorLDAP}, false},
+	}
+
+	for _, c := range cases {
+		if v, want := c.Admins.IsAdmin(c.Principal), c.Expected; v != want {
+			t.Errorf("case %s: is admin was '%v', expected '%v'", c.Name, v, want)
+		}
+	}
+}
+
+func TestNewAdminsRequiresAuthenticator(t *testing.T) {
+	cases := map[string][]string{
+		"unqualified":           {"admin"},
+		"unknown authenticator": {"other:admin"},
+		"no name":               {"ldap:"},
+	}
+
+	for name, users := range cases {
+		if _, err := NewAdmins(users, nil); err == nil {
+			t.Errorf("case %s: error expected", name)
+		}
+	}
+}
@@ -1,59 +1,110 @@
 package auth
 
 import (
+	"fmt"
 	"log"
 	"time"
 
+	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/zpatrick/rclient"
 )
 
+const passwordRealmGrantType = "http://auth0.com/oauth/grant-type/password-realm"
+
+// Auth0Authenticator authenticates users with the password-realm grant of an Auth0 connection.
+// The identity claims of the returned ID token are added to the user's principal.
 type Auth0Authenticator struct {
-	clientID   string
-	connection string
-	client     *rclient.RestClient
-	throttle   <-chan time.Time
+	clientID    string
+	connection  string
+	groupsClaim string
+	client      *rclient.RestClient
+	throttle    <-chan time.Time
 }
 
 type oauthReq struct {
-	ClientID   string `json:"client_id"`
-	Connection string `json:"connection"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	GrantType  string `json:"grant_type"`
-	Scope      string `json:"scope"`
+	ClientID  string `json:"client_id"`
+	Realm     string `json:"realm"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	GrantType string `json:"grant_type"`
+	Scope     string `json:"scope"`
+}
+
+type oauthResp struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
 }
 
-func NewAuth0Authenticator(domain, clientID, connection string, rateLimit time.Duration) *Auth0Authenticator {
+// NewAuth0Authenticator creates an Auth0Authenticator.
+// The user's groups are read from the groupsClaim of the ID token, which is usually a namespaced custom claim.
+func NewAuth0Authenticator(domain, clientID, connection, groupsClaim string, rateLimit time.Duration) *Auth0Authenticator {
 	return &Auth0Authenticator{
-		clientID:   clientID,
-		connection: connection,
-		client:     rclient.NewRestClient(domain),
-		throttle:   time.Tick(rateLimit),
+		clientID:    clientID,
+		connection:  connection,
+		groupsClaim: groupsClaim,
+		client:      rclient.NewRestClient(domain),
+		throttle:    time.Tick(rateLimit),
 	}
 }
 
-func (a *Auth0Authenticator) Authenticate(username, password string) (bool, error) {
+func (a *Auth0Authenticator) Authenticate(username, password string) (*Principal, bool, error) {
 	log.Printf("[DEBUG] Attempting to authenticate user '%s' through Auth0", username)
 
 	req := oauthReq{
-		ClientID:   a.clientID,
-		Connection: a.connection,
-		Username:   username,
-		Password:   password,
-		GrantType:  "password",
-		Scope:      "openid",
+		ClientID:  a.clientID,
+		Realm:     a.connection,
+		Username:  username,
+		Password:  password,
+		GrantType: passwordRealmGrantType,
+		Scope:     "openid profile email",
 	}
 
 	<-a.throttle
-	if err := a.client.Post("/oauth/ro", req, nil); err != nil {
-		if re, ok := err.(*rclient.ResponseError); ok && re.Response.StatusCode == 401 {
+	var resp oauthResp
+	if err := a.client.Post("/oauth/token", req, &resp); err != nil {
+		// auth0 responds with 403 when the credentials are invalid
+		if re, ok := err.(*rclient.ResponseError); ok && (re.Response.StatusCode == 401 || re.Response.StatusCode == 403) {
 			log.Printf("[DEBUG] User '%s' sent invalid Auth0 credentials", username)
-			return false, nil
+			return nil, false, nil
 		}
 
-		return false, err
+		return nil, false, err
+	}
+
+	principal, err := a.parseIDToken(username, resp.IDToken)
+	if err != nil {
+		return nil, false, err
 	}
 
 	log.Printf("[DEBUG] User '%s' sent valid Auth0 credentials", username)
-	return true, nil
+	return principal, true, nil
+}
+
+// parseIDToken reads the identity claims of an ID token into a principal.
+// The token was received directly from Auth0 over tls, so its signature is not verified.
+func (a *Auth0Authenticator) parseIDToken(username, idToken string) (*Principal, error) {
+	principal := NewPrincipal(username, AuthenticatorAuth0)
+	if idToken == "" {
+		return principal, nil
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(idToken, claims); err != nil {
+		return nil, fmt.Errorf("Failed to parse Auth0 ID token: %v", err)
+	}
+
+	principal.Email, _ = claims["email"].(string)
+	principal.Name, _ = claims["name"].(string)
+
+	if groups, ok := claims[a.groupsClaim].([]interface{}); ok {
+		for _, group := range groups {
+			if g, ok := group.(string); ok {
+				principal.Groups = append(principal.Groups, g)
+			}
+		}
+	}
+
+	return principal, nil
 }
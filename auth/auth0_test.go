@@ -4,38 +4,58 @@ import (
 	"net/http"
 	"testing"
 
+	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestAuth0AuthenticatorAuthenticate_ValidCreds(t *testing.T) {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, r.Method, "POST")
-		assert.Equal(t, r.URL.Path, "/oauth/ro")
+		assert.Equal(t, r.URL.Path, "/oauth/token")
 
 		var req oauthReq
 		Unmarshal(t, r, &req)
 
 		assert.Equal(t, req.Username, "valid username")
 		assert.Equal(t, req.Password, "valid password")
+		assert.Equal(t, req.GrantType, "http://auth0.com/oauth/grant-type/password-realm")
 
-		MarshalAndWrite(t, w, nil, 200)
+		claims := jwt.MapClaims{
+			"email":  "john.doe@example.com",
+			"name":   "John Doe",
+			"groups": []string{"developers", "admins"},
+		}
+
+		idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		MarshalAndWrite(t, w, oauthResp{IDToken: idToken}, 200)
 	}
 
 	auth0Authenticator, server := newAuth0AuthenticatorAndServer(handler)
 	defer server.Close()
 
-	valid, err := auth0Authenticator.Authenticate("valid username", "valid password")
+	principal, valid, err := auth0Authenticator.Authenticate("valid username", "valid password")
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	assert.Equal(t, valid, true)
+
+	expected := NewPrincipal("valid username", AuthenticatorAuth0)
+	expected.Email = "john.doe@example.com"
+	expected.Name = "John Doe"
+	expected.Groups = []string{"developers", "admins"}
+
+	assert.Equal(t, expected, principal)
 }
 
 func TestAuth0AuthenticatorAuthenticate_InvalidCreds(t *testing.T) {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, r.Method, "POST")
-		assert.Equal(t, r.URL.Path, "/oauth/ro")
+		assert.Equal(t, r.URL.Path, "/oauth/token")
 
 		var req oauthReq
 		Unmarshal(t, r, &req)
@@ -43,16 +63,17 @@ func TestAuth0AuthenticatorAuthenticate_InvalidCreds(t *testing.T) {
 		assert.Equal(t, req.Username, "invalid username")
 		assert.Equal(t, req.Password, "invalid password")
 
-		MarshalAndWrite(t, w, nil, 401)
+		MarshalAndWrite(t, w, nil, 403)
 	}
 
 	auth0Authenticator, server := newAuth0AuthenticatorAndServer(handler)
 	defer server.Close()
 
-	valid, err := auth0Authenticator.Authenticate("invalid username", "invalid password")
+	principal, valid, err := auth0Authenticator.Authenticate("invalid username", "invalid password")
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	assert.Equal(t, valid, false)
+	assert.Nil(t, principal)
 }
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAuthCacheSize       = 10000
+	DefaultAuthCacheValidTTL   = time.Minute * 15
+	DefaultAuthCacheInvalidTTL = time.Second * 30
+)
+
+// AuthCache is a size-bounded cache of authentication results.
+// When the cache is full, the least recently used entry is evicted.
+// Valid and invalid results expire after separate ttls, so failed logins are retried sooner.
+// Valid results may be tagged with the key of the token used to authenticate,
+// which allows them to be evicted with Invalidate as soon as the token is revoked.
+type AuthCache struct {
+	size       int
+	validTTL   time.Duration
+	invalidTTL time.Duration
+	mutex      sync.Mutex
+	entries    map[string]*list.Element
+	tags       map[string]map[string]bool
+	lru        *list.List
+	now        func() time.Time
+}
+
+type authCacheEntry struct {
+	key       string
+	tag       string
+	value     interface{}
+	valid     bool
+	expiresAt time.Time
+}
+
+func NewAuthCache(size int, validTTL, invalidTTL time.Duration) *AuthCache {
+	return &AuthCache{
+		size:       size,
+		validTTL:   validTTL,
+		invalidTTL: invalidTTL,
+		entries:    map[string]*list.Element{},
+		tags:       map[string]map[string]bool{},
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get returns the cached result for key.
+// The second return value is false if the cached result is invalid,
+// and the third return value is false if there is no unexpired result for key.
+func (a *AuthCache) Get(key string) (interface{}, bool, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	element, ok := a.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+
+	entry := element.Value.(*authCacheEntry)
+	if !a.now().Before(entry.expiresAt) {
+		a.remove(element)
+		return nil, false, false
+	}
+
+	a.lru.MoveToFront(element)
+	return entry.value, entry.valid, true
+}
+
+// SetValid caches a valid result for key.
+// If tag is not empty, the entry is evicted when tag is invalidated.
+// If expiresAt is not zero and comes before the valid ttl, the entry expires at expiresAt instead,
+// so credentials which expire are not honored from the cache after they expire.
+func (a *AuthCache) SetValid(key, tag string, value interface{}, expiresAt time.Time) {
+	entryExpiresAt := a.now().Add(a.validTTL)
+	if !expiresAt.IsZero() && expiresAt.Before(entryExpiresAt) {
+		entryExpiresAt = expiresAt
+	}
+
+	a.set(&authCacheEntry{
+		key:       key,
+		tag:       tag,
+		value:     value,
+		valid:     true,
+		expiresAt: entryExpiresAt,
+	})
+}
+
+// SetInvalid caches an invalid result for key
+func (a *AuthCache) SetInvalid(key string) {
+	a.set(&authCacheEntry{
+		key:       key,
+		expiresAt: a.now().Add(a.invalidTTL),
+	})
+}
+
+// Invalidate evicts each entry tagged with tag
+func (a *AuthCache) Invalidate(tag string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for key := range a.tags[tag] {
+		a.remove(a.entries[key])
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries which have not been evicted yet
+func (a *AuthCache) Len() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.lru.Len()
+}
+
+func (a *AuthCache) set(entry *authCacheEntry) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if element, ok := a.entries[entry.key]; ok {
+		a.remove(element)
+	}
+
+	a.entries[entry.key] = a.lru.PushFront(entry)
+	if entry.tag != "" {
+		if _, ok := a.tags[entry.tag]; !ok {
+			a.tags[entry.tag] = map[string]bool{}
+		}
+
+		a.tags[entry.tag][entry.key] = true
+	}
+
+	for a.lru.Len() > a.size {
+		a.remove(a.lru.Back())
+	}
+}
+
+func (a *AuthCache) remove(element *list.Element) {
+	entry := a.lru.Remove(element).(*authCacheEntry)
+	delete(a.entries, entry.key)
+
+	if keys, ok := a.tags[entry.tag]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(a.tags, entry.tag)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAuthCache(size int) (*AuthCache, *time.Time) {
+	now := time.Now()
+	cache := NewAuthCache(size, time.Minute, time.Second)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestAuthCacheExpiry(t *testing.T) {
+	cache, now := newTestAuthCache(10)
+	cache.SetValid("valid", "", "result", time.Time{})
+	cache.SetInvalid("invalid")
+
+	value, isValid, ok := cache.Get("valid")
+	assert.True(t, ok)
+	assert.True(t, isValid)
+	assert.Equal(t, "result", value)
+
+	_, isValid, ok = cache.Get("invalid")
+	assert.True(t, ok)
+	assert.False(t, isValid)
+
+	// invalid results expire before valid results
+	*now = now.Add(time.Second)
+	if _, _, ok := cache.Get("invalid"); ok {
+		t.Error("Invalid result did not expire")
+	}
+
+	if _, _, ok := cache.Get("valid"); !ok {
+		t.Error("Valid result expired early")
+	}
+
+	*now = now.Add(time.Minute)
+	if _, _, ok := cache.Get("valid"); ok {
+		t.Error("Valid result did not expire")
+	}
+
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestAuthCacheExpiresWithCredentials(t *testing.T) {
+	cache, now := newTestAuthCache(10)
+	cache.SetValid("expiring", "", "result", now.Add(time.Second*10))
+	cache.SetValid("lasting", "", "result", now.Add(time.Hour))
+
+	*now = now.Add(time.Second * 10)
+	if _, _, ok := cache.Get("expiring"); ok {
+		t.Error("Result did not expire with its credentials")
+	}
+
+	// credentials which outlive the valid ttl are still only cached for the ttl
+	if _, _, ok := cache.Get("lasting"); !ok {
+		t.Error("Valid result expired early")
+	}
+
+	*now = now.Add(time.Minute)
+	if _, _, ok := cache.Get("lasting"); ok {
+		t.Error("Valid result did not expire")
+	}
+}
+
+func TestAuthCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, _ := newTestAuthCache(2)
+	cache.SetValid("a", "", nil, time.Time{})
+	cache.SetValid("b", "", nil, time.Time{})
+
+	// reading 'a' makes 'b' the least recently used entry
+	cache.Get("a")
+	cache.SetInvalid("c")
+
+	assert.Equal(t, 2, cache.Len())
+
+	for key, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, _, ok := cache.Get(key); ok != expected {
+			t.Errorf("Entry '%s' cached was '%v', expected '%v'", key, ok, expected)
+		}
+	}
+}
+
+func TestAuthCacheInvalidate(t *testing.T) {
+	cache, _ := newTestAuthCache(10)
+	cache.SetValid("a", "token", nil, time.Time{})
+	cache.SetValid("b", "token", nil, time.Time{})
+	cache.SetValid("c", "other", nil, time.Time{})
+
+	cache.Invalidate("token")
+
+	for key, expected := range map[string]bool{"a": false, "b": false, "c": true} {
+		if _, _, ok := cache.Get(key); ok != expected {
+			t.Errorf("Entry '%s' cached was '%v', expected '%v'", key, ok, expected)
+		}
+	}
+
+	// replacing an entry removes it from its previous tag
+	cache.SetInvalid("c")
+	cache.Invalidate("other")
+	if _, _, ok := cache.Get("c"); !ok {
+		t.Error("Replaced entry was invalidated with its previous tag")
+	}
+}
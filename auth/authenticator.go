@@ -1,11 +1,37 @@
 package auth
 
+import "crypto/x509"
+
+// Authenticator authenticates basic auth credentials, returning the principal they belong to
 type Authenticator interface {
-	Authenticate(user, pass string) (bool, error)
+	Authenticate(user, pass string) (*Principal, bool, error)
 }
 
-type AuthenticatorFunc func(string, string) (bool, error)
+type AuthenticatorFunc func(string, string) (*Principal, bool, error)
 
-func (a AuthenticatorFunc) Authenticate(user, pass string) (bool, error) {
+func (a AuthenticatorFunc) Authenticate(user, pass string) (*Principal, bool, error) {
 	return a(user, pass)
 }
+
+// BearerAuthenticator authenticates bearer tokens, returning the principal the token was issued to
+type BearerAuthenticator interface {
+	AuthenticateBearer(token string) (*Principal, bool, error)
+}
+
+type BearerAuthenticatorFunc func(string) (*Principal, bool, error)
+
+func (b BearerAuthenticatorFunc) AuthenticateBearer(token string) (*Principal, bool, error) {
+	return b(token)
+}
+
+// CertificateAuthenticator authenticates tls client certificates, returning the principal the certificate was issued to.
+// The first certificate is the client's certificate; the others are intermediates the client sent along with it.
+type CertificateAuthenticator interface {
+	AuthenticateCertificate(certificates []*x509.Certificate) (*Principal, bool, error)
+}
+
+type CertificateAuthenticatorFunc func([]*x509.Certificate) (*Principal, bool, error)
+
+func (c CertificateAuthenticatorFunc) AuthenticateCertificate(certificates []*x509.Certificate) (*Principal, bool, error) {
+	return c(certificates)
+}
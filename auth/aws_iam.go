@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	// AWSIAMTokenPrefix identifies passwords which are presigned sts:GetCallerIdentity requests
+	AWSIAMTokenPrefix = "aws-iam-v1."
+	// AWSIAMAudienceHeader is the signed header which binds presigned requests to a d.ims.io deployment,
+	// so requests presigned for other services cannot be replayed against d.ims.io
+	AWSIAMAudienceHeader = "x-dims-io-id"
+	// maxAWSIAMTokenExpiry is the longest expiry allowed for presigned requests
+	maxAWSIAMTokenExpiry = time.Minute * 15
+)
+
+// the query parameters of a presigned sts:GetCallerIdentity request; other parameters are not allowed
+var awsIAMTokenParameters = map[string]bool{
+	"Action":               true,
+	"Version":              true,
+	"X-Amz-Algorithm":      true,
+	"X-Amz-Credential":     true,
+	"X-Amz-Date":           true,
+	"X-Amz-Expires":        true,
+	"X-Amz-Security-Token": true,
+	"X-Amz-SignedHeaders":  true,
+	"X-Amz-Signature":      true,
+}
+
+type callerIdentity struct {
+	Account string `json:"Account"`
+	Arn     string `json:"Arn"`
+	UserID  string `json:"UserId"`
+}
+
+type getCallerIdentityResp struct {
+	GetCallerIdentityResponse struct {
+		GetCallerIdentityResult callerIdentity `json:"GetCallerIdentityResult"`
+	} `json:"GetCallerIdentityResponse"`
+}
+
+// AWSIAMAuthenticator authenticates aws iam identities, in the style of aws-iam-authenticator.
+// The password is AWSIAMTokenPrefix followed by the base64url encoded url of an sts:GetCallerIdentity request,
+// presigned with the AWSIAMAudienceHeader set to the authenticator's audience.
+// The request is sent to sts, and the identity it returns must belong to one of the allowed accounts or roles.
+// The username of the principal is the identity's arn; assumed roles use the arn of their role,
+// e.g. 'arn:aws:iam::123456789012:role/builder', and their session name is used as the principal's name.
+type AWSIAMAuthenticator struct {
+	endpoint *url.URL
+	audience string
+	accounts []string
+	roles    []string
+	client   *http.Client
+}
+
+// NewAWSIAMAuthenticator creates an AWSIAMAuthenticator which sends requests to the sts endpoint, e.g. 'https://sts.amazonaws.com'.
+// Identities are allowed if their account id is in accounts, or their arn is in roles.
+// Role arns must not include a path, since it is not part of the arn of assumed roles.
+func NewAWSIAMAuthenticator(endpoint, audience string, accounts, roles []string) (*AWSIAMAuthenticator, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid STS endpoint '%s': %v", endpoint, err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid STS endpoint '%s': the scheme and host are required", endpoint)
+	}
+
+	return &AWSIAMAuthenticator{
+		endpoint: u,
+		audience: audience,
+		accounts: accounts,
+		roles:    roles,
+		client:   &http.Client{Timeout: time.Second * 10},
+	}, nil
+}
+
+func (a *AWSIAMAuthenticator) Authenticate(user, pass string) (*Principal, bool, error) {
+	if !strings.HasPrefix(pass, AWSIAMTokenPrefix) {
+		return nil, false, nil
+	}
+
+	log.Printf("[DEBUG] Attempting to authenticate user '%s' through AWS IAM", user)
+
+	query, err := a.parseToken(pass)
+	if err != nil {
+		log.Printf("[DEBUG] User '%s' sent a malformed AWS IAM token: %v", user, err)
+		return nil, false, nil
+	}
+
+	identity, ok, err := a.getCallerIdentity(query)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !ok {
+		log.Printf("[DEBUG] User '%s' sent an AWS IAM token which was rejected by STS", user)
+		return nil, false, nil
+	}
+
+	username, session, err := canonicalIdentityARN(identity.Arn)
+	if err != nil {
+		log.Printf("[DEBUG] User '%s' sent an AWS IAM token for an unsupported identity: %v", user, err)
+		return nil, false, nil
+	}
+
+	if !contains(a.accounts, identity.Account) && !contains(a.roles, username) {
+		log.Printf("[WARN] Denying AWS IAM identity '%s' which is not in an allowed account or role", username)
+		return nil, false, nil
+	}
+
+	principal := NewPrincipal(username, AuthenticatorAWSIAM)
+	principal.Name = session
+
+	log.Printf("[DEBUG] User '%s' sent a valid AWS IAM token for '%s'", user, username)
+	return principal, true, nil
+}
+
+// parseToken returns the query of the presigned request in the token.
+// The request is only ever sent to the authenticator's endpoint, so tokens cannot direct requests elsewhere.
+func (a *AWSIAMAuthenticator) parseToken(token string) (url.Values, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, AWSIAMTokenPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(string(decoded))
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(u.Host, a.endpoint.Host) {
+		return nil, fmt.Errorf("request is for host '%s', expected '%s'", u.Host, a.endpoint.Host)
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("request has unexpected path '%s'", u.Path)
+	}
+
+	query := u.Query()
+	for param := range query {
+		if !awsIAMTokenParameters[param] {
+			return nil, fmt.Errorf("request has unexpected parameter '%s'", param)
+		}
+	}
+
+	if v := query.Get("Action"); v != "GetCallerIdentity" {
+		return nil, fmt.Errorf("request is for action '%s', expected 'GetCallerIdentity'", v)
+	}
+
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	if !contains(signedHeaders, AWSIAMAudienceHeader) {
+		return nil, fmt.Errorf("request does not sign the '%s' header", AWSIAMAudienceHeader)
+	}
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 || time.Duration(expires)*time.Second > maxAWSIAMTokenExpiry {
+		return nil, fmt.Errorf("request must expire within %v", maxAWSIAMTokenExpiry)
+	}
+
+	return query, nil
+}
+
+// getCallerIdentity sends the presigned request to sts.
+// Sts responds with 403 when the signature is invalid or has expired.
+func (a *AWSIAMAuthenticator) getCallerIdentity(query url.Values) (*callerIdentity, bool, error) {
+	u := *a.endpoint
+	u.Path = "/"
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(AWSIAMAudienceHeader, a.audience)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to call STS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case resp.StatusCode == 400 || resp.StatusCode == 403:
+		return nil, false, nil
+	case resp.StatusCode != 200:
+		return nil, false, fmt.Errorf("STS responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	var r getCallerIdentityResp
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, false, fmt.Errorf("Failed to parse STS response: %v", err)
+	}
+
+	return &r.GetCallerIdentityResponse.GetCallerIdentityResult, true, nil
+}
+
+// canonicalIdentityARN returns the arn of the iam user or role of an identity, and the name of its session if it assumed a role.
+// The arns of assumed roles, e.g. 'arn:aws:sts::123456789012:assumed-role/builder/session',
+// are converted to the arn of their role, e.g. 'arn:aws:iam::123456789012:role/builder'.
+func canonicalIdentityARN(identityARN string) (string, string, error) {
+	parsed, err := arn.Parse(identityARN)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.Split(parsed.Resource, "/")
+	switch {
+	case parsed.Service == "iam" && (parts[0] == "user" || parts[0] == "role") && len(parts) >= 2:
+		return identityARN, "", nil
+	case parsed.Service == "sts" && parts[0] == "assumed-role" && len(parts) == 3:
+		role := arn.ARN{
+			Partition: parsed.Partition,
+			Service:   "iam",
+			AccountID: parsed.AccountID,
+			Resource:  "role/" + parts[1],
+		}
+
+		return role.String(), parts[2], nil
+	default:
+		return "", "", fmt.Errorf("arn '%s' is not an iam user or assumed role", identityARN)
+	}
+}
+
+// NewAWSIAMToken presigns an sts:GetCallerIdentity request with the credentials of the client,
+// returning a token which can be used as the password for an AWSIAMAuthenticator with the specified audience.
+func NewAWSIAMToken(client *sts.STS, audience string) (string, error) {
+	req, _ := client.GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	req.HTTPRequest.Header.Set(AWSIAMAudienceHeader, audience)
+
+	presigned, err := req.Presign(maxAWSIAMTokenExpiry)
+	if err != nil {
+		return "", err
+	}
+
+	return AWSIAMTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presigned)), nil
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSTSServer returns a stand-in for sts which returns the identity of the access key the request was signed with.
+// Requests signed with unknown access keys are rejected, as sts rejects requests with invalid signatures.
+func newTestSTSServer(t *testing.T, identities map[string]callerIdentity) *httptest.Server {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "GetCallerIdentity", r.URL.Query().Get("Action"))
+		assert.Equal(t, "d.ims.io", r.Header.Get(AWSIAMAudienceHeader))
+
+		accessKey := strings.Split(r.URL.Query().Get("X-Amz-Credential"), "/")[0]
+		identity, ok := identities[accessKey]
+		if !ok {
+			MarshalAndWrite(t, w, map[string]string{"Code": "SignatureDoesNotMatch"}, 403)
+			return
+		}
+
+		var resp getCallerIdentityResp
+		resp.GetCallerIdentityResponse.GetCallerIdentityResult = identity
+		MarshalAndWrite(t, w, resp, 200)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(handler))
+}
+
+func newTestAWSIAMToken(t *testing.T, endpoint, accessKey, audience string) string {
+	config := &aws.Config{
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(endpoint),
+		Credentials: credentials.NewStaticCredentials(accessKey, "secret", ""),
+	}
+
+	token, err := NewAWSIAMToken(sts.New(session.Must(session.NewSession(config))), audience)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestAWSIAMAuthenticator(t *testing.T) {
+	identities := map[string]callerIdentity{
+		"builder":  {Account: "111111111111", Arn: "arn:aws:sts::111111111111:assumed-role/builder/i-0123456789"},
+		"deployer": {Account: "222222222222", Arn: "arn:aws:iam::222222222222:user/deployer"},
+		"other":    {Account: "111111111111", Arn: "arn:aws:sts::111111111111:assumed-role/other/session"},
+	}
+
+	server := newTestSTSServer(t, identities)
+	defer server.Close()
+
+	target, err := NewAWSIAMAuthenticator(server.URL, "d.ims.io", []string{"222222222222"}, []string{"arn:aws:iam::111111111111:role/builder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name     string
+		Password string
+		Expected string
+	}{
+		{"allowed role", newTestAWSIAMToken(t, server.URL, "builder", "d.ims.io"), "arn:aws:iam::111111111111:role/builder"},
+		{"allowed account", newTestAWSIAMToken(t, server.URL, "deployer", "d.ims.io"), "arn:aws:iam::222222222222:user/deployer"},
+		{"role not allowed", newTestAWSIAMToken(t, server.URL, "other", "d.ims.io"), ""},
+		{"rejected by sts", newTestAWSIAMToken(t, server.URL, "unknown", "d.ims.io"), ""},
+		{"other host", newTestAWSIAMToken(t, "https://sts.example.com", "builder", "d.ims.io"), ""},
+		{"malformed", AWSIAMTokenPrefix + "!", ""},
+		{"not a token", "password", ""},
+	}
+
+	for _, c := range cases {
+		principal, ok, err := target.Authenticate("aws", c.Password)
+		if err != nil {
+			t.Fatalf("case %s: %v", c.Name, err)
+		}
+
+		if v, want := ok, c.Expected != ""; v != want {
+			t.Errorf("case %s: result was %v, expected %v", c.Name, v, want)
+			continue
+		}
+
+		if ok {
+			assert.Equal(t, c.Expected, principal.Username)
+			assert.Equal(t, AuthenticatorAWSIAM, principal.Authenticator)
+		}
+	}
+}
+
+func TestAWSIAMAuthenticatorSessionName(t *testing.T) {
+	server := newTestSTSServer(t, map[string]callerIdentity{
+		"builder": {Account: "111111111111", Arn: "arn:aws:sts::111111111111:assumed-role/builder/i-0123456789"},
+	})
+	defer server.Close()
+
+	target, err := NewAWSIAMAuthenticator(server.URL, "d.ims.io", []string{"111111111111"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, ok, err := target.Authenticate("aws", newTestAWSIAMToken(t, server.URL, "builder", "d.ims.io"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("token was not authenticated")
+	}
+
+	assert.Equal(t, "i-0123456789", principal.Name)
+}
+
+func TestAWSIAMAuthenticatorSTSErrors(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		MarshalAndWrite(t, w, nil, 500)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	target, err := NewAWSIAMAuthenticator(server.URL, "d.ims.io", []string{"111111111111"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := target.Authenticate("aws", newTestAWSIAMToken(t, server.URL, "builder", "d.ims.io")); err == nil {
+		t.Fatal("Error expected")
+	}
+}
+
+func TestAWSIAMAuthenticatorParseToken(t *testing.T) {
+	target, err := NewAWSIAMAuthenticator("https://sts.amazonaws.com", "d.ims.io", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := "Action=GetCallerIdentity&Version=2011-06-15&X-Amz-Credential=key&X-Amz-Expires=900&X-Amz-SignedHeaders=host%3Bx-dims-io-id&X-Amz-Signature=signature"
+	cases := map[string]bool{
+		"https://sts.amazonaws.com/?" + valid:                                                        true,
+		"https://sts.amazonaws.com?" + valid:                                                         true,
+		"https://sts.example.com/?" + valid:                                                          false,
+		"https://sts.amazonaws.com/other?" + valid:                                                   false,
+		"https://sts.amazonaws.com/?" + valid + "&RoleArn=role":                                      false,
+		"https://sts.amazonaws.com/?" + strings.Replace(valid, "GetCallerIdentity", "AssumeRole", 1): false,
+		"https://sts.amazonaws.com/?" + strings.Replace(valid, "%3Bx-dims-io-id", "", 1):             false,
+		"https://sts.amazonaws.com/?" + strings.Replace(valid, "=900", "=3600", 1):                   false,
+	}
+
+	for u, expected := range cases {
+		_, err := target.parseToken(AWSIAMTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(u)))
+		if v, want := err == nil, expected; v != want {
+			t.Errorf("case %s: result was %v, expected %v (%v)", u, v, want, err)
+		}
+	}
+}
+
+func TestCanonicalIdentityARN(t *testing.T) {
+	cases := []struct {
+		ARN             string
+		ExpectedARN     string
+		ExpectedSession string
+	}{
+		{"arn:aws:iam::111111111111:user/deployer", "arn:aws:iam::111111111111:user/deployer", ""},
+		{"arn:aws:iam::111111111111:role/builder", "arn:aws:iam::111111111111:role/builder", ""},
+		{"arn:aws:sts::111111111111:assumed-role/builder/session", "arn:aws:iam::111111111111:role/builder", "session"},
+		{"arn:aws-cn:sts::111111111111:assumed-role/builder/session", "arn:aws-cn:iam::111111111111:role/builder", "session"},
+		{"arn:aws:sts::111111111111:federated-user/john.doe", "", ""},
+		{"arn:aws:iam::111111111111:root", "", ""},
+		{"not an arn", "", ""},
+	}
+
+	for _, c := range cases {
+		v, session, err := canonicalIdentityARN(c.ARN)
+		if c.ExpectedARN == "" {
+			if err == nil {
+				t.Errorf("case %s: error expected", c.ARN)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("case %s: %v", c.ARN, err)
+			continue
+		}
+
+		if want := c.ExpectedARN; v != want {
+			t.Errorf("case %s: arn was '%v', expected '%v'", c.ARN, v, want)
+		}
+
+		if want := c.ExpectedSession; session != want {
+			t.Errorf("case %s: session was '%v', expected '%v'", c.ARN, session, want)
+		}
+	}
+}
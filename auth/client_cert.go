@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+)
+
+// Fields of client certificates which can be used as the username of their principal
+const (
+	CertificateUsernameCN    = "cn"
+	CertificateUsernameEmail = "email"
+	CertificateUsernameDNS   = "dns"
+)
+
+var AllCertificateUsernameFields = []string{CertificateUsernameCN, CertificateUsernameEmail, CertificateUsernameDNS}
+
+// ClientCertAuthenticator authenticates tls client certificates which chain to one of its certificate authorities
+// and allow client authentication. The username of the principal is read from the subject's common name,
+// or from the first email or dns subject alternative name. The subject's organizational units are not used as groups,
+// since groups can grant the admin role and access to owners, and the operators of a ca should not be able to grant them.
+type ClientCertAuthenticator struct {
+	roots         *x509.CertPool
+	usernameField string
+	now           func() time.Time
+}
+
+func NewClientCertAuthenticator(roots *x509.CertPool, usernameField string) *ClientCertAuthenticator {
+	return &ClientCertAuthenticator{
+		roots:         roots,
+		usernameField: usernameField,
+		now:           time.Now,
+	}
+}
+
+func (a *ClientCertAuthenticator) AuthenticateCertificate(certificates []*x509.Certificate) (*Principal, bool, error) {
+	if len(certificates) == 0 {
+		return nil, false, nil
+	}
+
+	cert := certificates[0]
+	log.Printf("[DEBUG] Attempting to authenticate client certificate '%s'", cert.Subject.CommonName)
+
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range certificates[1:] {
+		intermediates.AddCert(intermediate)
+	}
+
+	options := x509.VerifyOptions{
+		Roots:         a.roots,
+		Intermediates: intermediates,
+		CurrentTime:   a.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if _, err := cert.Verify(options); err != nil {
+		log.Printf("[DEBUG] Client certificate '%s' is invalid: %v", cert.Subject.CommonName, err)
+		return nil, false, nil
+	}
+
+	username := certificateUsername(cert, a.usernameField)
+	if username == "" {
+		log.Printf("[DEBUG] Client certificate '%s' does not have a '%s' to use as its username", cert.Subject.CommonName, a.usernameField)
+		return nil, false, nil
+	}
+
+	principal := NewPrincipal(username, AuthenticatorClientCert)
+	principal.Name = cert.Subject.CommonName
+	if len(cert.EmailAddresses) > 0 {
+		principal.Email = cert.EmailAddresses[0]
+	}
+
+	log.Printf("[DEBUG] Client certificate '%s' is valid for user '%s'", cert.Subject.CommonName, username)
+	return principal, true, nil
+}
+
+func certificateUsername(cert *x509.Certificate, field string) string {
+	switch field {
+	case CertificateUsernameEmail:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	case CertificateUsernameDNS:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	default:
+		return cert.Subject.CommonName
+	}
+
+	return ""
+}
+
+func ValidateCertificateUsernameField(field string) error {
+	if !contains(AllCertificateUsernameFields, field) {
+		return fmt.Errorf("Invalid certificate username field '%s': valid fields are %v", field, AllCertificateUsernameFields)
+	}
+
+	return nil
+}
+
+// LoadCertPool loads the pem encoded certificates in the file at path
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No certificates found in '%s'", path)
+	}
+
+	return pool, nil
+}
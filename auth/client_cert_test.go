@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestX509Certificate creates a certificate from the template, signed by parent.
+// The certificate is self-signed if parent is nil.
+func newTestX509Certificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func newTestCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	return newTestX509Certificate(t, template, nil, nil)
+}
+
+func newTestClientTemplate(usages ...x509.ExtKeyUsage) *x509.Certificate {
+	return &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         "build-farm",
+			OrganizationalUnit: []string{"carbon-developers"},
+		},
+		EmailAddresses: []string{"build@example.com"},
+		DNSNames:       []string{"build.example.com"},
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    usages,
+	}
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	ca, caKey := newTestCA(t, "ca")
+	intermediate, intermediateKey := newTestX509Certificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, ca, caKey)
+
+	otherCA, otherCAKey := newTestCA(t, "other")
+
+	client, _ := newTestX509Certificate(t, newTestClientTemplate(x509.ExtKeyUsageClientAuth), ca, caKey)
+	chained, _ := newTestX509Certificate(t, newTestClientTemplate(x509.ExtKeyUsageClientAuth), intermediate, intermediateKey)
+	untrusted, _ := newTestX509Certificate(t, newTestClientTemplate(x509.ExtKeyUsageClientAuth), otherCA, otherCAKey)
+	server, _ := newTestX509Certificate(t, newTestClientTemplate(x509.ExtKeyUsageServerAuth), ca, caKey)
+
+	expiredTemplate := newTestClientTemplate(x509.ExtKeyUsageClientAuth)
+	expiredTemplate.NotBefore = time.Now().Add(-time.Hour * 2)
+	expiredTemplate.NotAfter = time.Now().Add(-time.Hour)
+	expired, _ := newTestX509Certificate(t, expiredTemplate, ca, caKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	target := NewClientCertAuthenticator(roots, CertificateUsernameCN)
+
+	cases := []struct {
+		Name         string
+		Certificates []*x509.Certificate
+		Expected     bool
+	}{
+		{"valid", []*x509.Certificate{client}, true},
+		{"valid through intermediate", []*x509.Certificate{chained, intermediate}, true},
+		{"missing intermediate", []*x509.Certificate{chained}, false},
+		{"untrusted", []*x509.Certificate{untrusted}, false},
+		{"server certificate", []*x509.Certificate{server}, false},
+		{"expired", []*x509.Certificate{expired}, false},
+		{"no certificates", nil, false},
+	}
+
+	for _, c := range cases {
+		principal, ok, err := target.AuthenticateCertificate(c.Certificates)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := ok, c.Expected; v != want {
+			t.Errorf("case %s: result was %v, expected %v", c.Name, v, want)
+		}
+
+		if ok {
+			assert.Equal(t, "build-farm", principal.Username)
+			assert.Equal(t, AuthenticatorClientCert, principal.Authenticator)
+			assert.Empty(t, principal.Groups)
+			assert.Equal(t, "build@example.com", principal.Email)
+		}
+	}
+}
+
+func TestClientCertAuthenticatorUsernameFields(t *testing.T) {
+	ca, caKey := newTestCA(t, "ca")
+	client, _ := newTestX509Certificate(t, newTestClientTemplate(x509.ExtKeyUsageClientAuth), ca, caKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	cases := map[string]string{
+		CertificateUsernameCN:    "build-farm",
+		CertificateUsernameEmail: "build@example.com",
+		CertificateUsernameDNS:   "build.example.com",
+	}
+
+	for field, expected := range cases {
+		principal, ok, err := NewClientCertAuthenticator(roots, field).AuthenticateCertificate([]*x509.Certificate{client})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Fatalf("case %s: certificate was not authenticated", field)
+		}
+
+		if v, want := principal.Username, expected; v != want {
+			t.Errorf("case %s: username was '%v', expected '%v'", field, v, want)
+		}
+	}
+}
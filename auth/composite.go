@@ -1,24 +1,91 @@
 package auth
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+)
 
-func NewCompositeAuthenticator(authenticators ...Authenticator) AuthenticatorFunc {
-	return AuthenticatorFunc(func(user, pass string) (bool, error) {
-		if user == "" || pass == "" {
-			return false, fmt.Errorf("username and/or password is empty")
+// Failure policies decide what a CompositeAuthenticator does when one of its authenticators returns an error
+const (
+	// FailurePolicyAbort stops the chain and returns the error
+	FailurePolicyAbort = "abort"
+	// FailurePolicyFallThrough tries the next authenticator.
+	// The error is only returned if none of the other authenticators accept the credentials.
+	FailurePolicyFallThrough = "fall-through"
+)
+
+var AllFailurePolicies = []string{FailurePolicyAbort, FailurePolicyFallThrough}
+
+// ChainedAuthenticator is an authenticator in a CompositeAuthenticator
+type ChainedAuthenticator struct {
+	// Name identifies the authenticator in logs
+	Name          string
+	Authenticator Authenticator
+	FailurePolicy string
+}
+
+// CompositeAuthenticator authenticates users through each of its authenticators in order.
+// The principal returned by the first authenticator which accepts the credentials is used.
+type CompositeAuthenticator struct {
+	authenticators []ChainedAuthenticator
+}
+
+// NewCompositeAuthenticator creates a CompositeAuthenticator which aborts if any of its authenticators return an error
+func NewCompositeAuthenticator(authenticators ...Authenticator) *CompositeAuthenticator {
+	chain := make([]ChainedAuthenticator, len(authenticators))
+	for i, authenticator := range authenticators {
+		chain[i] = ChainedAuthenticator{
+			Authenticator: authenticator,
+			FailurePolicy: FailurePolicyAbort,
 		}
+	}
 
-		for _, authenticator := range authenticators {
-			isValid, err := authenticator.Authenticate(user, pass)
-			if err != nil {
-				return false, err
-			}
+	return NewAuthenticatorChain(chain...)
+}
+
+// NewAuthenticatorChain creates a CompositeAuthenticator which handles the errors of each authenticator with its FailurePolicy
+func NewAuthenticatorChain(authenticators ...ChainedAuthenticator) *CompositeAuthenticator {
+	return &CompositeAuthenticator{
+		authenticators: authenticators,
+	}
+}
+
+func ValidateFailurePolicy(policy string) error {
+	if !contains(AllFailurePolicies, policy) {
+		return fmt.Errorf("Invalid failure policy '%s': valid policies are %v", policy, AllFailurePolicies)
+	}
+
+	return nil
+}
+
+func (c *CompositeAuthenticator) Authenticate(user, pass string) (*Principal, bool, error) {
+	if user == "" || pass == "" {
+		return nil, false, fmt.Errorf("username and/or password is empty")
+	}
 
-			if isValid {
-				return true, nil
+	var fallThroughErr error
+	for _, chained := range c.authenticators {
+		principal, isValid, err := chained.Authenticator.Authenticate(user, pass)
+		if err != nil {
+			if chained.FailurePolicy != FailurePolicyFallThrough {
+				return nil, false, err
 			}
+
+			log.Printf("[WARN] Authenticator '%s' encountered an error, trying the next authenticator: %v", chained.Name, err)
+			fallThroughErr = err
+			continue
+		}
+
+		if isValid {
+			return principal, true, nil
 		}
+	}
+
+	// the credentials may have been valid for the authenticator which failed,
+	// so they are not reported as invalid, which would cache them and count them towards lockouts
+	if fallThroughErr != nil {
+		return nil, false, fallThroughErr
+	}
 
-		return false, nil
-	})
+	return nil, false, nil
 }
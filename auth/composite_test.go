@@ -1,18 +1,27 @@
 package auth
 
 import (
+	"errors"
 	"testing"
 )
 
 func newTestAuthenticator(isValid bool, err error) AuthenticatorFunc {
-	return AuthenticatorFunc(func(string, string) (bool, error) {
-		return isValid, err
+	return newTestPrincipalAuthenticator("test", isValid, err)
+}
+
+func newTestPrincipalAuthenticator(name string, isValid bool, err error) AuthenticatorFunc {
+	return AuthenticatorFunc(func(user, pass string) (*Principal, bool, error) {
+		if !isValid || err != nil {
+			return nil, false, err
+		}
+
+		return NewPrincipal(user, name), true, nil
 	})
 }
 
 func TestEmptyUserPass(t *testing.T) {
 	target := NewCompositeAuthenticator()
-	if _, err := target.Authenticate("", ""); err == nil {
+	if _, _, err := target.Authenticate("", ""); err == nil {
 		t.Fatalf("Error expected when authenticating with no user and pass")
 	}
 }
@@ -59,7 +68,7 @@ func TestCompositeAuthenticator(t *testing.T) {
 
 	for _, c := range cases {
 		target := NewCompositeAuthenticator(c.Authenticators...)
-		result, err := target.Authenticate("user", "pass")
+		_, result, err := target.Authenticate("user", "pass")
 		if err != nil {
 			t.Fatalf("Error on case %s: %v", c.Name, err)
 		}
@@ -69,3 +78,84 @@ func TestCompositeAuthenticator(t *testing.T) {
 		}
 	}
 }
+
+func TestCompositeAuthenticatorPrincipal(t *testing.T) {
+	target := NewCompositeAuthenticator(
+		newTestPrincipalAuthenticator("first", false, nil),
+		newTestPrincipalAuthenticator("second", true, nil),
+		newTestPrincipalAuthenticator("third", true, nil),
+	)
+
+	principal, ok, err := target.Authenticate("user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("Credentials were not accepted")
+	}
+
+	if v, want := principal.Authenticator, "second"; v != want {
+		t.Errorf("Authenticator was '%s', expected '%s'", v, want)
+	}
+
+	if v, want := principal.Username, "user"; v != want {
+		t.Errorf("Username was '%s', expected '%s'", v, want)
+	}
+}
+
+func TestCompositeAuthenticatorFailurePolicies(t *testing.T) {
+	errBackend := errors.New("backend unavailable")
+
+	cases := []struct {
+		Name           string
+		ExpectedResult bool
+		ExpectedError  error
+		Authenticators []ChainedAuthenticator
+	}{
+		{
+			Name:          "abort",
+			ExpectedError: errBackend,
+			Authenticators: []ChainedAuthenticator{
+				{Authenticator: newTestAuthenticator(false, errBackend), FailurePolicy: FailurePolicyAbort},
+				{Authenticator: newTestAuthenticator(true, nil), FailurePolicy: FailurePolicyAbort},
+			},
+		},
+		{
+			Name:           "fall through to valid",
+			ExpectedResult: true,
+			Authenticators: []ChainedAuthenticator{
+				{Authenticator: newTestAuthenticator(false, errBackend), FailurePolicy: FailurePolicyFallThrough},
+				{Authenticator: newTestAuthenticator(true, nil), FailurePolicy: FailurePolicyAbort},
+			},
+		},
+		{
+			Name:          "fall through to invalid",
+			ExpectedError: errBackend,
+			Authenticators: []ChainedAuthenticator{
+				{Authenticator: newTestAuthenticator(false, errBackend), FailurePolicy: FailurePolicyFallThrough},
+				{Authenticator: newTestAuthenticator(false, nil), FailurePolicy: FailurePolicyAbort},
+			},
+		},
+		{
+			Name:          "fall through to abort",
+			ExpectedError: errBackend,
+			Authenticators: []ChainedAuthenticator{
+				{Authenticator: newTestAuthenticator(false, nil), FailurePolicy: FailurePolicyFallThrough},
+				{Authenticator: newTestAuthenticator(false, errBackend), FailurePolicy: FailurePolicyAbort},
+				{Authenticator: newTestAuthenticator(true, nil), FailurePolicy: FailurePolicyAbort},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		_, result, err := NewAuthenticatorChain(c.Authenticators...).Authenticate("user", "pass")
+		if v, want := err, c.ExpectedError; v != want {
+			t.Errorf("case %s: error was '%v', expected '%v'", c.Name, v, want)
+		}
+
+		if v, want := result, c.ExpectedResult; v != want {
+			t.Errorf("case %s: result was %v, expected %v", c.Name, v, want)
+		}
+	}
+}
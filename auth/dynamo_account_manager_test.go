@@ -1,4 +1,4 @@
-package auth
+package auth_test
 
 import (
 	"testing"
@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/mock"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +16,7 @@ func TestDynamoGrantAccess(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
-	target := NewDynamoAccountManager("table", mockDynamoDB)
+	target := auth.NewDynamoAccountManager("table", mockDynamoDB)
 
 	item := map[string]*dynamodb.AttributeValue{
 		"AccountID": {
@@ -41,7 +42,7 @@ func TestDynamoRevokeAccess(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
-	target := NewDynamoAccountManager("table", mockDynamoDB)
+	target := auth.NewDynamoAccountManager("table", mockDynamoDB)
 
 	item := map[string]*dynamodb.AttributeValue{
 		"AccountID": {
@@ -67,7 +68,7 @@ func TestDynamoGetAccounts(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
-	target := NewDynamoAccountManager("table", mockDynamoDB)
+	target := auth.NewDynamoAccountManager("table", mockDynamoDB)
 
 	input := &dynamodb.ScanInput{}
 	input.SetTableName("table")
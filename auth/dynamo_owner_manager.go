@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoOwnerManager stores each owner as an item keyed by 'Owner'.
+// The roles of its members are stored in the maps 'Members' and 'Groups',
+// and its service accounts in the map 'ServiceAccounts'.
+// Items are only replaced if their 'Version' has not changed since they were read.
+type DynamoOwnerManager struct {
+	table    string
+	dynamodb dynamodbiface.DynamoDBAPI
+}
+
+func NewDynamoOwnerManager(table string, dynamodb dynamodbiface.DynamoDBAPI) *DynamoOwnerManager {
+	return &DynamoOwnerManager{
+		table:    table,
+		dynamodb: dynamodb,
+	}
+}
+
+func (d *DynamoOwnerManager) GetOwner(name string) (*Owner, error) {
+	key := map[string]*dynamodb.AttributeValue{
+		"Owner": {
+			S: aws.String(name),
+		},
+	}
+
+	input := &dynamodb.GetItemInput{}
+	input.SetTableName(d.table)
+	input.SetKey(key)
+	input.SetConsistentRead(true)
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	output, err := d.dynamodb.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Item) == 0 {
+		return nil, ErrOwnerNotFound
+	}
+
+	return itemToOwner(output.Item), nil
+}
+
+func (d *DynamoOwnerManager) CreateOwner(owner *Owner) error {
+	// 'Owner' is a reserved word in dynamodb expressions
+	input := d.newPutItemInput(owner, 1)
+	input.SetConditionExpression("attribute_not_exists(#Owner)")
+	input.SetExpressionAttributeNames(map[string]*string{
+		"#Owner": aws.String("Owner"),
+	})
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := d.dynamodb.PutItem(input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ErrOwnerExists
+		}
+
+		return err
+	}
+
+	owner.Version = 1
+	return nil
+}
+
+func (d *DynamoOwnerManager) PutOwner(owner *Owner) error {
+	input := d.newPutItemInput(owner, owner.Version+1)
+	if owner.Version == 0 {
+		input.SetConditionExpression("attribute_not_exists(Version)")
+	} else {
+		input.SetConditionExpression("Version = :version")
+		input.SetExpressionAttributeValues(map[string]*dynamodb.AttributeValue{
+			":version": versionAttribute(owner.Version),
+		})
+	}
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := d.dynamodb.PutItem(input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ErrOwnerModified
+		}
+
+		return err
+	}
+
+	owner.Version++
+	return nil
+}
+
+func (d *DynamoOwnerManager) ListOwners() ([]string, error) {
+	input := &dynamodb.ScanInput{}
+	input.SetTableName(d.table)
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	owners := []string{}
+	fn := func(output *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range output.Items {
+			owners = append(owners, aws.StringValue(item["Owner"].S))
+		}
+
+		return !lastPage
+	}
+
+	if err := d.dynamodb.ScanPages(input, fn); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(owners)
+	return owners, nil
+}
+
+func (d *DynamoOwnerManager) newPutItemInput(owner *Owner, version int64) *dynamodb.PutItemInput {
+	item := map[string]*dynamodb.AttributeValue{
+		"Owner": {
+			S: aws.String(owner.Name),
+		},
+		"Members":         rolesToAttribute(owner.Members),
+		"Groups":          rolesToAttribute(owner.Groups),
+		"ServiceAccounts": serviceAccountsToAttribute(owner.ServiceAccounts),
+		"Version":         versionAttribute(version),
+	}
+
+	input := &dynamodb.PutItemInput{}
+	input.SetTableName(d.table)
+	input.SetItem(item)
+	return input
+}
+
+func versionAttribute(version int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(version, 10))}
+}
+
+func rolesToAttribute(roles map[string]string) *dynamodb.AttributeValue {
+	m := map[string]*dynamodb.AttributeValue{}
+	for name, role := range roles {
+		m[name] = &dynamodb.AttributeValue{S: aws.String(role)}
+	}
+
+	return &dynamodb.AttributeValue{M: m}
+}
+
+func serviceAccountsToAttribute(serviceAccounts map[string]*ServiceAccount) *dynamodb.AttributeValue {
+	m := map[string]*dynamodb.AttributeValue{}
+	for name, serviceAccount := range serviceAccounts {
+		attributes := map[string]*dynamodb.AttributeValue{
+			"Role": {S: aws.String(serviceAccount.Role)},
+		}
+
+		// dynamodb does not allow empty strings
+		if serviceAccount.Description != "" {
+			attributes["Description"] = &dynamodb.AttributeValue{S: aws.String(serviceAccount.Description)}
+		}
+
+		m[name] = &dynamodb.AttributeValue{M: attributes}
+	}
+
+	return &dynamodb.AttributeValue{M: m}
+}
+
+func itemToOwner(item map[string]*dynamodb.AttributeValue) *Owner {
+	owner := NewOwner(aws.StringValue(item["Owner"].S))
+
+	// owners registered before roles were introduced list their members in string sets;
+	// those members maintain the owner so they can manage it through the api
+	if v, ok := item["Users"]; ok {
+		for _, user := range aws.StringValueSlice(v.SS) {
+			owner.Members[user] = RoleMaintainer
+		}
+	}
+
+	if v, ok := item["Members"]; ok {
+		for user, role := range v.M {
+			owner.Members[user] = aws.StringValue(role.S)
+		}
+	}
+
+	if v, ok := item["Groups"]; ok {
+		for _, group := range aws.StringValueSlice(v.SS) {
+			owner.Groups[group] = RoleMaintainer
+		}
+
+		for group, role := range v.M {
+			owner.Groups[group] = aws.StringValue(role.S)
+		}
+	}
+
+	if v, ok := item["Version"]; ok {
+		owner.Version, _ = strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+	}
+
+	if v, ok := item["ServiceAccounts"]; ok {
+		for name, attribute := range v.M {
+			serviceAccount := &ServiceAccount{Name: name}
+			if role, ok := attribute.M["Role"]; ok {
+				serviceAccount.Role = aws.StringValue(role.S)
+			}
+
+			if description, ok := attribute.M["Description"]; ok {
+				serviceAccount.Description = aws.StringValue(description.S)
+			}
+
+			owner.ServiceAccounts[name] = serviceAccount
+		}
+	}
+
+	return owner
+}
@@ -0,0 +1,244 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamoGetOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	validateGetItemInput := func(input *dynamodb.GetItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "carbon", aws.StringValue(input.Key["Owner"].S))
+	}
+
+	item := map[string]*dynamodb.AttributeValue{
+		"Owner": {S: aws.String("carbon")},
+		"Members": {M: map[string]*dynamodb.AttributeValue{
+			"john.doe": {S: aws.String(auth.RoleDeveloper)},
+		}},
+		"Groups": {M: map[string]*dynamodb.AttributeValue{
+			"carbon-readers": {S: aws.String(auth.RoleReader)},
+		}},
+		"ServiceAccounts": {M: map[string]*dynamodb.AttributeValue{
+			"ci": {M: map[string]*dynamodb.AttributeValue{
+				"Role":        {S: aws.String(auth.RoleDeveloper)},
+				"Description": {S: aws.String("pushes images")},
+			}},
+		}},
+	}
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Do(validateGetItemInput).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	owner, err := target.GetOwner("carbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := auth.NewOwner("carbon")
+	expected.Members["john.doe"] = auth.RoleDeveloper
+	expected.Groups["carbon-readers"] = auth.RoleReader
+	expected.ServiceAccounts["ci"] = &auth.ServiceAccount{Name: "ci", Role: auth.RoleDeveloper, Description: "pushes images"}
+
+	assert.Equal(t, expected, owner)
+}
+
+func TestDynamoGetLegacyOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	item := map[string]*dynamodb.AttributeValue{
+		"Owner":  {S: aws.String("carbon")},
+		"Users":  {SS: aws.StringSlice([]string{"john.doe"})},
+		"Groups": {SS: aws.StringSlice([]string{"carbon-developers"})},
+	}
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	owner, err := target.GetOwner("carbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := auth.NewOwner("carbon")
+	expected.Members["john.doe"] = auth.RoleMaintainer
+	expected.Groups["carbon-developers"] = auth.RoleMaintainer
+
+	assert.Equal(t, expected, owner)
+}
+
+func TestDynamoGetOwnerNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	if _, err := target.GetOwner("carbon"); err != auth.ErrOwnerNotFound {
+		t.Fatalf("Error was '%v', expected '%v'", err, auth.ErrOwnerNotFound)
+	}
+}
+
+func TestDynamoCreateOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	owner := auth.NewOwner("carbon")
+	owner.Members["john.doe"] = auth.RoleMaintainer
+	owner.ServiceAccounts["ci"] = &auth.ServiceAccount{Name: "ci", Role: auth.RoleReader}
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "attribute_not_exists(#Owner)", aws.StringValue(input.ConditionExpression))
+		assert.Equal(t, "carbon", aws.StringValue(input.Item["Owner"].S))
+		assert.Equal(t, "1", aws.StringValue(input.Item["Version"].N))
+		assert.Equal(t, auth.RoleMaintainer, aws.StringValue(input.Item["Members"].M["john.doe"].S))
+		assert.NotNil(t, input.Item["Groups"].M)
+
+		// empty descriptions are omitted since dynamodb does not allow empty strings
+		serviceAccount := input.Item["ServiceAccounts"].M["ci"].M
+		assert.Equal(t, auth.RoleReader, aws.StringValue(serviceAccount["Role"].S))
+		assert.NotContains(t, serviceAccount, "Description")
+	}
+
+	gomock.InOrder(
+		mockDynamoDB.EXPECT().
+			PutItem(gomock.Any()).
+			Do(validatePutItemInput).
+			Return(&dynamodb.PutItemOutput{}, nil),
+		mockDynamoDB.EXPECT().
+			PutItem(gomock.Any()).
+			Return(nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)),
+	)
+
+	if err := target.CreateOwner(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := target.CreateOwner(owner); err != auth.ErrOwnerExists {
+		t.Fatalf("Error was '%v', expected '%v'", err, auth.ErrOwnerExists)
+	}
+}
+
+func TestDynamoPutOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	owner := auth.NewOwner("carbon")
+	owner.Members["john.doe"] = auth.RoleMaintainer
+	owner.Version = 3
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "Version = :version", aws.StringValue(input.ConditionExpression))
+		assert.Equal(t, "3", aws.StringValue(input.ExpressionAttributeValues[":version"].N))
+		assert.Equal(t, "4", aws.StringValue(input.Item["Version"].N))
+	}
+
+	gomock.InOrder(
+		mockDynamoDB.EXPECT().
+			PutItem(gomock.Any()).
+			Do(validatePutItemInput).
+			Return(&dynamodb.PutItemOutput{}, nil),
+		mockDynamoDB.EXPECT().
+			PutItem(gomock.Any()).
+			Return(nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)),
+	)
+
+	if err := target.PutOwner(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(4), owner.Version)
+
+	if err := target.PutOwner(owner); err != auth.ErrOwnerModified {
+		t.Fatalf("Error was '%v', expected '%v'", err, auth.ErrOwnerModified)
+	}
+}
+
+func TestDynamoPutUnversionedOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		assert.Equal(t, "attribute_not_exists(Version)", aws.StringValue(input.ConditionExpression))
+		assert.Nil(t, input.ExpressionAttributeValues)
+		assert.Equal(t, "1", aws.StringValue(input.Item["Version"].N))
+	}
+
+	mockDynamoDB.EXPECT().
+		PutItem(gomock.Any()).
+		Do(validatePutItemInput).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	if err := target.PutOwner(auth.NewOwner("carbon")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamoListOwners(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoOwnerManager("table", mockDynamoDB)
+
+	scanPages := func(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) {
+		fn(&dynamodb.ScanOutput{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{"Owner": {S: aws.String("nitrogen")}},
+			},
+		}, false)
+
+		fn(&dynamodb.ScanOutput{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{"Owner": {S: aws.String("carbon")}},
+			},
+		}, true)
+	}
+
+	mockDynamoDB.EXPECT().
+		ScanPages(gomock.Any(), gomock.Any()).
+		Do(scanPages).
+		Return(nil)
+
+	owners, err := target.ListOwners()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"carbon", "nitrogen"}, owners)
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoRepositoryManager stores the metadata of each repository as an item keyed by 'Repository'.
+// The read acl is stored in the string sets 'ReadUsers' and 'ReadGroups', which are omitted when empty.
+type DynamoRepositoryManager struct {
+	table    string
+	dynamodb dynamodbiface.DynamoDBAPI
+}
+
+func NewDynamoRepositoryManager(table string, dynamodb dynamodbiface.DynamoDBAPI) *DynamoRepositoryManager {
+	return &DynamoRepositoryManager{
+		table:    table,
+		dynamodb: dynamodb,
+	}
+}
+
+func (d *DynamoRepositoryManager) GetRepository(name string) (*Repository, error) {
+	input := &dynamodb.GetItemInput{}
+	input.SetTableName(d.table)
+	input.SetKey(repositoryKey(name))
+	input.SetConsistentRead(true)
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	output, err := d.dynamodb.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Item) == 0 {
+		return nil, ErrRepositoryNotFound
+	}
+
+	return itemToRepository(output.Item), nil
+}
+
+func (d *DynamoRepositoryManager) CreateRepository(repository *Repository) error {
+	input := d.newPutItemInput(repository)
+	input.SetConditionExpression("attribute_not_exists(Repository)")
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := d.dynamodb.PutItem(input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ErrRepositoryExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (d *DynamoRepositoryManager) PutRepository(repository *Repository) error {
+	input := d.newPutItemInput(repository)
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := d.dynamodb.PutItem(input); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *DynamoRepositoryManager) newPutItemInput(repository *Repository) *dynamodb.PutItemInput {
+	item := map[string]*dynamodb.AttributeValue{
+		"Repository": {
+			S: aws.String(repository.Name),
+		},
+		"Visibility": {
+			S: aws.String(repository.Visibility),
+		},
+	}
+
+	// dynamodb does not allow empty sets
+	if len(repository.ReadUsers) > 0 {
+		item["ReadUsers"] = &dynamodb.AttributeValue{SS: aws.StringSlice(repository.ReadUsers)}
+	}
+
+	if len(repository.ReadGroups) > 0 {
+		item["ReadGroups"] = &dynamodb.AttributeValue{SS: aws.StringSlice(repository.ReadGroups)}
+	}
+
+	input := &dynamodb.PutItemInput{}
+	input.SetTableName(d.table)
+	input.SetItem(item)
+	return input
+}
+
+func (d *DynamoRepositoryManager) DeleteRepository(name string) error {
+	input := &dynamodb.DeleteItemInput{}
+	input.SetTableName(d.table)
+	input.SetKey(repositoryKey(name))
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := d.dynamodb.DeleteItem(input); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *DynamoRepositoryManager) ListRepositories() ([]*Repository, error) {
+	input := &dynamodb.ScanInput{}
+	input.SetTableName(d.table)
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	repositories := []*Repository{}
+	fn := func(output *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range output.Items {
+			repositories = append(repositories, itemToRepository(item))
+		}
+
+		return !lastPage
+	}
+
+	if err := d.dynamodb.ScanPages(input, fn); err != nil {
+		return nil, err
+	}
+
+	return repositories, nil
+}
+
+func repositoryKey(name string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Repository": {
+			S: aws.String(name),
+		},
+	}
+}
+
+func itemToRepository(item map[string]*dynamodb.AttributeValue) *Repository {
+	repository := NewRepository(aws.StringValue(item["Repository"].S))
+	if v, ok := item["Visibility"]; ok {
+		repository.Visibility = aws.StringValue(v.S)
+	}
+
+	if v, ok := item["ReadUsers"]; ok {
+		repository.ReadUsers = aws.StringValueSlice(v.SS)
+	}
+
+	if v, ok := item["ReadGroups"]; ok {
+		repository.ReadGroups = aws.StringValueSlice(v.SS)
+	}
+
+	return repository
+}
@@ -0,0 +1,131 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamoGetRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoRepositoryManager("table", mockDynamoDB)
+
+	validateGetItemInput := func(input *dynamodb.GetItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "carbon/api", aws.StringValue(input.Key["Repository"].S))
+	}
+
+	item := map[string]*dynamodb.AttributeValue{
+		"Repository": {S: aws.String("carbon/api")},
+		"Visibility": {S: aws.String(auth.VisibilityPrivate)},
+		"ReadUsers":  {SS: aws.StringSlice([]string{"john.doe"})},
+	}
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Do(validateGetItemInput).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	repository, err := target.GetRepository("carbon/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := auth.NewRepository("carbon/api")
+	expected.Visibility = auth.VisibilityPrivate
+	expected.ReadUsers = []string{"john.doe"}
+
+	assert.Equal(t, expected, repository)
+}
+
+func TestDynamoGetRepositoryNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoRepositoryManager("table", mockDynamoDB)
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	if _, err := target.GetRepository("carbon/api"); err != auth.ErrRepositoryNotFound {
+		t.Fatalf("Error was '%v', expected '%v'", err, auth.ErrRepositoryNotFound)
+	}
+}
+
+func TestDynamoCreateRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoRepositoryManager("table", mockDynamoDB)
+
+	repository := auth.NewRepository("carbon/api")
+	repository.Visibility = auth.VisibilityPrivate
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "attribute_not_exists(Repository)", aws.StringValue(input.ConditionExpression))
+		assert.Equal(t, "carbon/api", aws.StringValue(input.Item["Repository"].S))
+		assert.Equal(t, auth.VisibilityPrivate, aws.StringValue(input.Item["Visibility"].S))
+	}
+
+	gomock.InOrder(
+		mockDynamoDB.EXPECT().
+			PutItem(gomock.Any()).
+			Do(validatePutItemInput).
+			Return(&dynamodb.PutItemOutput{}, nil),
+		mockDynamoDB.EXPECT().
+			PutItem(gomock.Any()).
+			Return(nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil)),
+	)
+
+	if err := target.CreateRepository(repository); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := target.CreateRepository(repository); err != auth.ErrRepositoryExists {
+		t.Fatalf("Error was '%v', expected '%v'", err, auth.ErrRepositoryExists)
+	}
+}
+
+func TestDynamoPutRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoRepositoryManager("table", mockDynamoDB)
+
+	repository := auth.NewRepository("carbon/api")
+	repository.Visibility = auth.VisibilityPrivate
+	repository.ReadGroups = []string{"qa"}
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		assert.Equal(t, "table", aws.StringValue(input.TableName))
+		assert.Equal(t, "carbon/api", aws.StringValue(input.Item["Repository"].S))
+		assert.Equal(t, auth.VisibilityPrivate, aws.StringValue(input.Item["Visibility"].S))
+		assert.Equal(t, []string{"qa"}, aws.StringValueSlice(input.Item["ReadGroups"].SS))
+
+		// empty sets are not allowed by dynamodb
+		assert.Nil(t, input.Item["ReadUsers"])
+	}
+
+	mockDynamoDB.EXPECT().
+		PutItem(gomock.Any()).
+		Do(validatePutItemInput).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	if err := target.PutRepository(repository); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,37 +1,93 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
-	"math/rand"
+	"strconv"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
+// TokensUserIndex is the name of the global secondary index on the 'User' column of the tokens table
+const TokensUserIndex = "UserIndex"
+
+// DynamoTokenManager stores tokens in a DynamoDB table.
+// Tokens are never stored directly: the 'Token' column holds an HMAC-SHA256 hash of the token
+// which is keyed by a secret pepper, so read access to the table does not grant access to d.ims.io.
+// Tokens created before hashing was introduced are rehashed the first time they are used.
 type DynamoTokenManager struct {
+	// AllowLegacyTokens enables authentication with tokens created before the 'dims_' token format
+	AllowLegacyTokens bool
+	// OnRevoke, if set, is called with the hash key of each token which is revoked
+	OnRevoke func(key string)
 	table    string
+	pepper   []byte
 	dynamodb dynamodbiface.DynamoDBAPI
 }
 
-func NewDynamoTokenManager(table string, dynamodb dynamodbiface.DynamoDBAPI) *DynamoTokenManager {
+func NewDynamoTokenManager(table, pepper string, dynamodb dynamodbiface.DynamoDBAPI) *DynamoTokenManager {
 	return &DynamoTokenManager{
-		table:    table,
-		dynamodb: dynamodb,
+		AllowLegacyTokens: true,
+		table:             table,
+		pepper:            []byte(pepper),
+		dynamodb:          dynamodb,
 	}
 }
 
-func (d *DynamoTokenManager) CreateToken(user string) (string, error) {
-	token := convertToToken(randomString(20), randomString(20))
+func (d *DynamoTokenManager) CreateToken(user string, options TokenOptions) (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	hash := d.hashToken(token)
+	mask := MaskToken(token)
+	now := time.Now()
 
 	item := map[string]*dynamodb.AttributeValue{
 		"User": {
 			S: &user,
 		},
 		"Token": {
-			S: &token,
+			S: &hash,
+		},
+		"Mask": {
+			S: &mask,
 		},
+		"CreatedAt": unixAttribute(now),
+	}
+
+	// dynamodb does not allow empty string attributes
+	if options.Name != "" {
+		item["Name"] = &dynamodb.AttributeValue{S: aws.String(options.Name)}
+	}
+
+	if options.Description != "" {
+		item["Description"] = &dynamodb.AttributeValue{S: aws.String(options.Description)}
+	}
+
+	// tokens created without any scopes are unrestricted
+	scopes := options.Scopes
+	if len(scopes) == 0 {
+		scopes = AllScopes
+	}
+
+	item["Scopes"] = &dynamodb.AttributeValue{SS: aws.StringSlice(scopes)}
+
+	if len(options.Repositories) > 0 {
+		item["Repositories"] = &dynamodb.AttributeValue{SS: aws.StringSlice(options.Repositories)}
+	}
+
+	// the 'ExpiresAt' column is used as the table's ttl attribute
+	if options.ExpiresIn > 0 {
+		item["ExpiresAt"] = unixAttribute(now.Add(options.ExpiresIn))
 	}
 
 	input := &dynamodb.PutItemInput{}
@@ -49,10 +105,134 @@ func (d *DynamoTokenManager) CreateToken(user string) (string, error) {
 	return token, nil
 }
 
-func (d *DynamoTokenManager) DeleteToken(token string) error {
+func (d *DynamoTokenManager) GetToken(id string) (*Token, error) {
+	item, err := d.getItem(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(item) == 0 || isExpired(item) {
+		return nil, ErrTokenNotFound
+	}
+
+	token := itemToToken(item)
+	return &token, nil
+}
+
+func (d *DynamoTokenManager) RotateToken(id string, gracePeriod time.Duration) (string, error) {
+	item, err := d.getItem(id)
+	if err != nil {
+		return "", err
+	}
+
+	if len(item) == 0 || isExpired(item) {
+		return "", ErrTokenNotFound
+	}
+
+	current := itemToToken(item)
+	options := TokenOptions{
+		Name:         current.Name,
+		Description:  current.Description,
+		Scopes:       current.Scopes,
+		Repositories: current.Repositories,
+	}
+
+	// the replacement token gets the same lifetime as the current token
+	if !current.ExpiresAt.IsZero() {
+		options.ExpiresIn = current.ExpiresAt.Sub(current.CreatedAt)
+	}
+
+	replacement, err := d.CreateToken(current.User, options)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(gracePeriod)
+	if !current.ExpiresAt.IsZero() && current.ExpiresAt.Before(expiresAt) {
+		expiresAt = current.ExpiresAt
+	}
+
+	key := map[string]*dynamodb.AttributeValue{
+		"Token": item["Token"],
+	}
+
+	input := &dynamodb.UpdateItemInput{}
+	input.SetTableName(d.table)
+	input.SetKey(key)
+	input.SetUpdateExpression("SET ExpiresAt = :expiresAt")
+	input.SetExpressionAttributeValues(map[string]*dynamodb.AttributeValue{
+		":expiresAt": unixAttribute(expiresAt),
+	})
+
+	if err := input.Validate(); err != nil {
+		return "", err
+	}
+
+	if _, err := d.dynamodb.UpdateItem(input); err != nil {
+		return "", err
+	}
+
+	d.Revoke(aws.StringValue(item["Token"].S))
+	return replacement, nil
+}
+
+func (d *DynamoTokenManager) DeleteToken(id string) error {
+	if err := d.deleteItem(id); err != nil {
+		return err
+	}
+
+	d.Revoke(id)
+	return nil
+}
+
+func (d *DynamoTokenManager) DeleteUserTokens(user string) (int, error) {
+	keys := []string{}
+	fn := func(item map[string]*dynamodb.AttributeValue) {
+		keys = append(keys, aws.StringValue(item["Token"].S))
+	}
+
+	// expired tokens are deleted as well, they may linger until dynamodb's ttl process removes them
+	if err := d.queryUserItems(user, fn); err != nil {
+		return 0, err
+	}
+
+	for i, key := range keys {
+		if err := d.deleteItem(key); err != nil {
+			return i, err
+		}
+
+		d.Revoke(key)
+	}
+
+	log.Printf("[INFO] Deleted %d tokens of user '%s'", len(keys), user)
+	return len(keys), nil
+}
+
+// Revoke passes the hash key of the token stored under key to OnRevoke.
+// It is called when an item of the tokens table is removed or changed, including by other instances.
+// Legacy items which have not been migrated are stored under the token itself rather than its hash.
+func (d *DynamoTokenManager) Revoke(key string) {
+	if d.OnRevoke == nil {
+		return
+	}
+
+	if !isHashKey(key) {
+		key = d.hashToken(key)
+	}
+
+	d.OnRevoke(key)
+}
+
+// isHashKey returns false if key is the token itself, as in legacy items which have not been migrated
+func isHashKey(key string) bool {
+	_, err := hex.DecodeString(key)
+	return err == nil && len(key) == sha256.Size*2
+}
+
+func (d *DynamoTokenManager) deleteItem(hashKey string) error {
 	key := map[string]*dynamodb.AttributeValue{
 		"Token": {
-			S: &token,
+			S: &hashKey,
 		},
 	}
 
@@ -71,13 +251,177 @@ func (d *DynamoTokenManager) DeleteToken(token string) error {
 	return nil
 }
 
-func (d *DynamoTokenManager) Authenticate(user, pass string) (bool, error) {
+// ListTokens returns the unexpired tokens of the user.
+// Legacy items which have not been migrated are listed without an id; they are only migrated by Authenticate.
+func (d *DynamoTokenManager) ListTokens(user string) ([]Token, error) {
+	tokens := []Token{}
+	fn := func(item map[string]*dynamodb.AttributeValue) {
+		// expired tokens may linger until dynamodb's ttl process removes them
+		if !isExpired(item) {
+			tokens = append(tokens, itemToToken(item))
+		}
+	}
+
+	if err := d.queryUserItems(user, fn); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// queryUserItems calls fn with each item of the user, including expired items
+func (d *DynamoTokenManager) queryUserItems(user string, fn func(item map[string]*dynamodb.AttributeValue)) error {
+	input := &dynamodb.QueryInput{}
+	input.SetTableName(d.table)
+	input.SetIndexName(TokensUserIndex)
+	input.SetKeyConditionExpression("#User = :user")
+	input.SetExpressionAttributeNames(map[string]*string{
+		"#User": aws.String("User"),
+	})
+	input.SetExpressionAttributeValues(map[string]*dynamodb.AttributeValue{
+		":user": {
+			S: &user,
+		},
+	})
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	pageFn := func(output *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range output.Items {
+			fn(item)
+		}
+
+		return !lastPage
+	}
+
+	return d.dynamodb.QueryPages(input, pageFn)
+}
+
+// Authenticate returns the principal of the user who created the token held by the credentials.
+// The principal is restricted to the scopes and repositories of the token.
+func (d *DynamoTokenManager) Authenticate(user, pass string) (*Principal, bool, error) {
 	log.Printf("[DEBUG] Attempting to authenticate user '%s' through DynamoDB", user)
 
-	token := convertToToken(user, pass)
+	token, ok := d.parseCredentials(user, pass)
+	if !ok {
+		log.Printf("[DEBUG] User '%s' sent malformed DynamoDB credentials", user)
+		return nil, false, nil
+	}
+
+	item, err := d.lookupToken(token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(item) == 0 {
+		log.Printf("[DEBUG] User '%s' sent invalid DynamoDB credentials", user)
+		return nil, false, nil
+	}
+
+	// dynamodb's ttl process can take up to 48 hours to delete expired items
+	if isExpired(item) {
+		log.Printf("[DEBUG] User '%s' sent expired DynamoDB credentials", user)
+		return nil, false, nil
+	}
+
+	// the username sent with the credentials is chosen by the client, so it cannot stand in for a missing owner
+	current := itemToToken(item)
+	if current.User == "" {
+		log.Printf("[WARN] Token '%s' has no user and cannot be used", current.MaskedToken)
+		return nil, false, nil
+	}
+
+	principal := &Principal{
+		Permissions: Permissions{
+			Scopes:       current.Scopes,
+			Repositories: current.Repositories,
+		},
+		Username:      current.User,
+		Authenticator: AuthenticatorToken,
+		TokenID:       d.hashToken(token),
+		ExpiresAt:     current.ExpiresAt,
+	}
+
+	log.Printf("[DEBUG] User '%s' sent valid DynamoDB credentials for token '%s'", current.User, current.MaskedToken)
+	return principal, true, nil
+}
+
+// parseCredentials returns the token held by the credentials.
+// Tokens are sent as the password; the username is ignored.
+// Legacy tokens are the base64 encoding of the username and password.
+// The second return value is false if the credentials cannot possibly hold a valid token.
+func (d *DynamoTokenManager) parseCredentials(user, pass string) (string, bool) {
+	if IsTokenFormat(pass) {
+		return pass, ValidateTokenFormat(pass) == nil
+	}
+
+	if !d.AllowLegacyTokens {
+		return "", false
+	}
+
+	return convertToToken(user, pass), true
+}
+
+// lookupToken returns the item for the specified token.
+// If the token is only stored under its legacy, unhashed key, it is migrated to its hashed key.
+func (d *DynamoTokenManager) lookupToken(token string) (map[string]*dynamodb.AttributeValue, error) {
+	item, err := d.getItem(d.hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(item) > 0 || IsTokenFormat(token) {
+		return item, nil
+	}
+
+	legacyItem, err := d.getItem(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(legacyItem) == 0 || isExpired(legacyItem) {
+		return legacyItem, nil
+	}
+
+	return d.migrateItem(token, legacyItem)
+}
+
+func (d *DynamoTokenManager) migrateItem(token string, legacyItem map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	log.Printf("[INFO] Migrating token '%s' to its hashed key", MaskToken(token))
+
+	item := map[string]*dynamodb.AttributeValue{}
+	for k, v := range legacyItem {
+		item[k] = v
+	}
+
+	item["Token"] = &dynamodb.AttributeValue{S: aws.String(d.hashToken(token))}
+	item["Mask"] = &dynamodb.AttributeValue{S: aws.String(MaskToken(token))}
+
+	input := &dynamodb.PutItemInput{}
+	input.SetTableName(d.table)
+	input.SetItem(item)
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := d.dynamodb.PutItem(input); err != nil {
+		return nil, err
+	}
+
+	if err := d.deleteItem(token); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (d *DynamoTokenManager) getItem(hashKey string) (map[string]*dynamodb.AttributeValue, error) {
 	key := map[string]*dynamodb.AttributeValue{
 		"Token": {
-			S: &token,
+			S: &hashKey,
 		},
 	}
 
@@ -87,35 +431,96 @@ func (d *DynamoTokenManager) Authenticate(user, pass string) (bool, error) {
 	input.SetKey(key)
 
 	if err := input.Validate(); err != nil {
-		return false, err
+		return nil, err
 	}
 
 	output, err := d.dynamodb.GetItem(input)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	if len(output.Item) > 0 {
-		log.Printf("[DEBUG] User '%s' sent valid DynamoDB credentials", user)
-		return true, nil
+	return output.Item, nil
+}
+
+func itemToToken(item map[string]*dynamodb.AttributeValue) Token {
+	token := Token{}
+
+	// the key of legacy items is the token itself, so it cannot be used as the id
+	if v, ok := item["Token"]; ok && isHashKey(aws.StringValue(v.S)) {
+		token.ID = aws.StringValue(v.S)
+	}
+
+	if v, ok := item["User"]; ok {
+		token.User = aws.StringValue(v.S)
+	}
+
+	// legacy items store the token itself rather than its mask
+	if v, ok := item["Mask"]; ok {
+		token.MaskedToken = aws.StringValue(v.S)
+	} else if v, ok := item["Token"]; ok {
+		token.MaskedToken = MaskToken(aws.StringValue(v.S))
 	}
 
-	log.Printf("[DEBUG] User '%s' sent invalid DynamoDB credentials", user)
-	return false, nil
+	if v, ok := item["Name"]; ok {
+		token.Name = aws.StringValue(v.S)
+	}
+
+	if v, ok := item["Description"]; ok {
+		token.Description = aws.StringValue(v.S)
+	}
+
+	// tokens created before scopes were introduced are unrestricted
+	token.Scopes = AllScopes
+	if v, ok := item["Scopes"]; ok {
+		token.Scopes = aws.StringValueSlice(v.SS)
+	}
+
+	if v, ok := item["Repositories"]; ok {
+		token.Repositories = aws.StringValueSlice(v.SS)
+	}
+
+	if v, ok := item["CreatedAt"]; ok {
+		token.CreatedAt = parseUnixAttribute(v)
+	}
+
+	if v, ok := item["ExpiresAt"]; ok {
+		token.ExpiresAt = parseUnixAttribute(v)
+	}
+
+	return token
 }
 
-func convertToToken(user, pass string) string {
-	s := fmt.Sprintf("%s:%s", user, pass)
-	return base64.StdEncoding.EncodeToString([]byte(s))
+func isExpired(item map[string]*dynamodb.AttributeValue) bool {
+	v, ok := item["ExpiresAt"]
+	if !ok {
+		return false
+	}
+
+	return !time.Now().Before(parseUnixAttribute(v))
 }
 
-func randomString(length int) string {
-	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+func unixAttribute(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{
+		N: aws.String(strconv.FormatInt(t.Unix(), 10)),
+	}
+}
 
-	runes := make([]rune, length)
-	for i := range runes {
-		runes[i] = letters[rand.Intn(len(letters))]
+func parseUnixAttribute(v *dynamodb.AttributeValue) time.Time {
+	unix, err := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+	if err != nil {
+		return time.Time{}
 	}
 
-	return string(runes)
+	return time.Unix(unix, 0).UTC()
+}
+
+func (d *DynamoTokenManager) hashToken(token string) string {
+	mac := hmac.New(sha256.New, d.pepper)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func convertToToken(user, pass string) string {
+	s := fmt.Sprintf("%s:%s", user, pass)
+	return base64.StdEncoding.EncodeToString([]byte(s))
 }
@@ -1,12 +1,20 @@
-package auth
+package auth_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDynamoCreateToken(t *testing.T) {
@@ -14,7 +22,7 @@ func TestDynamoCreateToken(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
-	target := NewDynamoTokenManager("table", mockDynamoDB)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
 
 	validatePutItemInput := func(input *dynamodb.PutItemInput) {
 		if v, want := aws.StringValue(input.TableName), "table"; v != want {
@@ -28,6 +36,129 @@ func TestDynamoCreateToken(t *testing.T) {
 		if input.Item["Token"].S == nil {
 			t.Error("Column 'Token' was nil")
 		}
+
+		if input.Item["Mask"].S == nil {
+			t.Error("Column 'Mask' was nil")
+		}
+
+		if v, want := aws.StringValue(input.Item["Name"].S), "name"; v != want {
+			t.Errorf("Column 'Name' was '%v', expected '%v'", v, want)
+		}
+
+		if input.Item["CreatedAt"].N == nil {
+			t.Error("Column 'CreatedAt' was nil")
+		}
+
+		if _, ok := input.Item["Description"]; ok {
+			t.Error("Column 'Description' was set, expected it to be omitted")
+		}
+
+		if v, want := aws.StringValueSlice(input.Item["Scopes"].SS), []string{auth.ScopePull}; !assert.ObjectsAreEqual(v, want) {
+			t.Errorf("Column 'Scopes' was '%v', expected '%v'", v, want)
+		}
+
+		if v, want := aws.StringValueSlice(input.Item["Repositories"].SS), []string{"owner/*"}; !assert.ObjectsAreEqual(v, want) {
+			t.Errorf("Column 'Repositories' was '%v', expected '%v'", v, want)
+		}
+	}
+
+	var stored string
+	recordToken := func(input *dynamodb.PutItemInput) {
+		validatePutItemInput(input)
+		stored = aws.StringValue(input.Item["Token"].S)
+	}
+
+	mockDynamoDB.EXPECT().
+		PutItem(gomock.Any()).
+		Do(recordToken).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	options := auth.TokenOptions{
+		Name:         "name",
+		Scopes:       []string{auth.ScopePull},
+		Repositories: []string{"owner/*"},
+	}
+
+	token, err := target.CreateToken("user", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := auth.ValidateTokenFormat(token); err != nil {
+		t.Error(err)
+	}
+
+	// only the hash of the token may be stored
+	if v, want := stored, hashToken(token); v != want {
+		t.Errorf("Column 'Token' was '%v', expected '%v'", v, want)
+	}
+}
+
+func TestDynamoCreateTokenWithExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		createdAt, err := strconv.ParseInt(aws.StringValue(input.Item["CreatedAt"].N), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expiresAt, err := strconv.ParseInt(aws.StringValue(input.Item["ExpiresAt"].N), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := expiresAt-createdAt, int64(3600); v != want {
+			t.Errorf("Token lifetime was '%v', expected '%v'", v, want)
+		}
+	}
+
+	mockDynamoDB.EXPECT().
+		PutItem(gomock.Any()).
+		Do(validatePutItemInput).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	if _, err := target.CreateToken("user", auth.TokenOptions{ExpiresIn: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamoRotateToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	createdAt := time.Now().Add(-time.Hour)
+	item := map[string]*dynamodb.AttributeValue{
+		"User":      {S: aws.String("user")},
+		"Token":     {S: aws.String(hashToken("token"))},
+		"Name":      {S: aws.String("name")},
+		"CreatedAt": unixAttribute(createdAt),
+		"ExpiresAt": unixAttribute(createdAt.Add(time.Hour * 48)),
+	}
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		if v, want := aws.StringValue(input.Item["User"].S), "user"; v != want {
+			t.Errorf("Column 'User' was '%v', expected '%v'", v, want)
+		}
+
+		if v, want := aws.StringValue(input.Item["Name"].S), "name"; v != want {
+			t.Errorf("Column 'Name' was '%v', expected '%v'", v, want)
+		}
+
+		if input.Item["ExpiresAt"] == nil {
+			t.Error("Column 'ExpiresAt' was nil")
+		}
 	}
 
 	mockDynamoDB.EXPECT().
@@ -35,9 +166,158 @@ func TestDynamoCreateToken(t *testing.T) {
 		Do(validatePutItemInput).
 		Return(&dynamodb.PutItemOutput{}, nil)
 
-	if _, err := target.CreateToken("user"); err != nil {
+	validateUpdateItemInput := func(input *dynamodb.UpdateItemInput) {
+		if v, want := aws.StringValue(input.Key["Token"].S), hashToken("token"); v != want {
+			t.Errorf("Key 'Token' was '%v', expected '%v'", v, want)
+		}
+
+		expiresAt, err := strconv.ParseInt(aws.StringValue(input.ExpressionAttributeValues[":expiresAt"].N), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := expiresAt, time.Now().Add(time.Minute).Unix(); v > want {
+			t.Errorf("ExpiresAt was '%v', expected at most '%v'", v, want)
+		}
+	}
+
+	mockDynamoDB.EXPECT().
+		UpdateItem(gomock.Any()).
+		Do(validateUpdateItemInput).
+		Return(&dynamodb.UpdateItemOutput{}, nil)
+
+	if _, err := target.RotateToken(hashToken("token"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamoAuthenticatePrincipal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	// principals hold the hashed key of the token, never the token itself
+	tokenID := hashToken(base64.StdEncoding.EncodeToString([]byte("user:pass")))
+	expiresAt := time.Unix(time.Now().Add(time.Hour).Unix(), 0).UTC()
+
+	cases := []struct {
+		Name     string
+		Item     map[string]*dynamodb.AttributeValue
+		Expected *auth.Principal
+	}{
+		{
+			Name: "token without scopes",
+			Item: map[string]*dynamodb.AttributeValue{
+				"Token": {S: aws.String(tokenID)},
+				"User":  {S: aws.String("owner")},
+			},
+			Expected: &auth.Principal{
+				Permissions:   auth.Permissions{Scopes: auth.AllScopes},
+				Username:      "owner",
+				Authenticator: auth.AuthenticatorToken,
+				TokenID:       tokenID,
+			},
+		},
+		{
+			Name: "token with owner and scopes",
+			Item: map[string]*dynamodb.AttributeValue{
+				"Token":        {S: aws.String(tokenID)},
+				"User":         {S: aws.String("owner")},
+				"Scopes":       {SS: aws.StringSlice([]string{auth.ScopePull})},
+				"Repositories": {SS: aws.StringSlice([]string{"owner/*"})},
+			},
+			Expected: &auth.Principal{
+				Permissions: auth.Permissions{
+					Scopes:       []string{auth.ScopePull},
+					Repositories: []string{"owner/*"},
+				},
+				Username:      "owner",
+				Authenticator: auth.AuthenticatorToken,
+				TokenID:       tokenID,
+			},
+		},
+		{
+			Name: "token with expiry",
+			Item: map[string]*dynamodb.AttributeValue{
+				"Token":     {S: aws.String(tokenID)},
+				"User":      {S: aws.String("owner")},
+				"ExpiresAt": unixAttribute(expiresAt),
+			},
+			Expected: &auth.Principal{
+				Permissions:   auth.Permissions{Scopes: auth.AllScopes},
+				Username:      "owner",
+				Authenticator: auth.AuthenticatorToken,
+				TokenID:       tokenID,
+				ExpiresAt:     expiresAt,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		mockDynamoDB.EXPECT().
+			GetItem(gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: c.Item}, nil)
+
+		principal, ok, err := target.Authenticate("user", "pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Fatalf("case %s: result was 'false', expected 'true'", c.Name)
+		}
+
+		if !assert.ObjectsAreEqual(c.Expected, principal) {
+			t.Errorf("case %s: principal was '%+v', expected '%+v'", c.Name, principal, c.Expected)
+		}
+
+		if !principal.IsToken() {
+			t.Errorf("case %s: principal is not a token", c.Name)
+		}
+	}
+}
+
+func TestDynamoAuthenticateRejectsTokensWithoutUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	item := map[string]*dynamodb.AttributeValue{
+		"Token": {S: aws.String(hashToken(base64.StdEncoding.EncodeToString([]byte("user:pass"))))},
+	}
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	principal, ok, err := target.Authenticate("user", "pass")
+	if err != nil {
 		t.Fatal(err)
 	}
+
+	if ok {
+		t.Errorf("Token without a user was accepted as '%s'", principal.Username)
+	}
+}
+
+func TestDynamoRotateTokenNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	if _, err := target.RotateToken(hashToken("token"), time.Minute); err != auth.ErrTokenNotFound {
+		t.Fatalf("Error was '%v', expected '%v'", err, auth.ErrTokenNotFound)
+	}
 }
 
 func TestDynamoDeleteToken(t *testing.T) {
@@ -45,16 +325,15 @@ func TestDynamoDeleteToken(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
-	target := NewDynamoTokenManager("table", mockDynamoDB)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
 
+	keys := []string{}
 	validateDeleteItemInput := func(input *dynamodb.DeleteItemInput) {
 		if v, want := aws.StringValue(input.TableName), "table"; v != want {
 			t.Errorf("Table was '%v', expected '%v'", v, want)
 		}
 
-		if v, want := aws.StringValue(input.Key["Token"].S), "token"; v != want {
-			t.Errorf("Key 'Token' was '%v', expected '%v'", v, want)
-		}
+		keys = append(keys, aws.StringValue(input.Key["Token"].S))
 	}
 
 	mockDynamoDB.EXPECT().
@@ -62,9 +341,158 @@ func TestDynamoDeleteToken(t *testing.T) {
 		Do(validateDeleteItemInput).
 		Return(&dynamodb.DeleteItemOutput{}, nil)
 
-	if err := target.DeleteToken("token"); err != nil {
+	revoked := []string{}
+	target.OnRevoke = func(key string) {
+		revoked = append(revoked, key)
+	}
+
+	if err := target.DeleteToken(hashToken("token")); err != nil {
 		t.Fatal(err)
 	}
+
+	assert.Equal(t, []string{hashToken("token")}, keys)
+	assert.Equal(t, []string{hashToken("token")}, revoked)
+}
+
+func TestDynamoRevoke(t *testing.T) {
+	target := auth.NewDynamoTokenManager("table", "pepper", nil)
+
+	revoked := []string{}
+	target.OnRevoke = func(key string) {
+		revoked = append(revoked, key)
+	}
+
+	// legacy items are keyed by the token itself
+	target.Revoke(hashToken("token"))
+	target.Revoke("token")
+
+	assert.Equal(t, []string{hashToken("token"), hashToken("token")}, revoked)
+}
+
+func TestDynamoListTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	validateQueryInput := func(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) {
+		if v, want := aws.StringValue(input.TableName), "table"; v != want {
+			t.Errorf("Table was '%v', expected '%v'", v, want)
+		}
+
+		if v, want := aws.StringValue(input.IndexName), auth.TokensUserIndex; v != want {
+			t.Errorf("Index was '%v', expected '%v'", v, want)
+		}
+
+		if v, want := aws.StringValue(input.ExpressionAttributeValues[":user"].S), "user"; v != want {
+			t.Errorf("User was '%v', expected '%v'", v, want)
+		}
+
+		output := &dynamodb.QueryOutput{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{
+					"User":      {S: aws.String("user")},
+					"Token":     {S: aws.String(hashToken("0123456789abcdef"))},
+					"Mask":      {S: aws.String("dims...wxyz")},
+					"Name":      {S: aws.String("deploy")},
+					"Scopes":    {SS: aws.StringSlice([]string{auth.ScopePull})},
+					"CreatedAt": {N: aws.String("1500000000")},
+				},
+				{
+					"User":      {S: aws.String("user")},
+					"Token":     {S: aws.String("abcdefghijklmnop")},
+					"Name":      {S: aws.String("ci")},
+					"CreatedAt": {N: aws.String("1500000000")},
+				},
+			},
+		}
+
+		fn(output, true)
+	}
+
+	mockDynamoDB.EXPECT().
+		QueryPages(gomock.Any(), gomock.Any()).
+		Do(validateQueryInput).
+		Return(nil)
+
+	// listing tokens does not write to the table, so legacy items are listed without an id
+	tokens, err := target.ListTokens("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []auth.Token{
+		{
+			ID:          hashToken("0123456789abcdef"),
+			User:        "user",
+			Name:        "deploy",
+			MaskedToken: "dims...wxyz",
+			Scopes:      []string{auth.ScopePull},
+			CreatedAt:   time.Unix(1500000000, 0).UTC(),
+		},
+		{
+			User:        "user",
+			Name:        "ci",
+			MaskedToken: "abcd...mnop",
+			Scopes:      auth.AllScopes,
+			CreatedAt:   time.Unix(1500000000, 0).UTC(),
+		},
+	}
+
+	assert.Equal(t, expected, tokens)
+}
+
+func TestDynamoDeleteUserTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	queryPages := func(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) {
+		if v, want := aws.StringValue(input.ExpressionAttributeValues[":user"].S), "user"; v != want {
+			t.Errorf("User was '%v', expected '%v'", v, want)
+		}
+
+		fn(&dynamodb.QueryOutput{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{"Token": {S: aws.String(hashToken("a"))}},
+				{"Token": {S: aws.String(hashToken("b"))}, "ExpiresAt": {N: aws.String("1500000000")}},
+			},
+		}, true)
+	}
+
+	mockDynamoDB.EXPECT().
+		QueryPages(gomock.Any(), gomock.Any()).
+		Do(queryPages).
+		Return(nil)
+
+	keys := []string{}
+	validateDeleteItemInput := func(input *dynamodb.DeleteItemInput) {
+		keys = append(keys, aws.StringValue(input.Key["Token"].S))
+	}
+
+	mockDynamoDB.EXPECT().
+		DeleteItem(gomock.Any()).
+		Do(validateDeleteItemInput).
+		Return(&dynamodb.DeleteItemOutput{}, nil).
+		Times(2)
+
+	revoked := []string{}
+	target.OnRevoke = func(key string) {
+		revoked = append(revoked, key)
+	}
+
+	count, err := target.DeleteUserTokens("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expired tokens are deleted as well
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{hashToken("a"), hashToken("b")}, keys)
+	assert.Equal(t, keys, revoked)
 }
 
 func TestDynamoAuthenticate(t *testing.T) {
@@ -72,7 +500,7 @@ func TestDynamoAuthenticate(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
-	target := NewDynamoTokenManager("table", mockDynamoDB)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
 
 	validateGetItemInput := func(input *dynamodb.GetItemInput) {
 		if v, want := aws.StringValue(input.TableName), "table"; v != want {
@@ -100,17 +528,41 @@ func TestDynamoAuthenticate(t *testing.T) {
 			ExpectedResult: true,
 			Items: map[string]*dynamodb.AttributeValue{
 				"token": &dynamodb.AttributeValue{},
+				"User":  {S: aws.String("user")},
+			},
+		},
+		{
+			ExpectedResult: true,
+			Items: map[string]*dynamodb.AttributeValue{
+				"token":     &dynamodb.AttributeValue{},
+				"User":      {S: aws.String("user")},
+				"ExpiresAt": unixAttribute(time.Now().Add(time.Hour)),
+			},
+		},
+		{
+			ExpectedResult: false,
+			Items: map[string]*dynamodb.AttributeValue{
+				"token":     &dynamodb.AttributeValue{},
+				"User":      {S: aws.String("user")},
+				"ExpiresAt": unixAttribute(time.Now().Add(-time.Hour)),
 			},
 		},
 	}
 
 	for _, c := range cases {
+		// a miss on the hashed key is followed by a lookup of the legacy key
+		lookups := 1
+		if c.Items == nil {
+			lookups = 2
+		}
+
 		mockDynamoDB.EXPECT().
 			GetItem(gomock.Any()).
 			Do(validateGetItemInput).
-			Return(&dynamodb.GetItemOutput{Item: c.Items}, nil)
+			Return(&dynamodb.GetItemOutput{Item: c.Items}, nil).
+			Times(lookups)
 
-		ok, err := target.Authenticate("user", "pass")
+		_, ok, err := target.Authenticate("user", "pass")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -120,3 +572,148 @@ func TestDynamoAuthenticate(t *testing.T) {
 		}
 	}
 }
+
+func TestDynamoAuthenticateMigratesLegacyToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	token := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	legacyItem := map[string]*dynamodb.AttributeValue{
+		"User":  {S: aws.String("user")},
+		"Token": {S: aws.String(token)},
+	}
+
+	gomock.InOrder(
+		mockDynamoDB.EXPECT().
+			GetItem(gomock.Any()).
+			Return(&dynamodb.GetItemOutput{}, nil),
+		mockDynamoDB.EXPECT().
+			GetItem(gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: legacyItem}, nil),
+	)
+
+	validatePutItemInput := func(input *dynamodb.PutItemInput) {
+		if v, want := aws.StringValue(input.Item["Token"].S), hashToken(token); v != want {
+			t.Errorf("Column 'Token' was '%v', expected '%v'", v, want)
+		}
+
+		if v, want := aws.StringValue(input.Item["Mask"].S), auth.MaskToken(token); v != want {
+			t.Errorf("Column 'Mask' was '%v', expected '%v'", v, want)
+		}
+
+		if v, want := aws.StringValue(input.Item["User"].S), "user"; v != want {
+			t.Errorf("Column 'User' was '%v', expected '%v'", v, want)
+		}
+	}
+
+	mockDynamoDB.EXPECT().
+		PutItem(gomock.Any()).
+		Do(validatePutItemInput).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	validateDeleteItemInput := func(input *dynamodb.DeleteItemInput) {
+		if v, want := aws.StringValue(input.Key["Token"].S), token; v != want {
+			t.Errorf("Key 'Token' was '%v', expected '%v'", v, want)
+		}
+	}
+
+	mockDynamoDB.EXPECT().
+		DeleteItem(gomock.Any()).
+		Do(validateDeleteItemInput).
+		Return(&dynamodb.DeleteItemOutput{}, nil)
+
+	principal, ok, err := target.Authenticate("user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("Result was 'false', expected 'true'")
+	}
+
+	assert.Equal(t, hashToken(token), principal.TokenID)
+}
+
+func TestDynamoAuthenticateToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validateGetItemInput := func(input *dynamodb.GetItemInput) {
+		if v, want := aws.StringValue(input.Key["Token"].S), hashToken(token); v != want {
+			t.Errorf("Key 'Token' was '%v', expected '%v'", v, want)
+		}
+	}
+
+	// tokens in the current format are never looked up by their legacy key
+	mockDynamoDB.EXPECT().
+		GetItem(gomock.Any()).
+		Do(validateGetItemInput).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	_, ok, err := target.Authenticate("user", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Error("Result was 'true', expected 'false'")
+	}
+}
+
+func TestDynamoAuthenticateRejectsMalformedTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// no calls to dynamodb are expected
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	target := auth.NewDynamoTokenManager("table", "pepper", mockDynamoDB)
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pass := range []string{"dims_", "dims_abc", token[:len(token)-1] + "!", token + "a"} {
+		_, ok, err := target.Authenticate("user", pass)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok {
+			t.Errorf("Result for '%s' was 'true', expected 'false'", pass)
+		}
+	}
+
+	target.AllowLegacyTokens = false
+	_, ok, err := target.Authenticate("user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Error("Result for legacy token was 'true', expected 'false'")
+	}
+}
+
+func hashToken(token string) string {
+	mac := hmac.New(sha256.New, []byte("pepper"))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func unixAttribute(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{
+		N: aws.String(strconv.FormatInt(t.Unix(), 10)),
+	}
+}
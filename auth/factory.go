@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"time"
+)
+
+// Types of authenticators which can be configured in an AuthenticatorChainConfig
+const (
+	AuthenticatorTypeDynamoToken = "dynamo-token"
+	AuthenticatorTypeAuth0       = "auth0"
+	AuthenticatorTypeLDAP        = "ldap"
+	AuthenticatorTypeHtpasswd    = "htpasswd"
+	AuthenticatorTypeAWSIAM      = "aws-iam"
+)
+
+const (
+	defaultAuth0RateLimit   = time.Second / 2
+	defaultAuth0GroupsClaim = "groups"
+	defaultLDAPPoolSize     = 5
+	defaultLDAPTimeout      = time.Second * 10
+	defaultSTSEndpoint      = "https://sts.amazonaws.com"
+	defaultAWSIAMAudience   = "d.ims.io"
+)
+
+// AuthenticatorChainConfig lists the authenticators of a CompositeAuthenticator in the order they are tried
+type AuthenticatorChainConfig struct {
+	Authenticators []AuthenticatorConfig `json:"authenticators"`
+}
+
+// AuthenticatorConfig configures an authenticator of a chain
+type AuthenticatorConfig struct {
+	Type string `json:"type"`
+	// Name identifies the authenticator in logs; the type is used if it is empty
+	Name string `json:"name"`
+	// FailurePolicy is FailurePolicyAbort if it is empty
+	FailurePolicy string `json:"failure_policy"`
+	// Settings are decoded by the builder registered for the type
+	Settings json.RawMessage `json:"settings"`
+}
+
+// Auth0Settings are the settings of an 'auth0' authenticator
+type Auth0Settings struct {
+	Domain      string `json:"domain"`
+	ClientID    string `json:"client_id"`
+	Connection  string `json:"connection"`
+	GroupsClaim string `json:"groups_claim"`
+}
+
+// LDAPSettings are the settings of an 'ldap' authenticator, see LDAPConfig
+type LDAPSettings struct {
+	Address        string `json:"address"`
+	UserDNTemplate string `json:"user_dn_template"`
+	BaseDN         string `json:"base_dn"`
+	SearchFilter   string `json:"search_filter"`
+	BindDN         string `json:"bind_dn"`
+	BindPassword   string `json:"bind_password"`
+	StartTLS       bool   `json:"start_tls"`
+	Insecure       bool   `json:"insecure"`
+	PoolSize       int    `json:"pool_size"`
+}
+
+// HtpasswdSettings are the settings of an 'htpasswd' authenticator
+type HtpasswdSettings struct {
+	File string `json:"file"`
+}
+
+// AWSIAMSettings are the settings of an 'aws-iam' authenticator, see NewAWSIAMAuthenticator
+type AWSIAMSettings struct {
+	Accounts    []string `json:"accounts"`
+	Roles       []string `json:"roles"`
+	STSEndpoint string   `json:"sts_endpoint"`
+	Audience    string   `json:"audience"`
+}
+
+// LoadAuthenticatorChainConfig reads an AuthenticatorChainConfig from a json file.
+// Unknown fields are rejected so misspelled settings are not silently ignored.
+func LoadAuthenticatorChainConfig(path string) (*AuthenticatorChainConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config AuthenticatorChainConfig
+	if err := decodeStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("Failed to parse authenticator config '%s': %v", path, err)
+	}
+
+	return &config, nil
+}
+
+// AuthenticatorBuilder creates an authenticator from the settings in its AuthenticatorConfig
+type AuthenticatorBuilder func(settings json.RawMessage) (Authenticator, error)
+
+// AuthenticatorFactory builds CompositeAuthenticators from an AuthenticatorChainConfig
+// using the builder registered for the type of each authenticator.
+type AuthenticatorFactory struct {
+	builders map[string]AuthenticatorBuilder
+}
+
+// NewAuthenticatorFactory creates an AuthenticatorFactory with builders for the auth0, ldap, htpasswd, and aws-iam types.
+// Authenticators which share state with the rest of d.ims.io, such as the dynamo-token authenticator, must be registered by the caller.
+func NewAuthenticatorFactory() *AuthenticatorFactory {
+	f := &AuthenticatorFactory{
+		builders: map[string]AuthenticatorBuilder{},
+	}
+
+	f.Register(AuthenticatorTypeAuth0, buildAuth0Authenticator)
+	f.Register(AuthenticatorTypeLDAP, buildLDAPAuthenticator)
+	f.Register(AuthenticatorTypeHtpasswd, buildHtpasswdAuthenticator)
+	f.Register(AuthenticatorTypeAWSIAM, buildAWSIAMAuthenticator)
+	return f
+}
+
+// Register sets the builder of an authenticator type, replacing any existing builder
+func (f *AuthenticatorFactory) Register(authenticatorType string, builder AuthenticatorBuilder) {
+	f.builders[authenticatorType] = builder
+}
+
+// Types returns the authenticator types which have a builder, sorted by name
+func (f *AuthenticatorFactory) Types() []string {
+	types := make([]string, 0, len(f.builders))
+	for authenticatorType := range f.builders {
+		types = append(types, authenticatorType)
+	}
+
+	sort.Strings(types)
+	return types
+}
+
+func (f *AuthenticatorFactory) Build(config *AuthenticatorChainConfig) (*CompositeAuthenticator, error) {
+	if len(config.Authenticators) == 0 {
+		return nil, fmt.Errorf("No authenticators are configured")
+	}
+
+	chain := make([]ChainedAuthenticator, len(config.Authenticators))
+	for i, c := range config.Authenticators {
+		builder, ok := f.builders[c.Type]
+		if !ok {
+			return nil, fmt.Errorf("Authenticator %d has invalid type '%s': valid types are %v", i, c.Type, f.Types())
+		}
+
+		name := c.Name
+		if name == "" {
+			name = c.Type
+		}
+
+		policy := c.FailurePolicy
+		if policy == "" {
+			policy = FailurePolicyAbort
+		}
+
+		if err := ValidateFailurePolicy(policy); err != nil {
+			return nil, fmt.Errorf("Authenticator '%s': %v", name, err)
+		}
+
+		authenticator, err := builder(c.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("Authenticator '%s': %v", name, err)
+		}
+
+		chain[i] = ChainedAuthenticator{
+			Name:          name,
+			Authenticator: authenticator,
+			FailurePolicy: policy,
+		}
+	}
+
+	return NewAuthenticatorChain(chain...), nil
+}
+
+func buildAuth0Authenticator(settings json.RawMessage) (Authenticator, error) {
+	s := Auth0Settings{GroupsClaim: defaultAuth0GroupsClaim}
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+
+	if s.Domain == "" || s.ClientID == "" || s.Connection == "" {
+		return nil, fmt.Errorf("Auth0 domain, client_id, and connection must be set")
+	}
+
+	return NewAuth0Authenticator(s.Domain, s.ClientID, s.Connection, s.GroupsClaim, defaultAuth0RateLimit), nil
+}
+
+func buildLDAPAuthenticator(settings json.RawMessage) (Authenticator, error) {
+	s := LDAPSettings{PoolSize: defaultLDAPPoolSize}
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+
+	ldapAuthenticator, err := NewLDAPAuthenticatorFromSettings(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return ldapAuthenticator, nil
+}
+
+// NewLDAPAuthenticatorFromSettings creates and validates an LDAPAuthenticator.
+// The certificate of the ldap server is verified against the host of its address.
+func NewLDAPAuthenticatorFromSettings(s LDAPSettings) (*LDAPAuthenticator, error) {
+	host, _, err := net.SplitHostPort(s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid LDAP address '%s': %v", s.Address, err)
+	}
+
+	ldapAuthenticator := NewLDAPAuthenticator(LDAPConfig{
+		Address:        s.Address,
+		UserDNTemplate: s.UserDNTemplate,
+		BaseDN:         s.BaseDN,
+		SearchFilter:   s.SearchFilter,
+		BindDN:         s.BindDN,
+		BindPassword:   s.BindPassword,
+		StartTLS:       s.StartTLS,
+		Insecure:       s.Insecure,
+		TLSConfig:      &tls.Config{ServerName: host},
+		PoolSize:       s.PoolSize,
+		Timeout:        defaultLDAPTimeout,
+	})
+
+	if err := ldapAuthenticator.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ldapAuthenticator, nil
+}
+
+func buildHtpasswdAuthenticator(settings json.RawMessage) (Authenticator, error) {
+	var s HtpasswdSettings
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+
+	if s.File == "" {
+		return nil, fmt.Errorf("Htpasswd file must be set")
+	}
+
+	htpasswdAuthenticator, err := NewHtpasswdAuthenticator(s.File)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load htpasswd file '%s': %v", s.File, err)
+	}
+
+	return htpasswdAuthenticator, nil
+}
+
+func buildAWSIAMAuthenticator(settings json.RawMessage) (Authenticator, error) {
+	s := AWSIAMSettings{
+		STSEndpoint: defaultSTSEndpoint,
+		Audience:    defaultAWSIAMAudience,
+	}
+
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+
+	if len(s.Accounts) == 0 && len(s.Roles) == 0 {
+		return nil, fmt.Errorf("AWS IAM accounts and/or roles must be set")
+	}
+
+	awsIAMAuthenticator, err := NewAWSIAMAuthenticator(s.STSEndpoint, s.Audience, s.Accounts, s.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return awsIAMAuthenticator, nil
+}
+
+// decodeSettings decodes the settings of an authenticator into v, which holds the default settings
+func decodeSettings(settings json.RawMessage, v interface{}) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	if err := decodeStrict(settings, v); err != nil {
+		return fmt.Errorf("Invalid settings: %v", err)
+	}
+
+	return nil
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticatorFactoryBuild(t *testing.T) {
+	errBackend := errors.New("backend unavailable")
+
+	factory := NewAuthenticatorFactory()
+	factory.Register("failing", func(json.RawMessage) (Authenticator, error) {
+		return newTestAuthenticator(false, errBackend), nil
+	})
+
+	factory.Register("test", func(settings json.RawMessage) (Authenticator, error) {
+		var s struct {
+			Name string `json:"name"`
+		}
+
+		if err := decodeSettings(settings, &s); err != nil {
+			return nil, err
+		}
+
+		return newTestPrincipalAuthenticator(s.Name, true, nil), nil
+	})
+
+	config := &AuthenticatorChainConfig{
+		Authenticators: []AuthenticatorConfig{
+			{Type: "failing", FailurePolicy: FailurePolicyFallThrough},
+			{Type: "test", Settings: json.RawMessage(`{"name": "second"}`)},
+		},
+	}
+
+	target, err := factory.Build(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, ok, err := target.Authenticate("user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("Credentials were not accepted")
+	}
+
+	assert.Equal(t, "second", principal.Authenticator)
+
+	// the failing authenticator aborts the chain by default
+	config.Authenticators[0].FailurePolicy = ""
+	target, err = factory.Build(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := target.Authenticate("user", "pass"); err != errBackend {
+		t.Errorf("Error was '%v', expected '%v'", err, errBackend)
+	}
+}
+
+func TestAuthenticatorFactoryBuildErrors(t *testing.T) {
+	cases := map[string]AuthenticatorConfig{
+		"unknown type":     {Type: "unknown"},
+		"unknown policy":   {Type: AuthenticatorTypeHtpasswd, FailurePolicy: "retry"},
+		"unknown setting":  {Type: AuthenticatorTypeAuth0, Settings: json.RawMessage(`{"domain": "https://example.auth0.com", "client": "id"}`)},
+		"missing settings": {Type: AuthenticatorTypeAuth0},
+		"invalid ldap":     {Type: AuthenticatorTypeLDAP, Settings: json.RawMessage(`{"address": "ldap.example.com:389"}`)},
+		"missing htpasswd": {Type: AuthenticatorTypeHtpasswd, Settings: json.RawMessage(`{"file": "/does/not/exist"}`)},
+		"empty aws iam":    {Type: AuthenticatorTypeAWSIAM},
+	}
+
+	factory := NewAuthenticatorFactory()
+	for name, c := range cases {
+		if _, err := factory.Build(&AuthenticatorChainConfig{Authenticators: []AuthenticatorConfig{c}}); err == nil {
+			t.Errorf("case %s: error expected", name)
+		}
+	}
+
+	if _, err := factory.Build(&AuthenticatorChainConfig{}); err == nil {
+		t.Errorf("Error expected for an empty chain")
+	}
+}
+
+func TestLoadAuthenticatorChainConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authenticators")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	htpasswd := filepath.Join(dir, "htpasswd")
+	if err := ioutil.WriteFile(htpasswd, []byte(newTestHtpasswdEntry(t, "john.doe", "pass")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "authenticators.json")
+	content := `{
+		"authenticators": [
+			{"type": "htpasswd", "name": "local", "failure_policy": "fall-through", "settings": {"file": "` + htpasswd + `"}},
+			{"type": "aws-iam", "settings": {"accounts": ["111111111111"]}}
+		]
+	}`
+
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadAuthenticatorChainConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, config.Authenticators, 2)
+	assert.Equal(t, "local", config.Authenticators[0].Name)
+	assert.Equal(t, FailurePolicyFallThrough, config.Authenticators[0].FailurePolicy)
+
+	target, err := NewAuthenticatorFactory().Build(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, ok, err := target.Authenticate("john.doe", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("Credentials were not accepted")
+	}
+
+	assert.Equal(t, AuthenticatorHtpasswd, principal.Authenticator)
+
+	if err := ioutil.WriteFile(path, []byte(`{"authenticators": [], "unknown": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadAuthenticatorChainConfig(path); err == nil {
+		t.Errorf("Error expected for an unknown field")
+	}
+}
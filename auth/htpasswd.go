@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HtpasswdAuthenticator authenticates users against an Apache htpasswd file.
+// Only bcrypt entries (htpasswd -B) are supported; other entries are ignored.
+// The file is reloaded whenever its modification time or size changes.
+type HtpasswdAuthenticator struct {
+	path    string
+	mutex   sync.RWMutex
+	hashes  map[string][]byte
+	modTime time.Time
+	size    int64
+}
+
+// NewHtpasswdAuthenticator creates an HtpasswdAuthenticator and loads the htpasswd file at path
+func NewHtpasswdAuthenticator(path string) (*HtpasswdAuthenticator, error) {
+	a := &HtpasswdAuthenticator{
+		path: path,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.load(info); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *HtpasswdAuthenticator) Authenticate(username, password string) (*Principal, bool, error) {
+	log.Printf("[DEBUG] Attempting to authenticate user '%s' through htpasswd", username)
+
+	if err := a.reload(); err != nil {
+		return nil, false, err
+	}
+
+	a.mutex.RLock()
+	hash, ok := a.hashes[username]
+	a.mutex.RUnlock()
+
+	if !ok {
+		log.Printf("[DEBUG] User '%s' does not exist in htpasswd file", username)
+		return nil, false, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			log.Printf("[DEBUG] User '%s' sent invalid htpasswd credentials", username)
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	log.Printf("[DEBUG] User '%s' sent valid htpasswd credentials", username)
+	return NewPrincipal(username, AuthenticatorHtpasswd), true, nil
+}
+
+// reload loads the htpasswd file if it has changed since it was last loaded
+func (a *HtpasswdAuthenticator) reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.RLock()
+	changed := !info.ModTime().Equal(a.modTime) || info.Size() != a.size
+	a.mutex.RUnlock()
+
+	if !changed {
+		return nil
+	}
+
+	log.Printf("[INFO] Reloading htpasswd file '%s'", a.path)
+	return a.load(info)
+}
+
+func (a *HtpasswdAuthenticator) load(info os.FileInfo) error {
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hashes, err := parseHtpasswd(a.path, bufio.NewScanner(file))
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.hashes = hashes
+	a.modTime = info.ModTime()
+	a.size = info.Size()
+	return nil
+}
+
+func parseHtpasswd(path string, scanner *bufio.Scanner) (map[string][]byte, error) {
+	hashes := map[string][]byte{}
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		i := strings.Index(text, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid entry on line %d of htpasswd file '%s'", line, path)
+		}
+
+		username, hash := text[:i], text[i+1:]
+		if !isBcryptHash(hash) {
+			log.Printf("[WARN] Ignoring user '%s' in htpasswd file '%s': only bcrypt passwords are supported", username, path)
+			continue
+		}
+
+		hashes[username] = []byte(hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+func isBcryptHash(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestHtpasswdEntry(t *testing.T, username, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprintf("%s:%s\n", username, hash)
+}
+
+func writeTestHtpasswdFile(t *testing.T, path, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHtpasswdAuthenticator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := "# lab users\n"
+	content += newTestHtpasswdEntry(t, "john.doe", "secret")
+	content += "jane.doe:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"
+
+	path := filepath.Join(dir, "htpasswd")
+	writeTestHtpasswdFile(t, path, content, time.Now())
+
+	authenticator, err := NewHtpasswdAuthenticator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Username string
+		Password string
+		Expected bool
+	}{
+		{"john.doe", "secret", true},
+		{"john.doe", "wrong", false},
+		{"jane.doe", "secret", false},
+		{"unknown", "secret", false},
+	}
+
+	for _, c := range cases {
+		principal, valid, err := authenticator.Authenticate(c.Username, c.Password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := valid, c.Expected; v != want {
+			t.Errorf("Result for '%s:%s' was '%v', expected '%v'", c.Username, c.Password, v, want)
+		}
+
+		if valid {
+			assert.Equal(t, NewPrincipal(c.Username, AuthenticatorHtpasswd), principal)
+		}
+	}
+}
+
+func TestHtpasswdAuthenticatorReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "htpasswd")
+	writeTestHtpasswdFile(t, path, newTestHtpasswdEntry(t, "john.doe", "secret"), time.Now().Add(-time.Minute))
+
+	authenticator, err := NewHtpasswdAuthenticator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestHtpasswdFile(t, path, newTestHtpasswdEntry(t, "john.doe", "changed"), time.Now())
+
+	_, valid, err := authenticator.Authenticate("john.doe", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, false, valid)
+
+	_, valid, err = authenticator.Authenticate("john.doe", "changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, true, valid)
+}
+
+func TestNewHtpasswdAuthenticatorErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := NewHtpasswdAuthenticator(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Error expected when the htpasswd file does not exist")
+	}
+
+	path := filepath.Join(dir, "htpasswd")
+	writeTestHtpasswdFile(t, path, "john.doe\n", time.Now())
+	if _, err := NewHtpasswdAuthenticator(path); err == nil {
+		t.Error("Error expected when the htpasswd file is malformed")
+	}
+}
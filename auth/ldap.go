@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopkg.in/ldap.v2"
+)
+
+// LDAPConfig describes how the LDAPAuthenticator finds and binds as a user.
+// Users are located with either UserDNTemplate, or with SearchFilter under BaseDN.
+type LDAPConfig struct {
+	// Address is the host:port of the ldap server
+	Address string
+	// UserDNTemplate is used to build the user's DN, e.g. "uid=%s,ou=people,dc=example,dc=com"
+	UserDNTemplate string
+	// BaseDN is the root of the user search, e.g. "dc=example,dc=com"
+	BaseDN string
+	// SearchFilter is used to find the user's DN, e.g. "(sAMAccountName=%s)"
+	SearchFilter string
+	// BindDN and BindPassword are the service account credentials used to search for users
+	BindDN       string
+	BindPassword string
+	StartTLS     bool
+	// Insecure allows passwords to be sent to the ldap server in cleartext when StartTLS is not used
+	Insecure  bool
+	TLSConfig *tls.Config
+	// PoolSize is the maximum number of idle connections kept open to the ldap server
+	PoolSize int
+	Timeout  time.Duration
+}
+
+// LDAPAuthenticator authenticates users by binding as them against an ldap or active directory server
+type LDAPAuthenticator struct {
+	config LDAPConfig
+	pool   chan *ldap.Conn
+}
+
+func NewLDAPAuthenticator(config LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		config: config,
+		pool:   make(chan *ldap.Conn, config.PoolSize),
+	}
+}
+
+func (a *LDAPAuthenticator) Validate() error {
+	switch {
+	case a.config.Address == "":
+		return fmt.Errorf("LDAP address not set")
+	case a.config.UserDNTemplate == "" && a.config.SearchFilter == "":
+		return fmt.Errorf("LDAP user DN template or search filter must be set")
+	case a.config.UserDNTemplate != "" && a.config.SearchFilter != "":
+		return fmt.Errorf("LDAP user DN template and search filter cannot both be set")
+	case a.config.SearchFilter != "" && a.config.BaseDN == "":
+		return fmt.Errorf("LDAP base DN must be set when using a search filter")
+	case !a.config.StartTLS && !a.config.Insecure:
+		return fmt.Errorf("LDAP StartTLS must be enabled unless insecure connections are explicitly allowed")
+	}
+
+	return nil
+}
+
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*Principal, bool, error) {
+	log.Printf("[DEBUG] Attempting to authenticate user '%s' through LDAP", username)
+
+	// ldap servers treat a bind without a password as an anonymous bind, which always succeeds
+	if username == "" || password == "" {
+		return nil, false, nil
+	}
+
+	conn, isPooled, err := a.getConn()
+	if err != nil {
+		return nil, false, err
+	}
+
+	valid, err := a.authenticate(conn, username, password)
+
+	// the server may have closed the pooled connection after it was idle, so it is redialed once
+	if err != nil && isPooled && isConnectionError(err) {
+		log.Printf("[DEBUG] Redialing LDAP server after pooled connection failed: %v", err)
+		conn.Close()
+
+		conn, err = a.dial()
+		if err != nil {
+			return nil, false, err
+		}
+
+		valid, err = a.authenticate(conn, username, password)
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+
+	a.putConn(conn)
+
+	if !valid {
+		log.Printf("[DEBUG] User '%s' sent invalid LDAP credentials", username)
+		return nil, false, nil
+	}
+
+	log.Printf("[DEBUG] User '%s' sent valid LDAP credentials", username)
+	return NewPrincipal(username, AuthenticatorLDAP), true, nil
+}
+
+func (a *LDAPAuthenticator) authenticate(conn *ldap.Conn, username, password string) (bool, error) {
+	userDN, err := a.userDN(conn, username)
+	if err != nil {
+		return false, err
+	}
+
+	if userDN == "" {
+		return false, nil
+	}
+
+	if err := conn.Bind(userDN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// userDN returns the DN of the specified user, or an empty string if the user does not exist
+func (a *LDAPAuthenticator) userDN(conn *ldap.Conn, username string) (string, error) {
+	if a.config.UserDNTemplate != "" {
+		return fmt.Sprintf(a.config.UserDNTemplate, escapeDN(username)), nil
+	}
+
+	// the connection may still be bound as the previous user
+	if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+		return "", err
+	}
+
+	req := ldap.NewSearchRequest(
+		a.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		0,
+		false,
+		fmt.Sprintf(a.config.SearchFilter, ldap.EscapeFilter(username)),
+		[]string{"dn"},
+		nil)
+
+	result, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return "", fmt.Errorf("LDAP search for user '%s' returned multiple entries", username)
+		}
+
+		return "", err
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return "", nil
+	case 1:
+		return result.Entries[0].DN, nil
+	default:
+		return "", fmt.Errorf("LDAP search for user '%s' returned multiple entries", username)
+	}
+}
+
+// getConn returns an idle connection from the pool, or a new connection if the pool is empty.
+// The second return value is true if the connection came from the pool.
+func (a *LDAPAuthenticator) getConn() (*ldap.Conn, bool, error) {
+	select {
+	case conn := <-a.pool:
+		return conn, true, nil
+	default:
+		conn, err := a.dial()
+		return conn, false, err
+	}
+}
+
+func (a *LDAPAuthenticator) putConn(conn *ldap.Conn) {
+	select {
+	case a.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	conn, err := ldap.Dial("tcp", a.config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.config.Timeout > 0 {
+		conn.SetTimeout(a.config.Timeout)
+	}
+
+	if a.config.StartTLS {
+		if err := conn.StartTLS(a.config.TLSConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// isConnectionError returns true if err was caused by the connection to the ldap server rather than by a response from it
+func isConnectionError(err error) bool {
+	if ldapErr, ok := err.(*ldap.Error); ok {
+		return ldapErr.ResultCode == ldap.ErrorNetwork
+	}
+
+	// the connection returns the error which closed it without a result code
+	return strings.HasPrefix(err.Error(), "unable to read LDAP response packet")
+}
+
+// escapeDN escapes the special characters of an attribute value in a DN, as described in RFC 4514
+func escapeDN(value string) string {
+	var b strings.Builder
+	for i, r := range value {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		case (r == ' ' || r == '#') && i == 0:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == ' ' && i == len(value)-1:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
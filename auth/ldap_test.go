@@ -0,0 +1,406 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v2"
+)
+
+// ldapEntry is a user known to the testLDAPServer
+type ldapEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string]string
+}
+
+// testLDAPServer is an in-process stand-in for an ldap server.
+// It supports simple binds, StartTLS, and searches with a single equality filter.
+type testLDAPServer struct {
+	entries     []ldapEntry
+	requireTLS  bool
+	tlsConfig   *tls.Config
+	listener    net.Listener
+	connections int32
+	mutex       sync.Mutex
+	open        []net.Conn
+}
+
+func newTestLDAPServer(t *testing.T, entries ...ldapEntry) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testLDAPServer{
+		entries:   entries,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{newTestCertificate(t)}},
+		listener:  listener,
+	}
+
+	go s.serve()
+	return s
+}
+
+func (s *testLDAPServer) Address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) Close() {
+	s.listener.Close()
+}
+
+// closeConnections closes every open connection, like servers do to connections which have been idle for too long
+func (s *testLDAPServer) closeConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, conn := range s.open {
+		conn.Close()
+	}
+
+	s.open = nil
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		atomic.AddInt32(&s.connections, 1)
+		s.mutex.Lock()
+		s.open = append(s.open, conn)
+		s.mutex.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	isTLS := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		messageID := packet.Children[0].Value
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			name := request.Children[1].Value.(string)
+			password := request.Children[2].Data.String()
+
+			code := ldap.LDAPResultInvalidCredentials
+			switch {
+			case s.requireTLS && !isTLS:
+				code = ldap.LDAPResultConfidentialityRequired
+			case s.bind(name, password):
+				code = ldap.LDAPResultSuccess
+			}
+
+			writeLDAPResult(conn, messageID, ldap.ApplicationBindResponse, code)
+		case ldap.ApplicationSearchRequest:
+			filter := request.Children[6]
+			attribute := filter.Children[0].Value.(string)
+			value := filter.Children[1].Value.(string)
+
+			for _, entry := range s.entries {
+				if entry.Attributes[attribute] == value {
+					writeLDAPEntry(conn, messageID, entry)
+				}
+			}
+
+			writeLDAPResult(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)
+		case ldap.ApplicationExtendedRequest:
+			writeLDAPResult(conn, messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess)
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			conn = tlsConn
+			isTLS = true
+		default:
+			return
+		}
+	}
+}
+
+func (s *testLDAPServer) bind(dn, password string) bool {
+	for _, entry := range s.entries {
+		if entry.DN == dn && entry.Password == password {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeLDAPResult(conn net.Conn, messageID interface{}, tag ber.Tag, code int) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	packet.AppendChild(response)
+
+	conn.Write(packet.Bytes())
+}
+
+func writeLDAPEntry(conn net.Conn, messageID interface{}, entry ldapEntry) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "DN"))
+	response.AppendChild(ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes"))
+	packet.AppendChild(response)
+
+	conn.Write(packet.Bytes())
+}
+
+func newTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{cert}, PrivateKey: key}
+}
+
+var testLDAPEntries = []ldapEntry{
+	{
+		DN:         "uid=john.doe,ou=people,dc=example,dc=com",
+		Password:   "secret",
+		Attributes: map[string]string{"sAMAccountName": "john.doe"},
+	},
+	{
+		DN:       "cn=service,dc=example,dc=com",
+		Password: "service secret",
+	},
+}
+
+func TestLDAPAuthenticatorUserDNTemplate(t *testing.T) {
+	server := newTestLDAPServer(t, testLDAPEntries...)
+	defer server.Close()
+
+	authenticator := NewLDAPAuthenticator(LDAPConfig{
+		Address:        server.Address(),
+		UserDNTemplate: "uid=%s,ou=people,dc=example,dc=com",
+		PoolSize:       1,
+	})
+
+	cases := []struct {
+		Username string
+		Password string
+		Expected bool
+	}{
+		{"john.doe", "secret", true},
+		{"john.doe", "wrong", false},
+		{"john.doe", "", false},
+		{"jane.doe", "secret", false},
+	}
+
+	for _, c := range cases {
+		principal, valid, err := authenticator.Authenticate(c.Username, c.Password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := valid, c.Expected; v != want {
+			t.Errorf("Result for '%s:%s' was '%v', expected '%v'", c.Username, c.Password, v, want)
+		}
+
+		if valid {
+			assert.Equal(t, NewPrincipal(c.Username, AuthenticatorLDAP), principal)
+		}
+	}
+
+	// the pooled connection is reused for each attempt
+	if v, want := atomic.LoadInt32(&server.connections), int32(1); v != want {
+		t.Errorf("Server received %d connections, expected %d", v, want)
+	}
+}
+
+func TestLDAPAuthenticatorRedialsClosedConnections(t *testing.T) {
+	server := newTestLDAPServer(t, testLDAPEntries...)
+	defer server.Close()
+
+	authenticator := NewLDAPAuthenticator(LDAPConfig{
+		Address:      server.Address(),
+		BaseDN:       "dc=example,dc=com",
+		SearchFilter: "(sAMAccountName=%s)",
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "service secret",
+		PoolSize:     1,
+	})
+
+	for i := 0; i < 2; i++ {
+		_, valid, err := authenticator.Authenticate("john.doe", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, true, valid)
+
+		// the pooled connection is closed by the server before it is used again
+		server.closeConnections()
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	if v, want := atomic.LoadInt32(&server.connections), int32(2); v != want {
+		t.Errorf("Server received %d connections, expected %d", v, want)
+	}
+}
+
+func TestLDAPAuthenticatorSearchFilter(t *testing.T) {
+	server := newTestLDAPServer(t, testLDAPEntries...)
+	defer server.Close()
+
+	authenticator := NewLDAPAuthenticator(LDAPConfig{
+		Address:      server.Address(),
+		BaseDN:       "dc=example,dc=com",
+		SearchFilter: "(sAMAccountName=%s)",
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "service secret",
+	})
+
+	_, valid, err := authenticator.Authenticate("john.doe", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, true, valid)
+
+	_, valid, err = authenticator.Authenticate("jane.doe", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, false, valid)
+}
+
+func TestLDAPAuthenticatorSearchFilterInvalidBindCredentials(t *testing.T) {
+	server := newTestLDAPServer(t, testLDAPEntries...)
+	defer server.Close()
+
+	authenticator := NewLDAPAuthenticator(LDAPConfig{
+		Address:      server.Address(),
+		BaseDN:       "dc=example,dc=com",
+		SearchFilter: "(sAMAccountName=%s)",
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "wrong",
+	})
+
+	if _, _, err := authenticator.Authenticate("john.doe", "secret"); err == nil {
+		t.Fatal("Error expected when the service account credentials are invalid")
+	}
+}
+
+func TestLDAPAuthenticatorStartTLS(t *testing.T) {
+	server := newTestLDAPServer(t, testLDAPEntries...)
+	server.requireTLS = true
+	defer server.Close()
+
+	config := LDAPConfig{
+		Address:        server.Address(),
+		UserDNTemplate: "uid=%s,ou=people,dc=example,dc=com",
+	}
+
+	if _, valid, _ := NewLDAPAuthenticator(config).Authenticate("john.doe", "secret"); valid {
+		t.Fatal("Result without StartTLS was 'true', expected 'false'")
+	}
+
+	config.StartTLS = true
+	config.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+
+	_, valid, err := NewLDAPAuthenticator(config).Authenticate("john.doe", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, true, valid)
+}
+
+func TestLDAPAuthenticatorValidate(t *testing.T) {
+	cases := map[string]LDAPConfig{
+		"no address":          {UserDNTemplate: "uid=%s"},
+		"no user lookup":      {Address: "localhost:389"},
+		"both user lookups":   {Address: "localhost:389", UserDNTemplate: "uid=%s", SearchFilter: "(uid=%s)", BaseDN: "dc=example"},
+		"search without base": {Address: "localhost:389", SearchFilter: "(uid=%s)"},
+		"cleartext":           {Address: "localhost:389", UserDNTemplate: "uid=%s"},
+	}
+
+	for name, config := range cases {
+		if name != "cleartext" {
+			config.StartTLS = true
+		}
+
+		if err := NewLDAPAuthenticator(config).Validate(); err == nil {
+			t.Errorf("case %s: error expected", name)
+		}
+	}
+
+	for _, config := range []LDAPConfig{
+		{Address: "localhost:389", UserDNTemplate: "uid=%s", StartTLS: true},
+		{Address: "localhost:389", UserDNTemplate: "uid=%s", Insecure: true},
+	} {
+		if err := NewLDAPAuthenticator(config).Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEscapeDN(t *testing.T) {
+	cases := map[string]string{
+		"john.doe":     "john.doe",
+		"doe, john":    `doe\, john`,
+		"a+b=c":        `a\+b\=c`,
+		" john":        `\ john`,
+		"john ":        `john\ `,
+		"#john":        `\#john`,
+		`"john"<doe>;`: `\"john\"\<doe\>\;`,
+	}
+
+	for input, expected := range cases {
+		if v, want := escapeDN(input), expected; v != want {
+			t.Errorf("Escaped '%s' was '%s', expected '%s'", input, v, want)
+		}
+	}
+}
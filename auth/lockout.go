@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// LockoutTypeUser identifies lockouts of usernames
+	LockoutTypeUser = "user"
+	// LockoutTypeAddress identifies lockouts of source ip addresses
+	LockoutTypeAddress = "address"
+)
+
+// LockoutPolicy describes when a username or address is locked out.
+// Once Threshold consecutive failures have been counted, each further failure locks the
+// username or address out for BaseDelay, doubling with each failure up to MaxDelay.
+// Failures are forgotten once ResetAfter passes without a new failure.
+type LockoutPolicy struct {
+	Threshold  int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	ResetAfter time.Duration
+}
+
+var (
+	DefaultUserLockoutPolicy = LockoutPolicy{
+		Threshold:  5,
+		BaseDelay:  time.Second * 5,
+		MaxDelay:   time.Minute * 15,
+		ResetAfter: time.Hour,
+	}
+
+	// addresses are shared by users behind the same nat, so they are allowed more failures
+	DefaultAddressLockoutPolicy = LockoutPolicy{
+		Threshold:  20,
+		BaseDelay:  time.Second * 5,
+		MaxDelay:   time.Minute * 15,
+		ResetAfter: time.Hour,
+	}
+)
+
+// LockoutStatus describes the failures counted for a username or address
+type LockoutStatus struct {
+	Type string
+	Name string
+	// Address is the source address of the failures counted for a username
+	Address     string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Lockout counts failed logins per username and source address, and per source address,
+// and locks them out with exponential backoff to slow down password guessing.
+// A username is only locked out from the address its failures came from,
+// so failed logins from elsewhere cannot lock a user, e.g. an admin, out.
+// Failures are kept in memory, so each instance counts and locks out separately.
+type Lockout struct {
+	// TrustForwardedFor causes the AuthDecorator to use the address which the load balancer
+	// appended to the X-Forwarded-For header as the source address of requests.
+	// Clients can set the header themselves, so it must only be enabled behind a load balancer.
+	TrustForwardedFor bool
+	policies          map[string]LockoutPolicy
+	mutex             sync.Mutex
+	statuses          map[lockoutKey]*LockoutStatus
+	sweptAt           time.Time
+	now               func() time.Time
+}
+
+type lockoutKey struct {
+	Type    string
+	Name    string
+	Address string
+}
+
+func NewLockout(userPolicy, addressPolicy LockoutPolicy) *Lockout {
+	return &Lockout{
+		policies: map[string]LockoutPolicy{
+			LockoutTypeUser:    userPolicy,
+			LockoutTypeAddress: addressPolicy,
+		},
+		statuses: map[lockoutKey]*LockoutStatus{},
+		now:      time.Now,
+	}
+}
+
+// Check returns how long the user must wait before attempting to log in from address.
+// A zero duration means the attempt is allowed.
+func (l *Lockout) Check(user, address string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	var wait time.Duration
+	for _, key := range l.keys(user, address) {
+		if status := l.status(key, now); status != nil && status.LockedUntil.After(now) {
+			if d := status.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+
+	return wait
+}
+
+// Fail counts a failed login by the user from address
+func (l *Lockout) Fail(user, address string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	for _, key := range l.keys(user, address) {
+		status := l.status(key, now)
+		if status == nil {
+			status = &LockoutStatus{Type: key.Type, Name: key.Name, Address: key.Address}
+			l.statuses[key] = status
+		}
+
+		status.Failures++
+		status.LastFailure = now
+
+		policy := l.policies[key.Type]
+		if excess := status.Failures - policy.Threshold; excess > 0 {
+			status.LockedUntil = now.Add(backoff(policy, excess))
+		}
+	}
+}
+
+// Succeed forgets the failures of the user from address.
+// Failures of the address are kept, otherwise a single valid account could be used to reset them.
+func (l *Lockout) Succeed(user, address string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.statuses, lockoutKey{LockoutTypeUser, user, address})
+}
+
+// Lockouts returns the usernames and addresses with recent failures, sorted by type, name, and address
+func (l *Lockout) Lockouts() []LockoutStatus {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	statuses := []LockoutStatus{}
+	for key := range l.statuses {
+		if status := l.status(key, now); status != nil {
+			statuses = append(statuses, *status)
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Type != statuses[j].Type {
+			return statuses[i].Type < statuses[j].Type
+		}
+
+		if statuses[i].Name != statuses[j].Name {
+			return statuses[i].Name < statuses[j].Name
+		}
+
+		return statuses[i].Address < statuses[j].Address
+	})
+
+	return statuses
+}
+
+// Clear forgets the failures of a username from every address, or the failures of an address.
+// It returns false if no failures were counted for it.
+func (l *Lockout) Clear(lockoutType, name string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	cleared := false
+	for key := range l.statuses {
+		if key.Type != lockoutType || key.Name != name {
+			continue
+		}
+
+		if l.status(key, now) != nil {
+			cleared = true
+		}
+
+		delete(l.statuses, key)
+	}
+
+	return cleared
+}
+
+func (l *Lockout) keys(user, address string) []lockoutKey {
+	keys := []lockoutKey{}
+	if user != "" {
+		keys = append(keys, lockoutKey{LockoutTypeUser, user, address})
+	}
+
+	if address != "" {
+		keys = append(keys, lockoutKey{LockoutTypeAddress, address, ""})
+	}
+
+	return keys
+}
+
+// status returns the status of key, or nil if its failures have been forgotten
+func (l *Lockout) status(key lockoutKey, now time.Time) *LockoutStatus {
+	status, ok := l.statuses[key]
+	if !ok {
+		return nil
+	}
+
+	policy := l.policies[key.Type]
+	if now.Sub(status.LastFailure) > policy.ResetAfter && !status.LockedUntil.After(now) {
+		delete(l.statuses, key)
+		return nil
+	}
+
+	return status
+}
+
+// sweep forgets the failures which have expired, so guessing many usernames does not grow memory without bound
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < time.Minute {
+		return
+	}
+
+	for key := range l.statuses {
+		l.status(key, now)
+	}
+
+	l.sweptAt = now
+}
+
+// backoff returns how long to lock out after the specified number of failures past the threshold
+func backoff(policy LockoutPolicy, excess int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < excess; i++ {
+		delay *= 2
+		if delay >= policy.MaxDelay {
+			return policy.MaxDelay
+		}
+	}
+
+	if delay > policy.MaxDelay {
+		return policy.MaxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLockout() (*Lockout, *time.Time) {
+	now := time.Now()
+	policy := LockoutPolicy{
+		Threshold:  2,
+		BaseDelay:  time.Second,
+		MaxDelay:   time.Second * 3,
+		ResetAfter: time.Minute,
+	}
+
+	lockout := NewLockout(policy, policy)
+	lockout.now = func() time.Time { return now }
+	return lockout, &now
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	lockout, _ := newTestLockout()
+
+	// the first Threshold failures are not locked out
+	expected := []time.Duration{0, 0, 0, time.Second, time.Second * 2, time.Second * 3, time.Second * 3}
+	for i, want := range expected {
+		if v := lockout.Check("user", ""); v != want {
+			t.Errorf("Wait after %d failures was %v, expected %v", i, v, want)
+		}
+
+		lockout.Fail("user", "")
+	}
+}
+
+func TestLockoutSucceedKeepsAddressFailures(t *testing.T) {
+	lockout, _ := newTestLockout()
+	for i := 0; i < 3; i++ {
+		lockout.Fail("user", "10.0.0.1")
+	}
+
+	lockout.Succeed("user", "10.0.0.1")
+
+	assert.Equal(t, time.Duration(0), lockout.Check("user", ""))
+	assert.Equal(t, time.Second, lockout.Check("other", "10.0.0.1"))
+}
+
+func TestLockoutUserOnlyFromFailingAddress(t *testing.T) {
+	lockout, _ := newTestLockout()
+	for i := 0; i < 3; i++ {
+		lockout.Fail("admin", "10.0.0.1")
+	}
+
+	// failures from one address cannot lock the user out everywhere
+	assert.Equal(t, time.Second, lockout.Check("admin", "10.0.0.1"))
+	assert.Equal(t, time.Duration(0), lockout.Check("admin", "10.0.0.2"))
+
+	for i := 0; i < 3; i++ {
+		lockout.Fail("admin", "10.0.0.2")
+	}
+
+	// a successful login only resets the failures from its own address
+	lockout.Succeed("admin", "10.0.0.2")
+	assert.Equal(t, time.Second, lockout.Check("admin", "10.0.0.1"))
+}
+
+func TestLockoutReset(t *testing.T) {
+	lockout, now := newTestLockout()
+	for i := 0; i < 3; i++ {
+		lockout.Fail("user", "10.0.0.1")
+	}
+
+	*now = now.Add(time.Minute * 2)
+	assert.Equal(t, time.Duration(0), lockout.Check("user", "10.0.0.1"))
+	assert.Len(t, lockout.Lockouts(), 0)
+}
+
+func TestLockoutListAndClear(t *testing.T) {
+	lockout, now := newTestLockout()
+	for i := 0; i < 3; i++ {
+		lockout.Fail("user", "10.0.0.1")
+	}
+
+	lockout.Fail("user", "10.0.0.2")
+
+	expected := []LockoutStatus{
+		{Type: LockoutTypeAddress, Name: "10.0.0.1", Failures: 3, LastFailure: *now, LockedUntil: now.Add(time.Second)},
+		{Type: LockoutTypeAddress, Name: "10.0.0.2", Failures: 1, LastFailure: *now},
+		{Type: LockoutTypeUser, Name: "user", Address: "10.0.0.1", Failures: 3, LastFailure: *now, LockedUntil: now.Add(time.Second)},
+		{Type: LockoutTypeUser, Name: "user", Address: "10.0.0.2", Failures: 1, LastFailure: *now},
+	}
+
+	assert.Equal(t, expected, lockout.Lockouts())
+
+	// clearing a username clears its failures from every address
+	assert.True(t, lockout.Clear(LockoutTypeUser, "user"))
+	assert.False(t, lockout.Clear(LockoutTypeUser, "user"))
+	assert.Equal(t, expected[:2], lockout.Lockouts())
+}
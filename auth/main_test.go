@@ -19,7 +19,7 @@ type Handler func(w http.ResponseWriter, r *http.Request)
 
 func newAuth0AuthenticatorAndServer(handler Handler) (*Auth0Authenticator, *httptest.Server) {
 	server := httptest.NewServer(http.HandlerFunc(handler))
-	auth0Manager := NewAuth0Authenticator(server.URL, "", "", time.Second/2)
+	auth0Manager := NewAuth0Authenticator(server.URL, "", "", "groups", time.Second/2)
 
 	return auth0Manager, server
 }
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// DefaultJWKSRefreshInterval is how long an issuer's signing keys are cached
+	DefaultJWKSRefreshInterval = time.Hour
+	// minJWKSRefreshInterval limits how often unknown key ids can trigger a refresh of the signing keys
+	minJWKSRefreshInterval = time.Minute
+)
+
+// OIDCAuthenticator validates OIDC access tokens issued as JWTs.
+// Tokens are verified locally using the issuer's signing keys, which are fetched from its JWKS endpoint.
+// The keys are cached and refreshed periodically, or when a token is signed by an unknown key.
+// If the keys cannot be refreshed, the cached keys are used until the issuer is available again.
+type OIDCAuthenticator struct {
+	issuer          string
+	audience        string
+	usernameClaim   string
+	refreshInterval time.Duration
+	client          *http.Client
+	mutex           sync.Mutex
+	keys            map[string]interface{}
+	fetchedAt       time.Time
+	attemptedAt     time.Time
+	fetchErr        error
+}
+
+// NewOIDCAuthenticator creates an OIDCAuthenticator for the specified issuer.
+// Tokens must include audience in their 'aud' claim; otherwise, tokens the issuer grants to any of its clients would be accepted.
+// The value of usernameClaim (e.g. 'sub' or 'email') is used as the username.
+func NewOIDCAuthenticator(issuer, audience, usernameClaim string, refreshInterval time.Duration) (*OIDCAuthenticator, error) {
+	if audience == "" {
+		return nil, fmt.Errorf("An audience is required to authenticate tokens of OIDC issuer '%s'", issuer)
+	}
+
+	authenticator := &OIDCAuthenticator{
+		issuer:          strings.TrimSuffix(issuer, "/"),
+		audience:        audience,
+		usernameClaim:   usernameClaim,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: time.Second * 10},
+		keys:            map[string]interface{}{},
+	}
+
+	return authenticator, nil
+}
+
+// jwksError is returned from the key func when the signing keys cannot be fetched,
+// so it can be told apart from an invalid token
+type jwksError struct {
+	err error
+}
+
+func (e *jwksError) Error() string {
+	return fmt.Sprintf("Failed to fetch signing keys: %v", e.err)
+}
+
+func (o *OIDCAuthenticator) AuthenticateBearer(tokenString string) (*Principal, bool, error) {
+	log.Printf("[DEBUG] Attempting to authenticate bearer token through OIDC")
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, o.keyFunc); err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok {
+			if je, ok := ve.Inner.(*jwksError); ok {
+				return nil, false, je
+			}
+		}
+
+		log.Printf("[DEBUG] Invalid OIDC token: %v", err)
+		return nil, false, nil
+	}
+
+	// tokens without an expiry pass validation, but would be accepted forever
+	if _, ok := claims["exp"]; !ok {
+		log.Printf("[DEBUG] OIDC token does not expire")
+		return nil, false, nil
+	}
+
+	if !claims.VerifyIssuer(o.issuer, true) {
+		log.Printf("[DEBUG] OIDC token has an unexpected issuer")
+		return nil, false, nil
+	}
+
+	if !hasAudience(claims, o.audience) {
+		log.Printf("[DEBUG] OIDC token has an unexpected audience")
+		return nil, false, nil
+	}
+
+	username, ok := claims[o.usernameClaim].(string)
+	if !ok || username == "" {
+		log.Printf("[DEBUG] OIDC token is missing the '%s' claim", o.usernameClaim)
+		return nil, false, nil
+	}
+
+	log.Printf("[DEBUG] User '%s' sent a valid OIDC token", username)
+	return NewPrincipal(username, AuthenticatorOIDC), true, nil
+}
+
+func (o *OIDCAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+	default:
+		return nil, fmt.Errorf("Unexpected signing method '%s'", token.Method.Alg())
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	return o.getKey(kid)
+}
+
+func (o *OIDCAuthenticator) getKey(kid string) (interface{}, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// the issuer may have rotated its keys; refresh the cache if the key is unknown
+	key, ok := o.keys[kid]
+	isStale := time.Since(o.fetchedAt) > o.refreshInterval
+	canRefresh := time.Since(o.attemptedAt) > minJWKSRefreshInterval
+	if canRefresh && (isStale || !ok) {
+		o.attemptedAt = time.Now()
+		keys, err := o.fetchKeys()
+		o.fetchErr = err
+
+		switch {
+		case err == nil:
+			o.keys = keys
+			o.fetchedAt = time.Now()
+			key, ok = o.keys[kid]
+		case ok:
+			// an outage of the issuer should not prevent tokens signed by known keys from authenticating
+			log.Printf("[WARN] Using cached OIDC signing key '%s': %v", kid, err)
+		default:
+			return nil, &jwksError{err}
+		}
+	}
+
+	if !ok {
+		if o.fetchErr != nil {
+			return nil, &jwksError{o.fetchErr}
+		}
+
+		return nil, fmt.Errorf("Unknown signing key '%s'", kid)
+	}
+
+	return key, nil
+}
+
+type openIDConfiguration struct {
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	KID string `json:"kid"`
+	KTY string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	CRV string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (o *OIDCAuthenticator) fetchKeys() (map[string]interface{}, error) {
+	log.Printf("[DEBUG] Fetching OIDC signing keys for issuer '%s'", o.issuer)
+
+	var config openIDConfiguration
+	if err := o.getJSON(o.issuer+"/.well-known/openid-configuration", &config); err != nil {
+		return nil, err
+	}
+
+	if config.JWKSURI == "" {
+		return nil, fmt.Errorf("Issuer '%s' does not have a jwks_uri", o.issuer)
+	}
+
+	var jwks jsonWebKeySet
+	if err := o.getJSON(config.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("[WARN] Skipping OIDC signing key '%s': %v", jwk.KID, err)
+			continue
+		}
+
+		keys[jwk.KID] = key
+	}
+
+	return keys, nil
+}
+
+func (o *OIDCAuthenticator) getJSON(url string, v interface{}) error {
+	resp, err := o.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (j jsonWebKey) publicKey() (interface{}, error) {
+	switch j.KTY {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.CRV {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported curve '%s'", j.CRV)
+		}
+
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported key type '%s'", j.KTY)
+	}
+}
+
+// hasAudience checks the 'aud' claim, which may be a single string or a list of strings
+func hasAudience(claims jwt.MapClaims, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
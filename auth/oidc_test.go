@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIssuer serves the discovery document and signing keys of an oidc issuer
+type testIssuer struct {
+	*httptest.Server
+	keys         map[string]*rsa.PrivateKey
+	fetchedCount int
+}
+
+func newTestIssuer(t *testing.T, kids ...string) *testIssuer {
+	issuer := &testIssuer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		issuer.addKey(t, kid)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(openIDConfiguration{JWKSURI: issuer.URL + "/jwks"})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.fetchedCount++
+
+		jwks := jsonWebKeySet{}
+		for kid, key := range issuer.keys {
+			jwks.Keys = append(jwks.Keys, jsonWebKey{
+				KID: kid,
+				KTY: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+
+		json.NewEncoder(w).Encode(jwks)
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i.keys[kid] = key
+}
+
+func (i *testIssuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(i.keys[kid])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func (i *testIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   i.URL,
+		"aud":   []string{"d.ims.io", "other"},
+		"sub":   "subject",
+		"email": "john.doe@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newTestOIDCAuthenticator(t *testing.T, issuer, usernameClaim string) *OIDCAuthenticator {
+	authenticator, err := NewOIDCAuthenticator(issuer, "d.ims.io", usernameClaim, DefaultJWKSRefreshInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return authenticator
+}
+
+func TestNewOIDCAuthenticatorRequiresAudience(t *testing.T) {
+	if _, err := NewOIDCAuthenticator("https://example.com", "", "sub", DefaultJWKSRefreshInterval); err == nil {
+		t.Fatal("Error expected without an audience")
+	}
+}
+
+func TestOIDCAuthenticatorAuthenticateBearer(t *testing.T) {
+	issuer := newTestIssuer(t, "key")
+	defer issuer.Close()
+
+	authenticator := newTestOIDCAuthenticator(t, issuer.URL, "email")
+
+	principal, valid, err := authenticator.AuthenticateBearer(issuer.sign(t, "key", issuer.claims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, true, valid)
+	assert.Equal(t, NewPrincipal("john.doe@example.com", AuthenticatorOIDC), principal)
+
+	// the signing keys are cached
+	if _, _, err := authenticator.AuthenticateBearer(issuer.sign(t, "key", issuer.claims())); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, issuer.fetchedCount)
+}
+
+func TestOIDCAuthenticatorInvalidTokens(t *testing.T) {
+	issuer := newTestIssuer(t, "key")
+	defer issuer.Close()
+
+	other := newTestIssuer(t, "key")
+	defer other.Close()
+
+	authenticator := newTestOIDCAuthenticator(t, issuer.URL, "email")
+
+	cases := map[string]func() string{
+		"malformed": func() string {
+			return "not.a.token"
+		},
+		"expired": func() string {
+			claims := issuer.claims()
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return issuer.sign(t, "key", claims)
+		},
+		"wrong issuer": func() string {
+			claims := issuer.claims()
+			claims["iss"] = "https://example.com"
+			return issuer.sign(t, "key", claims)
+		},
+		"no expiry": func() string {
+			claims := issuer.claims()
+			delete(claims, "exp")
+			return issuer.sign(t, "key", claims)
+		},
+		"no audience": func() string {
+			claims := issuer.claims()
+			delete(claims, "aud")
+			return issuer.sign(t, "key", claims)
+		},
+		"wrong audience": func() string {
+			claims := issuer.claims()
+			claims["aud"] = "other"
+			return issuer.sign(t, "key", claims)
+		},
+		"missing username claim": func() string {
+			claims := issuer.claims()
+			delete(claims, "email")
+			return issuer.sign(t, "key", claims)
+		},
+		"wrong signing key": func() string {
+			return other.sign(t, "key", issuer.claims())
+		},
+		"unsigned": func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, issuer.claims())
+			signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return signed
+		},
+	}
+
+	for name, token := range cases {
+		_, valid, err := authenticator.AuthenticateBearer(token())
+		if err != nil {
+			t.Fatalf("case %s: %v", name, err)
+		}
+
+		if valid {
+			t.Errorf("case %s: result was 'true', expected 'false'", name)
+		}
+	}
+}
+
+func TestOIDCAuthenticatorKeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t, "old")
+	defer issuer.Close()
+
+	authenticator := newTestOIDCAuthenticator(t, issuer.URL, "sub")
+	if _, _, err := authenticator.AuthenticateBearer(issuer.sign(t, "old", issuer.claims())); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer.addKey(t, "new")
+	authenticator.attemptedAt = time.Now().Add(-minJWKSRefreshInterval * 2)
+
+	principal, valid, err := authenticator.AuthenticateBearer(issuer.sign(t, "new", issuer.claims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, true, valid)
+	assert.Equal(t, "subject", principal.Username)
+	assert.Equal(t, 2, issuer.fetchedCount)
+}
+
+func TestOIDCAuthenticatorIssuerUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t, "key")
+	token := issuer.sign(t, "key", issuer.claims())
+	issuer.Close()
+
+	authenticator := newTestOIDCAuthenticator(t, issuer.URL, "sub")
+	if _, _, err := authenticator.AuthenticateBearer(token); err == nil {
+		t.Fatal("Error expected when the issuer is unavailable")
+	}
+}
+
+func TestOIDCAuthenticatorUsesCachedKeysWhenIssuerUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t, "key")
+	token := issuer.sign(t, "key", issuer.claims())
+
+	authenticator := newTestOIDCAuthenticator(t, issuer.URL, "sub")
+	if _, _, err := authenticator.AuthenticateBearer(token); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cached keys are stale, but the issuer cannot be reached to refresh them
+	issuer.addKey(t, "other")
+	issuer.Close()
+	authenticator.fetchedAt = time.Now().Add(-DefaultJWKSRefreshInterval * 2)
+	authenticator.attemptedAt = authenticator.fetchedAt
+
+	principal, valid, err := authenticator.AuthenticateBearer(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, true, valid)
+	assert.Equal(t, "subject", principal.Username)
+
+	// unknown keys still cannot be verified
+	if _, _, err := authenticator.AuthenticateBearer(issuer.sign(t, "other", issuer.claims())); err == nil {
+		t.Fatal("Error expected for unknown keys when the issuer is unavailable")
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOwnerNotFound = errors.New("Owner not found")
+	ErrOwnerExists   = errors.New("Owner already exists")
+	ErrOwnerModified = errors.New("Owner was modified by another request")
+)
+
+// Roles of the members of an owner, from most to least privileged
+const (
+	// RoleMaintainer can manage the members of the owner, as well as everything a developer can do
+	RoleMaintainer = "maintainer"
+	// RoleDeveloper can create, delete, and push to the repositories of the owner
+	RoleDeveloper = "developer"
+	// RoleReader is a member of the owner who cannot change its repositories
+	RoleReader = "reader"
+)
+
+var AllRoles = []string{RoleMaintainer, RoleDeveloper, RoleReader}
+
+// Owner is a namespace of repositories, e.g. 'carbon' owns 'carbon/*'.
+// Only members of an owner can create, delete, or push to its repositories.
+type Owner struct {
+	Name string
+	// Members maps usernames to their roles
+	Members map[string]string
+	// Groups maps groups to the role of the users in them
+	Groups map[string]string
+	// ServiceAccounts maps the names of the owner's service accounts to them
+	ServiceAccounts map[string]*ServiceAccount
+	// Version is incremented each time the owner is stored, so concurrent changes do not overwrite each other.
+	// Owners which have never been stored, or were stored before versions were introduced, have version 0.
+	Version int64
+}
+
+func NewOwner(name string) *Owner {
+	return &Owner{
+		Name:            name,
+		Members:         map[string]string{},
+		Groups:          map[string]string{},
+		ServiceAccounts: map[string]*ServiceAccount{},
+	}
+}
+
+// Role returns the most privileged role the principal has through its username, groups, or service account.
+// An empty string is returned if the principal is not a member.
+func (o *Owner) Role(p *Principal) string {
+	roles := []string{}
+	if role, ok := o.Members[p.Username]; ok && p.Username != "" {
+		roles = append(roles, role)
+	}
+
+	if owner, name, ok := ParseServiceAccountUsername(p.Username); ok && owner == o.Name {
+		if serviceAccount, ok := o.ServiceAccounts[name]; ok {
+			roles = append(roles, serviceAccount.Role)
+		}
+	}
+
+	for _, group := range p.Groups {
+		if role, ok := o.Groups[group]; ok {
+			roles = append(roles, role)
+		}
+	}
+
+	for _, role := range AllRoles {
+		if contains(roles, role) {
+			return role
+		}
+	}
+
+	return ""
+}
+
+// CanWrite returns true if the principal can create, delete, or push to the repositories of the owner
+func (o *Owner) CanWrite(p *Principal) bool {
+	role := o.Role(p)
+	return role == RoleMaintainer || role == RoleDeveloper
+}
+
+// CanManage returns true if the principal can manage the members of the owner
+func (o *Owner) CanManage(p *Principal) bool {
+	return o.Role(p) == RoleMaintainer
+}
+
+// HasMaintainer returns true if at least one user or group maintains the owner
+func (o *Owner) HasMaintainer() bool {
+	for _, roles := range []map[string]string{o.Members, o.Groups} {
+		for _, role := range roles {
+			if role == RoleMaintainer {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func ValidateRole(role string) error {
+	if !contains(AllRoles, role) {
+		return fmt.Errorf("Invalid role '%s': valid roles are %v", role, AllRoles)
+	}
+
+	return nil
+}
+
+type OwnerManager interface {
+	// GetOwner returns ErrOwnerNotFound if the owner has not been registered
+	GetOwner(name string) (*Owner, error)
+	// CreateOwner returns ErrOwnerExists if the owner has already been registered
+	CreateOwner(owner *Owner) error
+	// PutOwner replaces the owner and increments its version.
+	// It returns ErrOwnerModified if the stored owner no longer has the version the owner was read with.
+	PutOwner(owner *Owner) error
+	ListOwners() ([]string, error)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestOwnerRole(t *testing.T) {
+	owner := NewOwner("carbon")
+	owner.Members["john.doe"] = RoleReader
+	owner.Members["jane.doe"] = RoleMaintainer
+	owner.Groups["carbon-developers"] = RoleDeveloper
+	owner.ServiceAccounts["ci"] = &ServiceAccount{Name: "ci", Role: RoleDeveloper}
+
+	cases := []struct {
+		Name      string
+		Principal *Principal
+		Expected  string
+	}{
+		{"user", &Principal{Username: "jane.doe"}, RoleMaintainer},
+		{"group", &Principal{Username: "other", Groups: []string{"other", "carbon-developers"}}, RoleDeveloper},
+		{"most privileged", &Principal{Username: "john.doe", Groups: []string{"carbon-developers"}}, RoleDeveloper},
+		{"neither", &Principal{Username: "other", Groups: []string{"other"}}, ""},
+		{"anonymous", &Principal{}, ""},
+		{"service account", &Principal{Username: ServiceAccountUsername("carbon", "ci")}, RoleDeveloper},
+		{"service account of other owner", &Principal{Username: ServiceAccountUsername("other", "ci")}, ""},
+		{"deleted service account", &Principal{Username: ServiceAccountUsername("carbon", "deleted")}, ""},
+	}
+
+	for _, c := range cases {
+		if v, want := owner.Role(c.Principal), c.Expected; v != want {
+			t.Errorf("case %s: role was '%v', expected '%v'", c.Name, v, want)
+		}
+	}
+}
+
+func TestOwnerCanWrite(t *testing.T) {
+	owner := NewOwner("carbon")
+	owner.Members["maintainer"] = RoleMaintainer
+	owner.Members["developer"] = RoleDeveloper
+	owner.Members["reader"] = RoleReader
+
+	cases := map[string]bool{
+		"maintainer": true,
+		"developer":  true,
+		"reader":     false,
+		"other":      false,
+	}
+
+	for user, expected := range cases {
+		if v, want := owner.CanWrite(&Principal{Username: user}), expected; v != want {
+			t.Errorf("case %s: result was %v, expected %v", user, v, want)
+		}
+	}
+}
+
+func TestOwnerHasMaintainer(t *testing.T) {
+	owner := NewOwner("carbon")
+	owner.Members["developer"] = RoleDeveloper
+	if owner.HasMaintainer() {
+		t.Fatal("Owner without maintainers has a maintainer")
+	}
+
+	owner.Groups["carbon-leads"] = RoleMaintainer
+	if !owner.HasMaintainer() {
+		t.Fatal("Group maintainer was not found")
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// ScopePull allows pulling images and reading from the api
+	ScopePull = "pull"
+	// ScopePush allows pushing images; it implies ScopePull
+	ScopePush = "push"
+	// ScopeManage allows creating and deleting repositories, images, and tokens
+	ScopeManage = "manage"
+	// ScopeAdmin allows everything, including managing account access
+	ScopeAdmin = "admin"
+)
+
+var AllScopes = []string{ScopePull, ScopePush, ScopeManage, ScopeAdmin}
+
+// Permissions describe what a set of credentials is allowed to do
+type Permissions struct {
+	Scopes []string
+	// Repositories are patterns such as "owner/name" or "owner/*" which restrict
+	// the repositories the credentials can access; no patterns means no restriction
+	Repositories []string
+}
+
+// FullPermissions are given to credentials which are not restricted,
+// e.g. active directory credentials
+func FullPermissions() *Permissions {
+	return &Permissions{
+		Scopes: AllScopes,
+	}
+}
+
+func (p *Permissions) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		switch {
+		case s == scope:
+			return true
+		case s == ScopeAdmin:
+			return true
+		case s == ScopePush && scope == ScopePull:
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAccessRepository returns true if the repository (or repository pattern) is
+// matched by at least one of the permission's repository patterns
+func (p *Permissions) CanAccessRepository(repository string) bool {
+	if len(p.Repositories) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Repositories {
+		if ok, _ := path.Match(pattern, repository); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !contains(AllScopes, scope) {
+			return fmt.Errorf("Invalid scope '%s': valid scopes are %v", scope, AllScopes)
+		}
+	}
+
+	return nil
+}
+
+func ValidateRepositoryPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.Count(pattern, "/") != 1 {
+			return fmt.Errorf("Invalid repository pattern '%s': patterns must be in the format 'owner/name' or 'owner/*'", pattern)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid repository pattern '%s': %v", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestPermissionsHasScope(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Scopes   []string
+		Scope    string
+		Expected bool
+	}{
+		{"empty", []string{}, ScopePull, false},
+		{"exact", []string{ScopePull}, ScopePull, true},
+		{"pull cannot push", []string{ScopePull}, ScopePush, false},
+		{"push implies pull", []string{ScopePush}, ScopePull, true},
+		{"push cannot manage", []string{ScopePush}, ScopeManage, false},
+		{"manage cannot push", []string{ScopeManage}, ScopePush, false},
+		{"admin implies manage", []string{ScopeAdmin}, ScopeManage, true},
+		{"admin implies push", []string{ScopeAdmin}, ScopePush, true},
+	}
+
+	for _, c := range cases {
+		permissions := &Permissions{Scopes: c.Scopes}
+		if v, want := permissions.HasScope(c.Scope), c.Expected; v != want {
+			t.Errorf("case %s: result was %v, expected %v", c.Name, v, want)
+		}
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	if err := ValidateScopes([]string{ScopePull, ScopePush}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ValidateScopes([]string{ScopePull, "invalid"}); err == nil {
+		t.Fatal("Error expected when validating an invalid scope")
+	}
+}
+
+func TestPermissionsCanAccessRepository(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Repositories []string
+		Repository   string
+		Expected     bool
+	}{
+		{"unrestricted", nil, "owner/name", true},
+		{"exact", []string{"owner/name"}, "owner/name", true},
+		{"exact mismatch", []string{"owner/name"}, "owner/other", false},
+		{"owner wildcard", []string{"owner/*"}, "owner/name", true},
+		{"owner wildcard mismatch", []string{"owner/*"}, "other/name", false},
+		{"multiple", []string{"owner/name", "other/*"}, "other/name", true},
+		{"wildcard pattern", []string{"owner/*"}, "owner/*", true},
+		{"narrower pattern", []string{"owner/name"}, "owner/*", false},
+	}
+
+	for _, c := range cases {
+		permissions := &Permissions{Repositories: c.Repositories}
+		if v, want := permissions.CanAccessRepository(c.Repository), c.Expected; v != want {
+			t.Errorf("case %s: result was %v, expected %v", c.Name, v, want)
+		}
+	}
+}
+
+func TestValidateRepositoryPatterns(t *testing.T) {
+	if err := ValidateRepositoryPatterns([]string{"owner/name", "owner/*"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"owner", "owner/name/extra", "owner/[name"} {
+		if err := ValidateRepositoryPatterns([]string{pattern}); err == nil {
+			t.Errorf("Error expected when validating pattern '%s'", pattern)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import "time"
+
+// Names of the authenticators which can accept credentials
+const (
+	AuthenticatorToken         = "token"
+	AuthenticatorLDAP          = "ldap"
+	AuthenticatorAuth0         = "auth0"
+	AuthenticatorHtpasswd      = "htpasswd"
+	AuthenticatorOIDC          = "oidc"
+	AuthenticatorRegistryToken = "registry-token"
+	AuthenticatorClientCert    = "client-cert"
+	AuthenticatorAWSIAM        = "aws-iam"
+	AuthenticatorAnonymous     = "anonymous"
+)
+
+// Principal describes an authenticated user and how they authenticated.
+// The embedded Permissions describe what the credentials used are allowed to do.
+type Principal struct {
+	Permissions
+	Username string
+	// Authenticator is the name of the authenticator which accepted the credentials, e.g. AuthenticatorToken
+	Authenticator string
+	// TokenID is the id of the token used to authenticate; it is empty if no token was used
+	TokenID string
+	// ExpiresAt is when the credentials used to authenticate expire; it is zero if they do not expire
+	ExpiresAt time.Time
+	Email     string
+	Name      string
+	Groups    []string
+	// Admin is true if the user has the admin role
+	Admin bool
+}
+
+// NewPrincipal returns a principal which is not restricted, like users who log in with active directory credentials
+func NewPrincipal(username, authenticator string) *Principal {
+	return &Principal{
+		Permissions:   *FullPermissions(),
+		Username:      username,
+		Authenticator: authenticator,
+	}
+}
+
+// NewAnonymousPrincipal returns the principal of a request without credentials, which can only pull from public repositories
+func NewAnonymousPrincipal() *Principal {
+	return &Principal{
+		Permissions:   Permissions{Scopes: []string{ScopePull}},
+		Authenticator: AuthenticatorAnonymous,
+	}
+}
+
+// IsAnonymous returns true if the principal made a request without credentials
+func (p *Principal) IsAnonymous() bool {
+	return p.Authenticator == AuthenticatorAnonymous
+}
+
+// IsToken returns true if the principal authenticated with a d.ims.io token
+func (p *Principal) IsToken() bool {
+	return p.TokenID != ""
+}
+
+// IsServiceAccount returns true if the principal authenticated as a service account
+func (p *Principal) IsServiceAccount() bool {
+	_, _, ok := ParseServiceAccountUsername(p.Username)
+	return ok
+}
+
+// IsAdmin returns true if the principal has the admin role and its credentials have the admin scope.
+// Tokens created without the admin scope cannot act as admins, even if their user can.
+func (p *Principal) IsAdmin() bool {
+	return p.Admin && p.HasScope(ScopeAdmin)
+}
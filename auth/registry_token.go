@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// DefaultRegistryTokenExpiry is how long registry tokens are valid
+const DefaultRegistryTokenExpiry = time.Minute * 5
+
+// ResourceAccess is an entry of the 'access' claim of a registry token,
+// see: https://docs.docker.com/registry/spec/auth/jwt/
+type ResourceAccess struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+}
+
+// ParseRegistryScope parses a scope such as 'repository:<owner>/<name>:pull,push',
+// see: https://docs.docker.com/registry/spec/auth/scope/
+func ParseRegistryScope(scope string) (ResourceAccess, error) {
+	first := strings.Index(scope, ":")
+	last := strings.LastIndex(scope, ":")
+	if first < 1 || first == last || last == len(scope)-1 {
+		return ResourceAccess{}, fmt.Errorf("Invalid scope '%s': scopes must be in the format 'type:name:actions'", scope)
+	}
+
+	access := ResourceAccess{
+		Type:    scope[:first],
+		Name:    scope[first+1 : last],
+		Actions: strings.Split(scope[last+1:], ","),
+	}
+
+	return access, nil
+}
+
+func (r ResourceAccess) String() string {
+	return fmt.Sprintf("%s:%s:%s", r.Type, r.Name, strings.Join(r.Actions, ","))
+}
+
+// RegistryClaims are the claims of a registry token
+type RegistryClaims struct {
+	jwt.StandardClaims
+	Access []ResourceAccess `json:"access"`
+}
+
+// Allows returns true if the claims grant the action on the repository
+func (r *RegistryClaims) Allows(repository, action string) bool {
+	for _, access := range r.Access {
+		if access.Type == "repository" && access.Name == repository && contains(access.Actions, action) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CatalogAccess allows listing every repository through the registry's catalog api
+var CatalogAccess = ResourceAccess{Type: "registry", Name: "catalog", Actions: []string{"*"}}
+
+// AllowsCatalog returns true if the claims grant CatalogAccess
+func (r *RegistryClaims) AllowsCatalog() bool {
+	for _, access := range r.Access {
+		if access.Type == CatalogAccess.Type && access.Name == CatalogAccess.Name && contains(access.Actions, "*") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RegistryTokenService issues and validates the short-lived tokens used by docker clients
+// to access the registry api, as described by https://docs.docker.com/registry/spec/auth/token/.
+// Tokens are signed with a shared secret so they can be validated locally by any instance of d.ims.io.
+type RegistryTokenService struct {
+	service string
+	secret  []byte
+	expiry  time.Duration
+}
+
+func NewRegistryTokenService(service, secret string, expiry time.Duration) *RegistryTokenService {
+	return &RegistryTokenService{
+		service: service,
+		secret:  []byte(secret),
+		expiry:  expiry,
+	}
+}
+
+// Service is the name of the registry the tokens are issued for
+func (r *RegistryTokenService) Service() string {
+	return r.service
+}
+
+func (r *RegistryTokenService) Expiry() time.Duration {
+	return r.expiry
+}
+
+func (r *RegistryTokenService) IssueToken(user string, access []ResourceAccess) (string, time.Time, error) {
+	id, err := randomBase62(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	claims := RegistryClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			Issuer:    r.service,
+			Audience:  r.service,
+			Subject:   user,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(r.expiry).Unix(),
+		},
+		Access: access,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(r.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, now, nil
+}
+
+// ValidateToken checks the signature, expiry, issuer, and audience of a registry token
+func (r *RegistryTokenService) ValidateToken(token string) (*RegistryClaims, error) {
+	claims := &RegistryClaims{}
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("Unexpected signing method '%s'", t.Method.Alg())
+		}
+
+		return r.secret, nil
+	}
+
+	if _, err := jwt.ParseWithClaims(token, claims, keyFunc); err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(r.service, true) || !claims.VerifyAudience(r.service, true) {
+		return nil, fmt.Errorf("Token was not issued for '%s'", r.service)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegistryScope(t *testing.T) {
+	access, err := ParseRegistryScope("repository:owner/name:pull,push")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ResourceAccess{
+		Type:    "repository",
+		Name:    "owner/name",
+		Actions: []string{"pull", "push"},
+	}
+
+	assert.Equal(t, expected, access)
+	assert.Equal(t, "repository:owner/name:pull,push", access.String())
+
+	// repository names may include a registry host with a port
+	access, err = ParseRegistryScope("repository:host:5000/owner/name:pull")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "host:5000/owner/name", access.Name)
+
+	for _, scope := range []string{"", "repository", "repository:owner/name", ":owner/name:pull", "repository:owner/name:"} {
+		if _, err := ParseRegistryScope(scope); err == nil {
+			t.Errorf("Error expected when parsing scope '%s'", scope)
+		}
+	}
+}
+
+func TestRegistryTokenService(t *testing.T) {
+	service := NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	access := []ResourceAccess{
+		{Type: "repository", Name: "owner/name", Actions: []string{"pull"}},
+	}
+
+	token, _, err := service.IssueToken("user", access)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := service.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "user", claims.Subject)
+	assert.Equal(t, access, claims.Access)
+	assert.True(t, claims.Allows("owner/name", "pull"))
+	assert.False(t, claims.Allows("owner/name", "push"))
+	assert.False(t, claims.Allows("owner/other", "pull"))
+}
+
+func TestRegistryTokenServiceRejectsInvalidTokens(t *testing.T) {
+	service := NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+
+	cases := map[string]*RegistryTokenService{
+		"wrong secret":  NewRegistryTokenService("d.ims.io", "other", time.Minute),
+		"wrong service": NewRegistryTokenService("other", "secret", time.Minute),
+		"expired":       NewRegistryTokenService("d.ims.io", "secret", -time.Minute),
+	}
+
+	for name, issuer := range cases {
+		token, _, err := issuer.IssueToken("user", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := service.ValidateToken(token); err == nil {
+			t.Errorf("case %s: error expected", name)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRepositoryNotFound = errors.New("Repository not found")
+	ErrRepositoryExists   = errors.New("Repository already exists")
+)
+
+// Visibilities of repositories
+const (
+	// VisibilityInternal repositories can be pulled by any authenticated user
+	VisibilityInternal = "internal"
+	// VisibilityPrivate repositories can only be pulled by members of their owner and the users and groups in their read acl
+	VisibilityPrivate = "private"
+	// VisibilityPublic repositories can be pulled by anyone, including anonymous users
+	VisibilityPublic = "public"
+)
+
+var AllVisibilities = []string{VisibilityInternal, VisibilityPrivate, VisibilityPublic}
+
+// Repository holds the metadata d.ims.io keeps about a repository, e.g. 'carbon/api'.
+// Repositories without metadata are internal.
+type Repository struct {
+	Name       string
+	Visibility string
+	// ReadUsers and ReadGroups are allowed to pull from a private repository
+	// in addition to the members of its owner
+	ReadUsers  []string
+	ReadGroups []string
+}
+
+func NewRepository(name string) *Repository {
+	return &Repository{
+		Name:       name,
+		Visibility: VisibilityInternal,
+		ReadUsers:  []string{},
+		ReadGroups: []string{},
+	}
+}
+
+// CanRead returns true if the principal can pull from the repository.
+// The owner of the repository is nil if it has not been registered.
+// Anonymous principals can only read public repositories.
+func (r *Repository) CanRead(p *Principal, owner *Owner) bool {
+	switch {
+	case r.Visibility == VisibilityPublic:
+		return true
+	case p.IsAnonymous():
+		return false
+	case r.Visibility != VisibilityPrivate:
+		return true
+	}
+
+	if owner != nil && owner.Role(p) != "" {
+		return true
+	}
+
+	if p.Username != "" && contains(r.ReadUsers, p.Username) {
+		return true
+	}
+
+	for _, group := range p.Groups {
+		if contains(r.ReadGroups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ValidateVisibility(visibility string) error {
+	if !contains(AllVisibilities, visibility) {
+		return fmt.Errorf("Invalid visibility '%s': valid visibilities are %v", visibility, AllVisibilities)
+	}
+
+	return nil
+}
+
+type RepositoryManager interface {
+	// GetRepository returns ErrRepositoryNotFound if no metadata is stored for the repository
+	GetRepository(name string) (*Repository, error)
+	// CreateRepository stores the metadata of a new repository.
+	// It returns ErrRepositoryExists if metadata is already stored for the repository.
+	CreateRepository(repository *Repository) error
+	// PutRepository creates or replaces the metadata of the repository
+	PutRepository(repository *Repository) error
+	DeleteRepository(name string) error
+	ListRepositories() ([]*Repository, error)
+}
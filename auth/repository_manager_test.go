@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestRepositoryCanRead(t *testing.T) {
+	owner := NewOwner("carbon")
+	owner.Members["reader"] = RoleReader
+
+	private := NewRepository("carbon/api")
+	private.Visibility = VisibilityPrivate
+	private.ReadUsers = []string{"john.doe"}
+	private.ReadGroups = []string{"qa"}
+
+	public := NewRepository("carbon/api")
+	public.Visibility = VisibilityPublic
+
+	cases := []struct {
+		Name       string
+		Repository *Repository
+		Owner      *Owner
+		Principal  *Principal
+		Expected   bool
+	}{
+		{"internal", NewRepository("carbon/api"), owner, &Principal{Username: "other"}, true},
+		{"owner member", private, owner, &Principal{Username: "reader"}, true},
+		{"read user", private, owner, &Principal{Username: "john.doe"}, true},
+		{"read group", private, owner, &Principal{Username: "other", Groups: []string{"qa"}}, true},
+		{"unregistered owner", private, nil, &Principal{Username: "john.doe"}, true},
+		{"other", private, owner, &Principal{Username: "other", Groups: []string{"other"}}, false},
+		{"anonymous", private, nil, &Principal{}, false},
+		{"public", public, owner, &Principal{Username: "other"}, true},
+		{"anonymous public", public, owner, NewAnonymousPrincipal(), true},
+		{"anonymous internal", NewRepository("carbon/api"), owner, NewAnonymousPrincipal(), false},
+	}
+
+	for _, c := range cases {
+		if v, want := c.Repository.CanRead(c.Principal, c.Owner), c.Expected; v != want {
+			t.Errorf("case %s: can read was '%v', expected '%v'", c.Name, v, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// serviceAccountPrefix prefixes the usernames of service accounts, e.g. 'serviceaccount:carbon/ci'
+const serviceAccountPrefix = "serviceaccount:"
+
+// ServiceAccount is a member of an owner which is not a person, e.g. a CI pipeline.
+// Service accounts have no password: the maintainers of their owner issue tokens to them,
+// so the tokens keep working when the people who created them leave.
+type ServiceAccount struct {
+	Name        string
+	Role        string
+	Description string
+}
+
+// ServiceAccountUsername returns the username the tokens of a service account authenticate as
+func ServiceAccountUsername(owner, name string) string {
+	return serviceAccountPrefix + owner + "/" + name
+}
+
+// ParseServiceAccountUsername returns the owner and name of the service account with the username.
+// It returns false if the username does not belong to a service account.
+func ParseServiceAccountUsername(username string) (string, string, bool) {
+	if !strings.HasPrefix(username, serviceAccountPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(username, serviceAccountPrefix), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func ValidateServiceAccountName(name string) error {
+	if name == "" {
+		return fmt.Errorf("Service account name is required")
+	}
+
+	if strings.ContainsAny(name, "/:") {
+		return fmt.Errorf("Service account name cannot contain '/' or ':' characters")
+	}
+
+	return nil
+}
+
+// ValidateServiceAccountRole returns an error if service accounts cannot have the role.
+// Service accounts cannot be maintainers, as they are managed by the maintainers of their owner.
+func ValidateServiceAccountRole(role string) error {
+	if err := ValidateRole(role); err != nil {
+		return err
+	}
+
+	if role == RoleMaintainer {
+		return fmt.Errorf("Service accounts cannot have the '%s' role", RoleMaintainer)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestParseServiceAccountUsername(t *testing.T) {
+	cases := []struct {
+		Username string
+		Owner    string
+		Name     string
+		OK       bool
+	}{
+		{ServiceAccountUsername("carbon", "ci"), "carbon", "ci", true},
+		{"serviceaccount:carbon/ci", "carbon", "ci", true},
+		{"john.doe", "", "", false},
+		{"serviceaccount:carbon", "", "", false},
+		{"serviceaccount:/ci", "", "", false},
+		{"serviceaccount:carbon/", "", "", false},
+	}
+
+	for _, c := range cases {
+		owner, name, ok := ParseServiceAccountUsername(c.Username)
+		if owner != c.Owner || name != c.Name || ok != c.OK {
+			t.Errorf("case %s: result was ('%v', '%v', %v), expected ('%v', '%v', %v)", c.Username, owner, name, ok, c.Owner, c.Name, c.OK)
+		}
+	}
+}
+
+func TestValidateServiceAccountRole(t *testing.T) {
+	cases := map[string]bool{
+		RoleMaintainer: false,
+		RoleDeveloper:  true,
+		RoleReader:     true,
+		"invalid":      false,
+	}
+
+	for role, expected := range cases {
+		if v, want := ValidateServiceAccountRole(role) == nil, expected; v != want {
+			t.Errorf("case %s: valid was %v, expected %v", role, v, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"strings"
+)
+
+// TokenPrefix identifies d.ims.io tokens, e.g. for secret scanning tools
+const TokenPrefix = "dims_"
+
+const (
+	tokenBodyLength     = 32
+	tokenChecksumLength = 6
+	base62Alphabet      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// GenerateToken returns a new token in the format 'dims_<body><checksum>',
+// where the body is random and the checksum is the base62-encoded CRC32 of the body
+func GenerateToken() (string, error) {
+	body, err := randomBase62(tokenBodyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return TokenPrefix + body + tokenChecksum(body), nil
+}
+
+// IsTokenFormat returns true if the value looks like a token, regardless of whether its checksum is valid
+func IsTokenFormat(value string) bool {
+	return strings.HasPrefix(value, TokenPrefix)
+}
+
+// ValidateTokenFormat checks the structure and checksum of a token without looking it up
+func ValidateTokenFormat(token string) error {
+	if !IsTokenFormat(token) {
+		return fmt.Errorf("Token does not start with '%s'", TokenPrefix)
+	}
+
+	value := strings.TrimPrefix(token, TokenPrefix)
+	if len(value) != tokenBodyLength+tokenChecksumLength {
+		return fmt.Errorf("Token has an invalid length")
+	}
+
+	for _, r := range value {
+		if !strings.ContainsRune(base62Alphabet, r) {
+			return fmt.Errorf("Token contains invalid character '%c'", r)
+		}
+	}
+
+	body, checksum := value[:tokenBodyLength], value[tokenBodyLength:]
+	if checksum != tokenChecksum(body) {
+		return fmt.Errorf("Token has an invalid checksum")
+	}
+
+	return nil
+}
+
+func tokenChecksum(body string) string {
+	n := crc32.ChecksumIEEE([]byte(body))
+	base := uint32(len(base62Alphabet))
+
+	checksum := make([]byte, tokenChecksumLength)
+	for i := len(checksum) - 1; i >= 0; i-- {
+		checksum[i] = base62Alphabet[n%base]
+		n /= base
+	}
+
+	return string(checksum)
+}
+
+func randomBase62(length int) (string, error) {
+	max := big.NewInt(int64(len(base62Alphabet)))
+
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = base62Alphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateToken(t *testing.T) {
+	token, err := GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(token, TokenPrefix) {
+		t.Errorf("Token '%s' does not start with '%s'", token, TokenPrefix)
+	}
+
+	if err := ValidateTokenFormat(token); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateTokenFormat(t *testing.T) {
+	body := strings.Repeat("a", tokenBodyLength)
+	valid := TokenPrefix + body + tokenChecksum(body)
+
+	cases := map[string]bool{
+		valid:                               true,
+		strings.TrimPrefix(valid, "dims_"):  false,
+		"dims_":                             false,
+		valid + "a":                         false,
+		valid[:len(valid)-1]:                false,
+		strings.Replace(valid, "a", "b", 1): false,
+		strings.Replace(valid, "a", "-", 1): false,
+	}
+
+	for token, expected := range cases {
+		if v, want := ValidateTokenFormat(token) == nil, expected; v != want {
+			t.Errorf("Validation of '%s' was '%v', expected '%v'", token, v, want)
+		}
+	}
+}
@@ -1,6 +1,58 @@
 package auth
 
+import (
+	"errors"
+	"time"
+)
+
+// DefaultRotationGracePeriod is how long a rotated token remains valid
+const DefaultRotationGracePeriod = time.Hour * 24
+
+var ErrTokenNotFound = errors.New("token does not exist or has expired")
+
+// TokenManager manages tokens by their id, so the tokens themselves never need to be sent to the api
 type TokenManager interface {
-	CreateToken(user string) (string, error)
-	DeleteToken(token string) error
+	CreateToken(user string, options TokenOptions) (string, error)
+	// GetToken returns ErrTokenNotFound if no unexpired token has the id
+	GetToken(id string) (*Token, error)
+	DeleteToken(id string) error
+	// DeleteUserTokens deletes every token created by the user and returns how many were deleted
+	DeleteUserTokens(user string) (int, error)
+	ListTokens(user string) ([]Token, error)
+	RotateToken(id string, gracePeriod time.Duration) (string, error)
+}
+
+// TokenOptions holds the user-supplied fields for a new token
+type TokenOptions struct {
+	Name        string
+	Description string
+	// ExpiresIn is the lifetime of the token; zero means the token never expires
+	ExpiresIn time.Duration
+	// Scopes restrict what the token can do; no scopes means the token is unrestricted
+	Scopes []string
+	// Repositories restrict which repositories the token can access, e.g. "owner/*"
+	Repositories []string
+}
+
+// Token describes a token without exposing its secret
+type Token struct {
+	// ID identifies the token without exposing it
+	ID           string
+	User         string
+	Name         string
+	Description  string
+	MaskedToken  string
+	Scopes       []string
+	Repositories []string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// MaskToken hides all but the first and last few characters of a token
+func MaskToken(token string) string {
+	if len(token) <= 8 {
+		return "********"
+	}
+
+	return token[:4] + "..." + token[len(token)-4:]
 }
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+const (
+	// DefaultTokenStreamPollInterval is how often the TokenStreamWatcher reads new records
+	DefaultTokenStreamPollInterval = time.Second * 5
+	// tokenStreamShardRefreshInterval is how often the TokenStreamWatcher looks for new shards
+	tokenStreamShardRefreshInterval = time.Minute
+)
+
+// TokenStreamWatcher reads the DynamoDB stream of the tokens table so that each instance
+// learns about tokens which were deleted, expired, or rotated by other instances.
+// The hash key of each removed or modified item is passed to onRevoke.
+type TokenStreamWatcher struct {
+	table        string
+	pollInterval time.Duration
+	onRevoke     func(key string)
+	dynamodb     dynamodbiface.DynamoDBAPI
+	streams      dynamodbstreamsiface.DynamoDBStreamsAPI
+	streamARN    string
+	iterators    map[string]string
+	finished     map[string]bool
+	refreshedAt  time.Time
+}
+
+func NewTokenStreamWatcher(table string, pollInterval time.Duration, dynamodb dynamodbiface.DynamoDBAPI, streams dynamodbstreamsiface.DynamoDBStreamsAPI, onRevoke func(key string)) *TokenStreamWatcher {
+	return &TokenStreamWatcher{
+		table:        table,
+		pollInterval: pollInterval,
+		onRevoke:     onRevoke,
+		dynamodb:     dynamodb,
+		streams:      streams,
+		iterators:    map[string]string{},
+		finished:     map[string]bool{},
+	}
+}
+
+// Open finds the stream of the tokens table and starts reading each of its open shards.
+// Records written to the stream before Open is called are skipped.
+func (w *TokenStreamWatcher) Open() error {
+	input := &dynamodb.DescribeTableInput{}
+	input.SetTableName(w.table)
+
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	output, err := w.dynamodb.DescribeTable(input)
+	if err != nil {
+		return err
+	}
+
+	w.streamARN = aws.StringValue(output.Table.LatestStreamArn)
+	if w.streamARN == "" {
+		return fmt.Errorf("Table '%s' does not have a stream enabled", w.table)
+	}
+
+	return w.refreshShards(dynamodbstreams.ShardIteratorTypeLatest)
+}
+
+// Watch polls the stream until the program exits
+func (w *TokenStreamWatcher) Watch() {
+	for range time.Tick(w.pollInterval) {
+		w.Poll()
+	}
+}
+
+// Poll reads the new records of each shard.
+// Errors are logged rather than returned so a single failing shard does not stop the others.
+func (w *TokenStreamWatcher) Poll() {
+	if time.Since(w.refreshedAt) > tokenStreamShardRefreshInterval {
+		// shards discovered after Open are read from the beginning so no revocations are missed
+		if err := w.refreshShards(dynamodbstreams.ShardIteratorTypeTrimHorizon); err != nil {
+			log.Printf("[ERROR] Failed to refresh shards of stream '%s': %v", w.streamARN, err)
+		}
+	}
+
+	for shardID, iterator := range w.iterators {
+		input := &dynamodbstreams.GetRecordsInput{}
+		input.SetShardIterator(iterator)
+
+		output, err := w.streams.GetRecords(input)
+		if err != nil {
+			// the shard is read again once the shards are refreshed
+			log.Printf("[ERROR] Failed to read shard '%s' of stream '%s': %v", shardID, w.streamARN, err)
+			delete(w.iterators, shardID)
+			w.refreshedAt = time.Time{}
+			continue
+		}
+
+		for _, record := range output.Records {
+			w.handleRecord(record)
+		}
+
+		if output.NextShardIterator == nil {
+			log.Printf("[DEBUG] Finished reading closed shard '%s' of stream '%s'", shardID, w.streamARN)
+			delete(w.iterators, shardID)
+			w.finished[shardID] = true
+			w.refreshedAt = time.Time{}
+			continue
+		}
+
+		w.iterators[shardID] = aws.StringValue(output.NextShardIterator)
+	}
+}
+
+func (w *TokenStreamWatcher) handleRecord(record *dynamodbstreams.Record) {
+	switch aws.StringValue(record.EventName) {
+	case dynamodbstreams.OperationTypeModify, dynamodbstreams.OperationTypeRemove:
+	default:
+		return
+	}
+
+	if record.Dynamodb == nil {
+		return
+	}
+
+	if key, ok := record.Dynamodb.Keys["Token"]; ok && key.S != nil {
+		log.Printf("[DEBUG] Token stream reported %s of token '%s'", aws.StringValue(record.EventName), MaskToken(aws.StringValue(key.S)))
+		w.onRevoke(aws.StringValue(key.S))
+	}
+}
+
+// refreshShards starts reading the shards of the stream which are not being read yet.
+// Shards which are already closed when the watcher is opened are skipped.
+func (w *TokenStreamWatcher) refreshShards(iteratorType string) error {
+	shards, err := w.describeShards()
+	if err != nil {
+		return err
+	}
+
+	finished := map[string]bool{}
+	for _, shard := range shards {
+		shardID := aws.StringValue(shard.ShardId)
+		isClosed := shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil
+
+		switch {
+		case w.finished[shardID], isClosed && iteratorType == dynamodbstreams.ShardIteratorTypeLatest:
+			finished[shardID] = true
+			continue
+		case w.iterators[shardID] != "":
+			continue
+		}
+
+		input := &dynamodbstreams.GetShardIteratorInput{}
+		input.SetStreamArn(w.streamARN)
+		input.SetShardId(shardID)
+		input.SetShardIteratorType(iteratorType)
+
+		if err := input.Validate(); err != nil {
+			return err
+		}
+
+		output, err := w.streams.GetShardIterator(input)
+		if err != nil {
+			return err
+		}
+
+		w.iterators[shardID] = aws.StringValue(output.ShardIterator)
+	}
+
+	// shards eventually disappear from the stream, so they no longer need to be remembered
+	w.finished = finished
+	w.refreshedAt = time.Now()
+	return nil
+}
+
+func (w *TokenStreamWatcher) describeShards() ([]*dynamodbstreams.Shard, error) {
+	shards := []*dynamodbstreams.Shard{}
+
+	var lastShardID *string
+	for {
+		input := &dynamodbstreams.DescribeStreamInput{}
+		input.SetStreamArn(w.streamARN)
+		input.ExclusiveStartShardId = lastShardID
+
+		if err := input.Validate(); err != nil {
+			return nil, err
+		}
+
+		output, err := w.streams.DescribeStream(input)
+		if err != nil {
+			return nil, err
+		}
+
+		shards = append(shards, output.StreamDescription.Shards...)
+		lastShardID = output.StreamDescription.LastEvaluatedShardId
+		if lastShardID == nil {
+			return shards, nil
+		}
+	}
+}
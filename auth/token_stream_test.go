@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testStreamARN   = "arn:aws:dynamodb:us-west-2:123456789012:table/table/stream/2018-01-01T00:00:00.000"
+	testOpenShard   = "shardId-00000001514764800000-00000001"
+	testClosedShard = "shardId-00000001514764800000-00000000"
+)
+
+func newStreamRecord(eventName, key string) *dynamodbstreams.Record {
+	return &dynamodbstreams.Record{
+		EventName: aws.String(eventName),
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			Keys: map[string]*dynamodb.AttributeValue{
+				"Token": {S: aws.String(key)},
+			},
+		},
+	}
+}
+
+func TestTokenStreamWatcher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	mockStreams := mock.NewMockDynamoDBStreamsAPI(ctrl)
+
+	revoked := []string{}
+	target := auth.NewTokenStreamWatcher("table", time.Second, mockDynamoDB, mockStreams, func(key string) {
+		revoked = append(revoked, key)
+	})
+
+	mockDynamoDB.EXPECT().
+		DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("table")}).
+		Return(&dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{LatestStreamArn: aws.String(testStreamARN)},
+		}, nil)
+
+	// closed shards are skipped when the watcher is opened
+	mockStreams.EXPECT().
+		DescribeStream(gomock.Any()).
+		Return(&dynamodbstreams.DescribeStreamOutput{
+			StreamDescription: &dynamodbstreams.StreamDescription{
+				Shards: []*dynamodbstreams.Shard{
+					{
+						ShardId: aws.String(testClosedShard),
+						SequenceNumberRange: &dynamodbstreams.SequenceNumberRange{
+							EndingSequenceNumber: aws.String("1"),
+						},
+					},
+					{
+						ShardId:             aws.String(testOpenShard),
+						SequenceNumberRange: &dynamodbstreams.SequenceNumberRange{},
+					},
+				},
+			},
+		}, nil)
+
+	validateGetShardIteratorInput := func(input *dynamodbstreams.GetShardIteratorInput) {
+		assert.Equal(t, testStreamARN, aws.StringValue(input.StreamArn))
+		assert.Equal(t, testOpenShard, aws.StringValue(input.ShardId))
+		assert.Equal(t, dynamodbstreams.ShardIteratorTypeLatest, aws.StringValue(input.ShardIteratorType))
+	}
+
+	mockStreams.EXPECT().
+		GetShardIterator(gomock.Any()).
+		Do(validateGetShardIteratorInput).
+		Return(&dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String("iterator")}, nil)
+
+	if err := target.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	mockStreams.EXPECT().
+		GetRecords(&dynamodbstreams.GetRecordsInput{ShardIterator: aws.String("iterator")}).
+		Return(&dynamodbstreams.GetRecordsOutput{
+			Records: []*dynamodbstreams.Record{
+				newStreamRecord(dynamodbstreams.OperationTypeInsert, "inserted"),
+				newStreamRecord(dynamodbstreams.OperationTypeModify, "modified"),
+				newStreamRecord(dynamodbstreams.OperationTypeRemove, "removed"),
+			},
+			NextShardIterator: aws.String("next"),
+		}, nil)
+
+	mockStreams.EXPECT().
+		GetRecords(&dynamodbstreams.GetRecordsInput{ShardIterator: aws.String("next")}).
+		Return(&dynamodbstreams.GetRecordsOutput{}, nil)
+
+	target.Poll()
+	target.Poll()
+
+	assert.Equal(t, []string{"modified", "removed"}, revoked)
+}
+
+func TestTokenStreamWatcherRequiresStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := mock.NewMockDynamoDBAPI(ctrl)
+	mockStreams := mock.NewMockDynamoDBStreamsAPI(ctrl)
+	target := auth.NewTokenStreamWatcher("table", time.Second, mockDynamoDB, mockStreams, func(string) {})
+
+	mockDynamoDB.EXPECT().
+		DescribeTable(gomock.Any()).
+		Return(&dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{}}, nil)
+
+	if err := target.Open(); err == nil {
+		t.Fatal("Error expected when the table does not have a stream")
+	}
+}
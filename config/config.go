@@ -1,23 +1,73 @@
 package config
 
 const (
-	ENVVAR_PORT              = "DIMSIO_PORT"
-	ENVVAR_DEBUG             = "DIMSIO_DEBUG"
-	ENVVAR_AWS_ACCESS_KEY    = "DIMSIO_AWS_ACCESS_KEY"
-	ENVVAR_AWS_SECRET_KEY    = "DIMSIO_AWS_SECRET_KEY"
-	ENVVAR_AWS_REGION        = "DIMSIO_AWS_REGION"
-	ENVVAR_REGISTRY_ENDPOINT = "DIMSIO_REGISTRY_ENDPOINT"
-	ENVVAR_TOKENS_TABLE      = "DIMSIO_TOKENS_TABLE"
-	ENVVAR_ACCOUNTS_TABLE    = "DIMSIO_ACCOUNTS_TABLE"
-	ENVVAR_AUTH0_DOMAIN      = "DIMSIO_AUTH0_DOMAIN"
-	ENVVAR_AUTH0_CLIENT_ID   = "DIMSIO_AUTH0_CLIENT_ID"
-	ENVVAR_AUTH0_CONNECTION  = "DIMSIO_AUTH0_CONNECTION"
+	ENVVAR_PORT                   = "DIMSIO_PORT"
+	ENVVAR_DEBUG                  = "DIMSIO_DEBUG"
+	ENVVAR_AWS_ACCESS_KEY         = "DIMSIO_AWS_ACCESS_KEY"
+	ENVVAR_AWS_SECRET_KEY         = "DIMSIO_AWS_SECRET_KEY"
+	ENVVAR_AWS_REGION             = "DIMSIO_AWS_REGION"
+	ENVVAR_REGISTRY_ENDPOINT      = "DIMSIO_REGISTRY_ENDPOINT"
+	ENVVAR_REGISTRY_TOKEN_SECRET  = "DIMSIO_REGISTRY_TOKEN_SECRET"
+	ENVVAR_REGISTRY_TOKEN_REALM   = "DIMSIO_REGISTRY_TOKEN_REALM"
+	ENVVAR_REGISTRY_SERVICE       = "DIMSIO_REGISTRY_SERVICE"
+	ENVVAR_TOKENS_TABLE           = "DIMSIO_TOKENS_TABLE"
+	ENVVAR_TOKEN_PEPPER           = "DIMSIO_TOKEN_PEPPER"
+	ENVVAR_LEGACY_TOKENS          = "DIMSIO_LEGACY_TOKENS"
+	ENVVAR_WATCH_TOKENS_STREAM    = "DIMSIO_WATCH_TOKENS_STREAM"
+	ENVVAR_AUTH_CACHE_SIZE        = "DIMSIO_AUTH_CACHE_SIZE"
+	ENVVAR_AUTH_CACHE_VALID_TTL   = "DIMSIO_AUTH_CACHE_VALID_TTL"
+	ENVVAR_AUTH_CACHE_INVALID_TTL = "DIMSIO_AUTH_CACHE_INVALID_TTL"
+	ENVVAR_LOCKOUT                = "DIMSIO_LOCKOUT"
+	ENVVAR_TRUST_FORWARDED_FOR    = "DIMSIO_TRUST_FORWARDED_FOR"
+	ENVVAR_ADMIN_USERS            = "DIMSIO_ADMIN_USERS"
+	ENVVAR_ADMIN_GROUPS           = "DIMSIO_ADMIN_GROUPS"
+	ENVVAR_ACCOUNTS_TABLE         = "DIMSIO_ACCOUNTS_TABLE"
+	ENVVAR_OWNERS_TABLE           = "DIMSIO_OWNERS_TABLE"
+	ENVVAR_REPOSITORIES_TABLE     = "DIMSIO_REPOSITORIES_TABLE"
+	ENVVAR_AUDIT_TABLE            = "DIMSIO_AUDIT_TABLE"
+	ENVVAR_AUDIT_FILE             = "DIMSIO_AUDIT_FILE"
+	ENVVAR_AUTH0_DOMAIN           = "DIMSIO_AUTH0_DOMAIN"
+	ENVVAR_AUTH0_CLIENT_ID        = "DIMSIO_AUTH0_CLIENT_ID"
+	ENVVAR_AUTH0_CONNECTION       = "DIMSIO_AUTH0_CONNECTION"
+	ENVVAR_AUTH0_GROUPS_CLAIM     = "DIMSIO_AUTH0_GROUPS_CLAIM"
+	ENVVAR_LDAP_ADDRESS           = "DIMSIO_LDAP_ADDRESS"
+	ENVVAR_LDAP_USER_DN_TEMPLATE  = "DIMSIO_LDAP_USER_DN_TEMPLATE"
+	ENVVAR_LDAP_BASE_DN           = "DIMSIO_LDAP_BASE_DN"
+	ENVVAR_LDAP_SEARCH_FILTER     = "DIMSIO_LDAP_SEARCH_FILTER"
+	ENVVAR_LDAP_BIND_DN           = "DIMSIO_LDAP_BIND_DN"
+	ENVVAR_LDAP_BIND_PASSWORD     = "DIMSIO_LDAP_BIND_PASSWORD"
+	ENVVAR_LDAP_START_TLS         = "DIMSIO_LDAP_START_TLS"
+	ENVVAR_LDAP_INSECURE          = "DIMSIO_LDAP_INSECURE"
+	ENVVAR_LDAP_POOL_SIZE         = "DIMSIO_LDAP_POOL_SIZE"
+	ENVVAR_HTPASSWD_FILE          = "DIMSIO_HTPASSWD_FILE"
+	ENVVAR_OIDC_ISSUER            = "DIMSIO_OIDC_ISSUER"
+	ENVVAR_OIDC_AUDIENCE          = "DIMSIO_OIDC_AUDIENCE"
+	ENVVAR_OIDC_USERNAME_CLAIM    = "DIMSIO_OIDC_USERNAME_CLAIM"
+	ENVVAR_AUTHENTICATORS_FILE    = "DIMSIO_AUTHENTICATORS_FILE"
+	ENVVAR_AWS_IAM_ACCOUNTS       = "DIMSIO_AWS_IAM_ACCOUNTS"
+	ENVVAR_AWS_IAM_ROLES          = "DIMSIO_AWS_IAM_ROLES"
+	ENVVAR_AWS_IAM_STS_ENDPOINT   = "DIMSIO_AWS_IAM_STS_ENDPOINT"
+	ENVVAR_AWS_IAM_AUDIENCE       = "DIMSIO_AWS_IAM_AUDIENCE"
+	ENVVAR_TLS_CERT_FILE          = "DIMSIO_TLS_CERT_FILE"
+	ENVVAR_TLS_KEY_FILE           = "DIMSIO_TLS_KEY_FILE"
+	ENVVAR_TLS_CLIENT_CA_FILE     = "DIMSIO_TLS_CLIENT_CA_FILE"
+	ENVVAR_CLIENT_CERT_USERNAME   = "DIMSIO_CLIENT_CERT_USERNAME"
 )
 
 const (
-	DEFAULT_PORT           = "80"
-	DEFAULT_AWS_REGION     = "us-west-2"
-	DEFAULT_TOKENS_TABLE   = "d.ims.io.tokens"
-	DEFAULT_ACCOUNTS_TABLE = "d.ims.io.accounts"
-	DEFAULT_AUTH0_DOMAIN   = "https://imshealth.auth0.com"
+	DEFAULT_PORT                 = "80"
+	DEFAULT_AWS_REGION           = "us-west-2"
+	DEFAULT_TOKENS_TABLE         = "d.ims.io.tokens"
+	DEFAULT_ACCOUNTS_TABLE       = "d.ims.io.accounts"
+	DEFAULT_OWNERS_TABLE         = "d.ims.io.owners"
+	DEFAULT_REPOSITORIES_TABLE   = "d.ims.io.repositories"
+	DEFAULT_AUDIT_TABLE          = "d.ims.io.audit"
+	DEFAULT_AUTH0_DOMAIN         = "https://imshealth.auth0.com"
+	DEFAULT_AUTH0_GROUPS_CLAIM   = "groups"
+	DEFAULT_LDAP_POOL_SIZE       = 5
+	DEFAULT_OIDC_USERNAME_CLAIM  = "sub"
+	DEFAULT_REGISTRY_SERVICE     = "d.ims.io"
+	DEFAULT_AWS_IAM_STS_ENDPOINT = "https://sts.amazonaws.com"
+	DEFAULT_AWS_IAM_AUDIENCE     = "d.ims.io"
+	DEFAULT_CLIENT_CERT_USERNAME = "cn"
 )
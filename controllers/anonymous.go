@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/zpatrick/fireball"
+)
+
+// AnonymousDecorator handles requests without credentials as an anonymous principal if allow returns true for them.
+// Other requests, including every request with credentials, are handled by the authenticated decorator, e.g. the AuthDecorator,
+// so users who log in are still identified.
+func AnonymousDecorator(allow func(c *fireball.Context) (bool, error), authenticated fireball.Decorator) fireball.Decorator {
+	return func(handler fireball.Handler) fireball.Handler {
+		authenticatedHandler := authenticated(handler)
+		return func(c *fireball.Context) (fireball.Response, error) {
+			if hasCredentials(c.Request) {
+				return authenticatedHandler(c)
+			}
+
+			ok, err := allow(c)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				return authenticatedHandler(c)
+			}
+
+			log.Printf("[DEBUG] Allowing anonymous request %s %s", c.Request.Method, c.Request.URL.String())
+			return authorize(c, handler, auth.NewAnonymousPrincipal())
+		}
+	}
+}
+
+// AllowAnonymous allows every request without credentials, e.g. for the registry token endpoint,
+// which only grants anonymous users access to public repositories
+func AllowAnonymous(c *fireball.Context) (bool, error) {
+	return true, nil
+}
+
+// AllowPublicPulls allows registry requests which read the manifests, blobs, or tags of a public repository
+func AllowPublicPulls(repositories auth.RepositoryManager) func(c *fireball.Context) (bool, error) {
+	return func(c *fireball.Context) (bool, error) {
+		if c.Request.Method != "GET" && c.Request.Method != "HEAD" {
+			return false, nil
+		}
+
+		name, ok := parseRegistryRepository(c.Request.URL.Path)
+		if !ok {
+			return false, nil
+		}
+
+		// the status of blob uploads is also read with GET requests
+		rest := strings.TrimPrefix(c.Request.URL.Path, "/v2/"+name)
+		if strings.HasPrefix(rest, "/blobs/uploads/") {
+			return false, nil
+		}
+
+		repository, err := getRepository(repositories, name)
+		if err != nil {
+			return false, err
+		}
+
+		return repository.Visibility == auth.VisibilityPublic, nil
+	}
+}
+
+// hasCredentials returns true if the request contains any form of credentials the AuthDecorator accepts
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || hasClientCertificate(r)
+}
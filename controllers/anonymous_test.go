@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
+)
+
+func TestAnonymousDecoratorAllowsPublicPulls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+
+	public := auth.NewRepository("carbon/base")
+	public.Visibility = auth.VisibilityPublic
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("carbon/base").
+		Return(public, nil).
+		AnyTimes()
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("carbon/api").
+		Return(nil, auth.ErrRepositoryNotFound).
+		AnyTimes()
+
+	// repositories named after registry routes
+	for _, name := range []string{"team/tags", "team/manifests", "team/blobs"} {
+		repository := auth.NewRepository(name)
+		repository.Visibility = auth.VisibilityPublic
+
+		mockRepositoryManager.EXPECT().
+			GetRepository(name).
+			Return(repository, nil).
+			AnyTimes()
+	}
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("team/uploads").
+		Return(nil, auth.ErrRepositoryNotFound).
+		AnyTimes()
+
+	cases := []struct {
+		Name          string
+		Method        string
+		Path          string
+		Authorization string
+		Anonymous     bool
+	}{
+		{"manifest", "GET", "/v2/carbon/base/manifests/latest", "", true},
+		{"manifest head", "HEAD", "/v2/carbon/base/manifests/latest", "", true},
+		{"blob", "GET", "/v2/carbon/base/blobs/sha256:digest", "", true},
+		{"tags", "GET", "/v2/carbon/base/tags/list", "", true},
+		{"internal repository", "GET", "/v2/carbon/api/manifests/latest", "", false},
+		{"push", "PUT", "/v2/carbon/base/manifests/latest", "", false},
+		{"upload status", "GET", "/v2/carbon/base/blobs/uploads/id", "", false},
+		{"ping", "GET", "/v2/", "", false},
+		{"credentials", "GET", "/v2/carbon/base/manifests/latest", "Basic dXNlcjpwYXNz", false},
+		{"repository named tags", "GET", "/v2/team/tags/manifests/latest", "", true},
+		{"repository named manifests", "GET", "/v2/team/manifests/tags/list", "", true},
+		{"repository named blobs", "GET", "/v2/team/blobs/blobs/sha256:digest", "", true},
+		{"upload status of repository named blobs", "GET", "/v2/team/blobs/blobs/uploads/id", "", false},
+		{"upload status of repository named tags", "GET", "/v2/team/tags/blobs/uploads/id", "", false},
+		{"internal repository named uploads", "GET", "/v2/team/uploads/manifests/latest", "", false},
+		{"nested repository", "GET", "/v2/carbon/base/tags/manifests/latest", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			var authenticatedCalled bool
+			authenticated := func(fireball.Handler) fireball.Handler {
+				return func(c *fireball.Context) (fireball.Response, error) {
+					authenticatedCalled = true
+					return fireball.NewResponse(401, nil, nil), nil
+				}
+			}
+
+			handler := func(c *fireball.Context) (fireball.Response, error) {
+				assert.True(t, getPrincipal(c).IsAnonymous())
+				return fireball.NewResponse(200, nil, nil), nil
+			}
+
+			ctx := generateContext(t, nil, nil)
+			ctx.Request.Method = c.Method
+			ctx.Request.URL = &url.URL{Path: c.Path}
+			if c.Authorization != "" {
+				ctx.Request.Header.Set("Authorization", c.Authorization)
+			}
+
+			resp, err := AnonymousDecorator(AllowPublicPulls(mockRepositoryManager), authenticated)(handler)(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.Anonymous {
+				assertResponseCode(t, resp, 200)
+			}
+
+			assert.Equal(t, !c.Anonymous, authenticatedCalled)
+		})
+	}
+}
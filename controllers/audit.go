@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quintilesims/d.ims.io/audit"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/zpatrick/fireball"
+)
+
+const (
+	defaultAuditPeriod = time.Hour * 24
+	maxAuditPeriod     = time.Hour * 24 * 31
+	defaultAuditLimit  = 100
+	maxAuditLimit      = 1000
+)
+
+// AuditDecorator records an event in sink after each request that action returns a non-empty action for.
+// The principal stored in the context by the AuthDecorator is recorded as the user who made the request,
+// so the AuditDecorator must wrap the AuthDecorator.
+func AuditDecorator(sink audit.Sink, trustForwardedFor bool, action func(c *fireball.Context) string) fireball.Decorator {
+	return func(handler fireball.Handler) fireball.Handler {
+		return func(c *fireball.Context) (fireball.Response, error) {
+			name := action(c)
+			if name == "" {
+				return handler(c)
+			}
+
+			resp, err := handler(c)
+			if err != nil {
+				status := 500
+				if err, ok := err.(*fireball.HTTPError); ok {
+					status = err.Status
+				}
+
+				recordEvent(c, sink, trustForwardedFor, name, status)
+				return nil, err
+			}
+
+			// the status of proxied responses is only known once they have been written
+			audited := fireball.ResponseFunc(func(w http.ResponseWriter, r *http.Request) {
+				recorder := &statusRecorder{ResponseWriter: w, status: 200}
+				resp.Write(recorder, r)
+				recordEvent(c, sink, trustForwardedFor, name, recorder.status)
+			})
+
+			return audited, nil
+		}
+	}
+}
+
+// AuditRoutes decorates the handlers of the routes which change state with an AuditDecorator.
+// The action of their events is the method and path of the route, e.g. 'DELETE /repository/:owner/:name'.
+func AuditRoutes(routes []*fireball.Route, sink audit.Sink, trustForwardedFor bool) []*fireball.Route {
+	decorated := make([]*fireball.Route, len(routes))
+	for i, route := range routes {
+		decorated[i] = &fireball.Route{
+			Path:     route.Path,
+			Handlers: fireball.Handlers{},
+		}
+
+		for method, handler := range route.Handlers {
+			if method != "GET" && method != "HEAD" && method != "OPTIONS" {
+				action := method + " " + route.Path
+				handler = AuditDecorator(sink, trustForwardedFor, func(*fireball.Context) string { return action })(handler)
+			}
+
+			decorated[i].Handlers[method] = handler
+		}
+	}
+
+	return decorated
+}
+
+// RegistryAuditAction returns the action of registry requests which push or delete images.
+// Only the PUT which completes a blob upload is audited, not its start or each of its chunks.
+func RegistryAuditAction(c *fireball.Context) string {
+	path := c.Request.URL.Path
+	method := c.Request.Method
+	switch {
+	case method == "PUT" && strings.Contains(path, "/manifests/"):
+		return "registry.push"
+	case method == "PUT" && strings.Contains(path, "/blobs/uploads/"):
+		return "registry.upload"
+	case c.Request.Method == "DELETE":
+		return "registry.delete"
+	default:
+		return ""
+	}
+}
+
+func recordEvent(c *fireball.Context, sink audit.Sink, trustForwardedFor bool, action string, status int) {
+	event, err := audit.NewEvent(time.Now())
+	if err != nil {
+		log.Printf("[ERROR] Failed to create audit event: %v", err)
+		return
+	}
+
+	event.Action = action
+	event.Target = c.Request.URL.Path
+	event.Status = status
+	event.Outcome = audit.OutcomeOf(status)
+	event.SourceIP = sourceAddress(c.Request, trustForwardedFor)
+	event.Owner, event.Repository = auditTarget(c)
+
+	if principal, ok := c.Meta[principalKey].(*auth.Principal); ok {
+		event.User = principal.Username
+		event.ServiceAccount = principal.IsServiceAccount()
+		event.Authenticator = principal.Authenticator
+		event.TokenID = principal.TokenID
+	}
+
+	if err := sink.Record(event); err != nil {
+		log.Printf("[ERROR] Failed to record audit event for %s %s by '%s': %v", c.Request.Method, event.Target, event.User, err)
+	}
+}
+
+// auditTarget returns the owner and repository targeted by the request, if any
+func auditTarget(c *fireball.Context) (string, string) {
+	if repository, ok := parseRegistryRepository(c.Request.URL.Path); ok {
+		return repositoryOwner(repository), repository
+	}
+
+	owner := c.PathVariables["owner"]
+	if name := c.PathVariables["name"]; name != "" && strings.HasPrefix(c.Request.URL.Path, "/repository/") {
+		return owner, owner + "/" + name
+	}
+
+	return owner, ""
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes flushes through so streamed proxy responses are not buffered
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the wrapped writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+type AuditController struct {
+	sink audit.Sink
+}
+
+func NewAuditController(s audit.Sink) *AuditController {
+	return &AuditController{
+		sink: s,
+	}
+}
+
+func (a *AuditController) Routes() []*fireball.Route {
+	return []*fireball.Route{
+		{
+			Path: "/audit",
+			Handlers: fireball.Handlers{
+				"GET": a.ListEvents,
+			},
+		},
+	}
+}
+
+// ListEvents returns the audit events selected by the query parameters, newest first.
+// Events from the last day are returned unless 'start' or 'end' are set.
+func (a *AuditController) ListEvents(c *fireball.Context) (fireball.Response, error) {
+	query := c.Request.URL.Query()
+	filter := audit.Filter{
+		User:       query.Get("user"),
+		Owner:      query.Get("owner"),
+		Repository: query.Get("repository"),
+		End:        time.Now(),
+		Limit:      defaultAuditLimit,
+	}
+
+	for param, t := range map[string]*time.Time{"start": &filter.Start, "end": &filter.End} {
+		if v := query.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return fireball.NewJSONError(400, fmt.Errorf("Parameter '%s' must be an RFC 3339 time, e.g. '2018-01-01T00:00:00Z'", param))
+			}
+
+			*t = parsed
+		}
+	}
+
+	if filter.Start.IsZero() {
+		filter.Start = filter.End.Add(-defaultAuditPeriod)
+	}
+
+	if filter.End.Before(filter.Start) {
+		return fireball.NewJSONError(400, fmt.Errorf("Parameter 'end' cannot be before 'start'"))
+	}
+
+	if filter.End.Sub(filter.Start) > maxAuditPeriod {
+		return fireball.NewJSONError(400, fmt.Errorf("Cannot query more than %v of events at once", maxAuditPeriod))
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			return fireball.NewJSONError(400, fmt.Errorf("Parameter 'limit' must be between 1 and %d", maxAuditLimit))
+		}
+
+		filter.Limit = limit
+	}
+
+	events, err := a.sink.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ListAuditEventsResponse{
+		Events: make([]models.AuditEvent, len(events)),
+	}
+
+	for i, event := range events {
+		resp.Events[i] = models.AuditEvent{
+			ID:             event.ID,
+			Time:           event.Time,
+			User:           event.User,
+			ServiceAccount: event.ServiceAccount,
+			Authenticator:  event.Authenticator,
+			TokenID:        event.TokenID,
+			Action:         event.Action,
+			Target:         event.Target,
+			Owner:          event.Owner,
+			Repository:     event.Repository,
+			Status:         event.Status,
+			Outcome:        event.Outcome,
+			SourceIP:       event.SourceIP,
+		}
+	}
+
+	return fireball.NewJSONResponse(200, resp)
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/audit"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
+)
+
+func TestAuditRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSink := mock.NewMockSink(ctrl)
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		principal := auth.NewPrincipal(auth.ServiceAccountUsername("carbon", "ci"), auth.AuthenticatorToken)
+		principal.TokenID = "token"
+		c.Meta = map[string]interface{}{principalKey: principal}
+		return fireball.NewResponse(202, nil, nil), nil
+	}
+
+	routes := []*fireball.Route{
+		{
+			Path: "/repository/:owner/:name",
+			Handlers: fireball.Handlers{
+				"GET":    handler,
+				"DELETE": handler,
+			},
+		},
+	}
+
+	routes = AuditRoutes(routes, mockSink, false)
+
+	validateEvent := func(event audit.Event) {
+		assert.NotEmpty(t, event.ID)
+		assert.Equal(t, "serviceaccount:carbon/ci", event.User)
+		assert.True(t, event.ServiceAccount)
+		assert.Equal(t, "token", event.TokenID)
+		assert.Equal(t, "DELETE /repository/:owner/:name", event.Action)
+		assert.Equal(t, "/repository/carbon/api", event.Target)
+		assert.Equal(t, "carbon", event.Owner)
+		assert.Equal(t, "carbon/api", event.Repository)
+		assert.Equal(t, 202, event.Status)
+		assert.Equal(t, audit.OutcomeSuccess, event.Outcome)
+		assert.Equal(t, "10.0.0.1", event.SourceIP)
+	}
+
+	// only the request which changes state is recorded
+	mockSink.EXPECT().
+		Record(gomock.Any()).
+		Do(validateEvent).
+		Return(nil)
+
+	for _, method := range []string{"GET", "DELETE"} {
+		req := httptest.NewRequest(method, "/repository/carbon/api", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		c := &fireball.Context{
+			Request:       req,
+			PathVariables: map[string]string{"owner": "carbon", "name": "api"},
+		}
+
+		resp, err := routes[0].Handlers[method](c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp.Write(httptest.NewRecorder(), req)
+	}
+}
+
+func TestAuditDecoratorRecordsDeniedRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSink := mock.NewMockSink(ctrl)
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(401, nil, nil), nil
+	}
+
+	validateEvent := func(event audit.Event) {
+		assert.Equal(t, "", event.User)
+		assert.Equal(t, "registry.push", event.Action)
+		assert.Equal(t, "carbon/api", event.Repository)
+		assert.Equal(t, audit.OutcomeDenied, event.Outcome)
+	}
+
+	mockSink.EXPECT().
+		Record(gomock.Any()).
+		Do(validateEvent).
+		Return(nil)
+
+	req := httptest.NewRequest("PUT", "/v2/carbon/api/manifests/latest", nil)
+	resp, err := AuditDecorator(mockSink, false, RegistryAuditAction)(handler)(&fireball.Context{Request: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	resp.Write(recorder, req)
+	assert.Equal(t, 401, recorder.Code)
+}
+
+func TestAuditDecoratorRecordsErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSink := mock.NewMockSink(ctrl)
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return nil, errors.New("some error")
+	}
+
+	validateEvent := func(event audit.Event) {
+		assert.Equal(t, 500, event.Status)
+		assert.Equal(t, audit.OutcomeFailure, event.Outcome)
+	}
+
+	mockSink.EXPECT().
+		Record(gomock.Any()).
+		Do(validateEvent).
+		Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/v2/carbon/api/manifests/digest", nil)
+	if _, err := AuditDecorator(mockSink, false, RegistryAuditAction)(handler)(&fireball.Context{Request: req}); err == nil {
+		t.Fatal("Error expected")
+	}
+}
+
+func TestAuditDecoratorRecordsErrorStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSink := mock.NewMockSink(ctrl)
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return nil, fireball.NewError(400, errors.New("some error"), nil)
+	}
+
+	validateEvent := func(event audit.Event) {
+		assert.Equal(t, 400, event.Status)
+		assert.Equal(t, audit.OutcomeFailure, event.Outcome)
+	}
+
+	mockSink.EXPECT().
+		Record(gomock.Any()).
+		Do(validateEvent).
+		Return(nil)
+
+	req := httptest.NewRequest("POST", "/repository/carbon", nil)
+	action := func(*fireball.Context) string { return "POST /repository/:owner" }
+	if _, err := AuditDecorator(mockSink, false, action)(handler)(&fireball.Context{Request: req}); err == nil {
+		t.Fatal("Error expected")
+	}
+}
+
+func TestRegistryAuditAction(t *testing.T) {
+	cases := []struct {
+		Method   string
+		Path     string
+		Expected string
+	}{
+		{"GET", "/v2/carbon/api/manifests/latest", ""},
+		{"HEAD", "/v2/carbon/api/blobs/digest", ""},
+		{"PUT", "/v2/carbon/api/manifests/latest", "registry.push"},
+		{"POST", "/v2/carbon/api/blobs/uploads/", ""},
+		{"PATCH", "/v2/carbon/api/blobs/uploads/id", ""},
+		{"PUT", "/v2/carbon/api/blobs/uploads/id?digest=sha256:digest", "registry.upload"},
+		{"GET", "/v2/carbon/api/blobs/uploads/id", ""},
+		{"DELETE", "/v2/carbon/api/manifests/digest", "registry.delete"},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.Method, c.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := RegistryAuditAction(&fireball.Context{Request: req}), c.Expected; v != want {
+			t.Errorf("%s %s: action was '%v', expected '%v'", c.Method, c.Path, v, want)
+		}
+	}
+}
+
+func TestStatusRecorderFlushes(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := &statusRecorder{ResponseWriter: w, status: 200}
+
+	var flusher http.Flusher = recorder
+	flusher.Flush()
+
+	assert.True(t, w.Flushed)
+	assert.Equal(t, w, recorder.Unwrap())
+}
+
+func TestListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSink := mock.NewMockSink(ctrl)
+	controller := NewAuditController(mockSink)
+
+	now := time.Unix(1500000000, 0).UTC()
+	validateFilter := func(filter audit.Filter) {
+		assert.Equal(t, "john.doe", filter.User)
+		assert.Equal(t, "carbon", filter.Owner)
+		assert.Equal(t, "carbon/api", filter.Repository)
+		assert.Equal(t, now.Add(-time.Hour), filter.Start)
+		assert.Equal(t, now, filter.End)
+		assert.Equal(t, 10, filter.Limit)
+	}
+
+	events := []audit.Event{
+		{ID: "id", Time: now, User: "john.doe", Action: "registry.push", Repository: "carbon/api", Status: 201, Outcome: audit.OutcomeSuccess},
+	}
+
+	mockSink.EXPECT().
+		Query(gomock.Any()).
+		Do(validateFilter).
+		Return(events, nil)
+
+	c := generateContext(t, nil, nil)
+	c.Request = httptest.NewRequest("GET", "/audit?user=john.doe&owner=carbon&repository=carbon/api&start=2017-07-14T01:40:00Z&end=2017-07-14T02:40:00Z&limit=10", nil)
+
+	resp, err := controller.ListEvents(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.ListAuditEventsResponse
+	unmarshalBody(t, resp, &response)
+
+	expected := []models.AuditEvent{
+		{ID: "id", Time: now, User: "john.doe", Action: "registry.push", Repository: "carbon/api", Status: 201, Outcome: audit.OutcomeSuccess},
+	}
+
+	assert.Equal(t, expected, response.Events)
+}
+
+func TestListAuditEventsInputValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	controller := NewAuditController(mock.NewMockSink(ctrl))
+
+	queries := []string{
+		"start=yesterday",
+		"start=2018-01-02T00:00:00Z&end=2018-01-01T00:00:00Z",
+		"start=2018-01-01T00:00:00Z&end=2018-03-01T00:00:00Z",
+		"limit=0",
+		"limit=100000",
+	}
+
+	for _, query := range queries {
+		c := generateContext(t, nil, nil)
+		c.Request = httptest.NewRequest("GET", "/audit?"+query, nil)
+
+		resp, err := controller.ListEvents(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertResponseCode(t, resp, 400)
+	}
+}
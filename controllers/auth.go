@@ -4,30 +4,51 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/zpatrick/fireball"
-	cache "github.com/zpatrick/go-cache"
 )
 
-const (
-	validAuthExpiry = time.Hour
-)
+const principalKey = "principal"
 
 func hash(user, pass string) string {
 	sum := sha256.Sum256([]byte(user + pass))
 	return fmt.Sprintf("%x", sum)
 }
 
-func AuthDecorator(auth auth.Authenticator) fireball.Decorator {
-	che := cache.New()
+// AuthDecorator authenticates requests with either basic auth or a bearer token,
+// and stores the principal of the authenticated user in the context.
+// Bearer tokens are only accepted if bearer is not nil.
+// Requests without basic auth or a bearer token are authenticated with their tls client certificate if certificates is not nil.
+// Basic auth results are cached in che; if che is nil, a cache with the default size and ttls is used.
+// Cached principals are tagged with the id of the token they authenticated with, so they can be evicted when it is revoked,
+// and are not cached past the expiry of their credentials.
+// If lockout is not nil, failed basic auth attempts are counted and locked out users and addresses receive a 429.
+// Principals listed in admins are given the admin role; no one is an admin if admins is nil.
+func AuthDecorator(authenticator auth.Authenticator, bearer auth.BearerAuthenticator, certificates auth.CertificateAuthenticator, che *auth.AuthCache, lockout *auth.Lockout, admins *auth.Admins) fireball.Decorator {
+	if che == nil {
+		che = auth.NewAuthCache(auth.DefaultAuthCacheSize, auth.DefaultAuthCacheValidTTL, auth.DefaultAuthCacheInvalidTTL)
+	}
+
 	return func(handler fireball.Handler) fireball.Handler {
 		return func(c *fireball.Context) (fireball.Response, error) {
 			headers := map[string]string{"WWW-Authenticate": "Basic realm=\"Restricted\""}
 			invalidAuthResponse := fireball.NewResponse(401, []byte("401 Unauthorized\n"), headers)
 
+			if token, ok := bearerToken(c.Request); ok && bearer != nil {
+				return authenticateBearer(c, handler, bearer, admins, token)
+			}
+
 			user, pass, ok := c.Request.BasicAuth()
+			if !ok && certificates != nil && hasClientCertificate(c.Request) {
+				return authenticateCertificate(c, handler, certificates, admins)
+			}
+
 			if !ok {
 				log.Printf("[DEBUG] Request %s %s did not contain basic auth", c.Request.Method, c.Request.URL.String())
 				return invalidAuthResponse, nil
@@ -35,33 +56,259 @@ func AuthDecorator(auth auth.Authenticator) fireball.Decorator {
 
 			log.Printf("[DEBUG] Attempting to authenticate user '%s'", user)
 
-			key := hash(user, pass)
-			isValidCreds, ok := che.GetOK(key)
-			if ok && isValidCreds.(bool) {
-				log.Printf("[DEBUG] Allowing valid cached creds for user '%s'", user)
-				return handler(c)
+			var address string
+			if lockout != nil {
+				address = sourceAddress(c.Request, lockout.TrustForwardedFor)
+				if wait := lockout.Check(user, address); wait > 0 {
+					log.Printf("[INFO] Denying locked out user '%s' from '%s' for %v", user, address, wait)
+					return lockedOutResponse(c.Request, wait)
+				}
 			}
 
-			if ok && !isValidCreds.(bool) {
+			key := hash(user, pass)
+			// valid creds are cached with their principal
+			if cached, isValid, ok := che.Get(key); ok {
+				if isValid {
+					log.Printf("[DEBUG] Allowing valid cached creds for user '%s'", user)
+					return authorize(c, handler, cached.(*auth.Principal))
+				}
+
 				log.Printf("[DEBUG] Denying invalid cached creds for user '%s'", user)
+				if lockout != nil {
+					lockout.Fail(user, address)
+				}
+
 				return invalidAuthResponse, nil
 			}
 
-			isAuthenticated, err := auth.Authenticate(user, pass)
+			principal, isAuthenticated, err := authenticator.Authenticate(user, pass)
 			if err != nil {
 				log.Printf("[ERROR] Authenticator encountered an unexpected error: %v", err)
 				return nil, err
 			}
 
+			// service accounts have no password, so only their tokens can authenticate as them
+			if isAuthenticated && principal.IsServiceAccount() && principal.Authenticator != auth.AuthenticatorToken {
+				log.Printf("[WARN] Denying user '%s' who authenticated through %s as service account '%s'", user, principal.Authenticator, principal.Username)
+				isAuthenticated = false
+			}
+
 			if !isAuthenticated {
 				log.Printf("[DEBUG] User '%s' failed to authenticate", user)
-				che.Set(key, false)
+				che.SetInvalid(key)
+				if lockout != nil {
+					lockout.Fail(user, address)
+				}
+
 				return invalidAuthResponse, nil
 			}
 
-			log.Printf("[DEBUG] User '%s' successfully authenticated", user)
-			che.Set(key, true, cache.Expire(validAuthExpiry))
-			return handler(c)
+			log.Printf("[DEBUG] User '%s' successfully authenticated through %s as '%s'", user, principal.Authenticator, principal.Username)
+			principal.Admin = admins.IsAdmin(principal)
+			che.SetValid(key, principal.TokenID, principal, principal.ExpiresAt)
+			if lockout != nil {
+				lockout.Succeed(user, address)
+			}
+
+			return authorize(c, handler, principal)
+		}
+	}
+}
+
+// authenticateBearer authenticates a bearer token.
+// Bearer tokens are verified locally, so their results are not cached.
+func authenticateBearer(c *fireball.Context, handler fireball.Handler, bearer auth.BearerAuthenticator, admins *auth.Admins, token string) (fireball.Response, error) {
+	principal, isAuthenticated, err := bearer.AuthenticateBearer(token)
+	if err != nil {
+		log.Printf("[ERROR] Bearer authenticator encountered an unexpected error: %v", err)
+		return nil, err
+	}
+
+	// service accounts can only authenticate with their tokens
+	if isAuthenticated && principal.IsServiceAccount() {
+		log.Printf("[WARN] Denying bearer token issued to service account '%s'", principal.Username)
+		isAuthenticated = false
+	}
+
+	if !isAuthenticated {
+		log.Printf("[DEBUG] Request %s %s contained an invalid bearer token", c.Request.Method, c.Request.URL.String())
+		headers := map[string]string{"WWW-Authenticate": "Bearer realm=\"Restricted\", error=\"invalid_token\""}
+		return fireball.NewResponse(401, []byte("401 Unauthorized\n"), headers), nil
+	}
+
+	log.Printf("[DEBUG] User '%s' successfully authenticated with a bearer token", principal.Username)
+	principal.Admin = admins.IsAdmin(principal)
+	return authorize(c, handler, principal)
+}
+
+// authenticateCertificate authenticates the tls client certificate of the request.
+// The tls handshake has already proven the client holds the certificate's key, so its results are not cached.
+func authenticateCertificate(c *fireball.Context, handler fireball.Handler, certificates auth.CertificateAuthenticator, admins *auth.Admins) (fireball.Response, error) {
+	principal, isAuthenticated, err := certificates.AuthenticateCertificate(c.Request.TLS.PeerCertificates)
+	if err != nil {
+		log.Printf("[ERROR] Certificate authenticator encountered an unexpected error: %v", err)
+		return nil, err
+	}
+
+	// service accounts can only authenticate with their tokens
+	if isAuthenticated && principal.IsServiceAccount() {
+		log.Printf("[WARN] Denying client certificate '%s' issued to service account '%s'", c.Request.TLS.PeerCertificates[0].Subject.CommonName, principal.Username)
+		isAuthenticated = false
+	}
+
+	if !isAuthenticated {
+		log.Printf("[DEBUG] Request %s %s contained an invalid client certificate", c.Request.Method, c.Request.URL.String())
+		headers := map[string]string{"WWW-Authenticate": "Basic realm=\"Restricted\""}
+		return fireball.NewResponse(401, []byte("401 Unauthorized\n"), headers), nil
+	}
+
+	log.Printf("[DEBUG] User '%s' successfully authenticated with a client certificate", principal.Username)
+	principal.Admin = admins.IsAdmin(principal)
+	return authorize(c, handler, principal)
+}
+
+// hasClientCertificate returns true if the client sent a certificate during the tls handshake
+func hasClientCertificate(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.PeerCertificates) > 0
+}
+
+// lockedOutResponse tells the client to wait before attempting to authenticate again
+func lockedOutResponse(r *http.Request, wait time.Duration) (fireball.Response, error) {
+	// round up so clients do not retry before the lockout ends
+	retryAfter := strconv.Itoa(int((wait + time.Second - 1) / time.Second))
+	message := "too many failed authentication attempts"
+
+	if isRegistryRequest(r) {
+		resp, err := newRegistryError(429, "TOOMANYREQUESTS", message, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Headers["Retry-After"] = retryAfter
+		return resp, nil
+	}
+
+	resp, err := fireball.NewJSONError(429, fmt.Errorf("Too many failed authentication attempts, retry after %s seconds", retryAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	// copy the headers so the shared fireball.JSONHeaders are not modified
+	headers := map[string]string{"Retry-After": retryAfter}
+	for k, v := range resp.Headers {
+		headers[k] = v
+	}
+
+	resp.Headers = headers
+	return resp, nil
+}
+
+// sourceAddress returns the ip address of the client which sent the request.
+// Load balancers append the address they received the request from to the X-Forwarded-For header,
+// so only the last address can be trusted; earlier addresses are set by the client.
+func sourceAddress(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if header := r.Header.Get("X-Forwarded-For"); header != "" {
+			addresses := strings.Split(header, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
 		}
 	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// bearerToken returns the token from the request's 'Authorization: Bearer <token>' header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+func authorize(c *fireball.Context, handler fireball.Handler, principal *auth.Principal) (fireball.Response, error) {
+	scope := requiredScope(c.Request)
+	if !principal.HasScope(scope) {
+		log.Printf("[DEBUG] User '%s' is missing the '%s' scope for %s %s", principal.Username, scope, c.Request.Method, c.Request.URL.String())
+		if isRegistryRequest(c.Request) {
+			return newRegistryError(403, "DENIED", "requested access to the resource is denied", map[string]string{"scope": scope})
+		}
+
+		return fireball.NewJSONError(403, fmt.Errorf("Credentials do not have the '%s' scope", scope))
+	}
+
+	if scope == auth.ScopeAdmin && !principal.IsAdmin() {
+		log.Printf("[DEBUG] User '%s' is not an admin for %s %s", principal.Username, c.Request.Method, c.Request.URL.String())
+		return newNotAdminError(principal.Username)
+	}
+
+	if c.Meta == nil {
+		c.Meta = map[string]interface{}{}
+	}
+
+	c.Meta[principalKey] = principal
+	return handler(c)
+}
+
+// requiredScope returns the scope needed to make the specified request.
+// Registry api requests are authorized by their http method: reads require ScopePull, writes require ScopePush.
+// Lockouts and audit events can only be viewed or cleared with ScopeAdmin. Other api requests that read require ScopePull,
+// managing account access or the tokens of other users and creating owners requires ScopeAdmin,
+// and all other writes require ScopeManage.
+// Requests which require ScopeAdmin can only be made by admins.
+func requiredScope(r *http.Request) string {
+	isRead := r.Method == "GET" || r.Method == "HEAD"
+
+	switch {
+	case isRegistryRequest(r) && isRead:
+		return auth.ScopePull
+	case isRegistryRequest(r):
+		return auth.ScopePush
+	case strings.HasPrefix(r.URL.Path, "/lockout"), strings.HasPrefix(r.URL.Path, "/audit"):
+		return auth.ScopeAdmin
+	case isRead:
+		return auth.ScopePull
+	case strings.HasPrefix(r.URL.Path, "/account"), strings.HasPrefix(r.URL.Path, "/user/"), r.URL.Path == "/owner":
+		return auth.ScopeAdmin
+	default:
+		return auth.ScopeManage
+	}
+}
+
+func isRegistryRequest(r *http.Request) bool {
+	return r.URL.Path == "/v2" || strings.HasPrefix(r.URL.Path, "/v2/")
+}
+
+// getPrincipal returns the principal stored in the context by the AuthDecorator.
+// Requests which were not authenticated by the AuthDecorator, e.g. on a route which is missing the decorator,
+// are treated as anonymous without any scopes so they cannot access anything which requires credentials.
+func getPrincipal(c *fireball.Context) *auth.Principal {
+	if principal, ok := c.Meta[principalKey].(*auth.Principal); ok {
+		return principal
+	}
+
+	log.Printf("[ERROR] No principal is stored for %s %s", c.Request.Method, c.Request.URL.Path)
+	return &auth.Principal{Authenticator: auth.AuthenticatorAnonymous}
+}
+
+// getPermissions returns the permissions of the principal stored in the context by the AuthDecorator
+func getPermissions(c *fireball.Context) *auth.Permissions {
+	return &getPrincipal(c).Permissions
+}
+
+// isAdmin returns true if the principal stored in the context by the AuthDecorator can act as an admin
+func isAdmin(c *fireball.Context) bool {
+	return getPrincipal(c).IsAdmin()
+}
+
+// getUser returns the name of the user stored in the context by the AuthDecorator
+func getUser(c *fireball.Context) string {
+	return getPrincipal(c).Username
 }
@@ -1,24 +1,39 @@
 package controllers
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/zpatrick/fireball"
 )
 
+// testPrincipal returns the result of an authenticator which accepts the credentials if isValid is true
+func testPrincipal(user string, isValid bool) (*auth.Principal, bool, error) {
+	if !isValid {
+		return nil, false, nil
+	}
+
+	return auth.NewPrincipal(user, "test"), true, nil
+}
+
 func TestAuthDecoratorHonorsValidAuth(t *testing.T) {
 	handler := func(c *fireball.Context) (fireball.Response, error) {
 		return fireball.NewResponse(200, nil, nil), nil
 	}
 
-	authenticator := auth.AuthenticatorFunc(func(user, pass string) (bool, error) {
-		return true, nil
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		return testPrincipal(user, true)
 	})
 
 	c := newContextWithBasicAuth(t, "user", "pass")
-	resp, err := AuthDecorator(authenticator)(handler)(c)
+	resp, err := AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)(c)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -32,12 +47,12 @@ func TestAuthDecoratorHonorsInvalidAuth(t *testing.T) {
 		return nil, nil
 	}
 
-	authenticator := auth.AuthenticatorFunc(func(user, pass string) (bool, error) {
-		return false, nil
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		return testPrincipal(user, false)
 	})
 
 	c := newContextWithBasicAuth(t, "user", "pass")
-	resp, err := AuthDecorator(authenticator)(handler)(c)
+	resp, err := AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)(c)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -58,14 +73,14 @@ func TestAuthDecoratorCachesCredStatus(t *testing.T) {
 			}
 
 			var authenticatorCalls int
-			authenticator := auth.AuthenticatorFunc(func(user, pass string) (bool, error) {
+			authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
 				authenticatorCalls++
-				return isValidCreds, nil
+				return testPrincipal(user, isValidCreds)
 			})
 
 			// use the same decorated handler for multiple calls to
 			// ensure we only use a single cache
-			handler = AuthDecorator(authenticator)(handler)
+			handler = AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)
 			for i := 0; i < 5; i++ {
 				c := newContextWithBasicAuth(t, "user", "pass")
 				resp, err := handler(c)
@@ -84,3 +99,472 @@ func TestAuthDecoratorCachesCredStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthDecoratorEvictsRevokedTokens(t *testing.T) {
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	var authenticatorCalls int
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		authenticatorCalls++
+		principal := auth.NewPrincipal(user, auth.AuthenticatorToken)
+		principal.TokenID = pass
+		return principal, true, nil
+	})
+
+	che := auth.NewAuthCache(10, time.Hour, time.Hour)
+	handler = AuthDecorator(authenticator, nil, nil, che, nil, nil)(handler)
+	for i := 0; i < 2; i++ {
+		if _, err := handler(newContextWithBasicAuth(t, "user", "token")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, 1, authenticatorCalls)
+
+	che.Invalidate("token")
+	if _, err := handler(newContextWithBasicAuth(t, "user", "token")); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, authenticatorCalls)
+}
+
+func TestAuthDecoratorDoesNotCacheExpiredTokens(t *testing.T) {
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	var authenticatorCalls int
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		authenticatorCalls++
+		if authenticatorCalls > 1 {
+			return nil, false, nil
+		}
+
+		// the token expires long before the cache's valid ttl
+		principal := auth.NewPrincipal(user, auth.AuthenticatorToken)
+		principal.TokenID = pass
+		principal.ExpiresAt = time.Now().Add(time.Millisecond * 50)
+		return principal, true, nil
+	})
+
+	che := auth.NewAuthCache(10, time.Hour, time.Hour)
+	handler = AuthDecorator(authenticator, nil, nil, che, nil, nil)(handler)
+	resp, err := handler(newContextWithBasicAuth(t, "user", "token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+
+	time.Sleep(time.Millisecond * 100)
+	resp, err = handler(newContextWithBasicAuth(t, "user", "token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 401)
+	assert.Equal(t, 2, authenticatorCalls)
+}
+
+func TestAuthDecoratorLockout(t *testing.T) {
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	var authenticatorCalls int
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		authenticatorCalls++
+		return testPrincipal(user, pass == "valid")
+	})
+
+	policy := auth.LockoutPolicy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour}
+	lockout := auth.NewLockout(policy, policy)
+	handler = AuthDecorator(authenticator, nil, nil, nil, lockout, nil)(handler)
+
+	// failures served from the cache are counted too
+	for _, code := range []int{401, 401, 401, 429} {
+		resp, err := handler(newContextWithBasicAuth(t, "user", "invalid"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertResponseCode(t, resp, code)
+	}
+
+	// valid credentials are also denied while the user is locked out
+	resp, err := handler(newContextWithBasicAuth(t, "user", "valid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := unmarshalBody(t, resp, nil)
+	assert.Equal(t, 429, recorder.Code)
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, 1, authenticatorCalls)
+}
+
+func TestSourceAddress(t *testing.T) {
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+
+	assert.Equal(t, "10.0.0.1", sourceAddress(req, false))
+	assert.Equal(t, "2.2.2.2", sourceAddress(req, true))
+}
+
+func TestAuthDecoratorStoresPrincipal(t *testing.T) {
+	principal := &auth.Principal{
+		Permissions:   auth.Permissions{Scopes: auth.AllScopes},
+		Username:      "owner",
+		Authenticator: auth.AuthenticatorToken,
+		TokenID:       "key",
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		assert.Equal(t, principal, getPrincipal(c))
+		assert.Equal(t, "owner", getUser(c))
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		return principal, true, nil
+	})
+
+	// the principal is also used for cached creds
+	handler = AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)
+	for i := 0; i < 2; i++ {
+		resp, err := handler(newContextWithBasicAuth(t, "user", "pass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertResponseCode(t, resp, 200)
+	}
+}
+
+func TestAuthDecoratorBearerAuth(t *testing.T) {
+	cases := map[string]int{
+		"valid":   200,
+		"invalid": 401,
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		t.Fatal("basic authenticator was called")
+		return nil, false, nil
+	})
+
+	bearer := auth.BearerAuthenticatorFunc(func(token string) (*auth.Principal, bool, error) {
+		return testPrincipal("user", token == "valid")
+	})
+
+	handler = AuthDecorator(authenticator, bearer, nil, nil, nil, nil)(handler)
+	for token, expectedCode := range cases {
+		t.Run(token, func(t *testing.T) {
+			c := newContextWithBasicAuth(t, "", "")
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := handler(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, expectedCode)
+		})
+	}
+}
+
+func TestAuthDecoratorRejectsBearerTokensOfServiceAccounts(t *testing.T) {
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		t.Fatal("handler was called")
+		return nil, nil
+	}
+
+	// e.g. an oidc token whose username claim can be edited by its user
+	bearer := auth.BearerAuthenticatorFunc(func(token string) (*auth.Principal, bool, error) {
+		return auth.NewPrincipal(auth.ServiceAccountUsername("carbon", "ci"), auth.AuthenticatorOIDC), true, nil
+	})
+
+	c := newContextWithBasicAuth(t, "", "")
+	c.Request.Header.Set("Authorization", "Bearer token")
+
+	resp, err := AuthDecorator(nil, bearer, nil, nil, nil, nil)(handler)(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 401)
+}
+
+func TestAuthDecoratorIgnoresBearerAuthWhenDisabled(t *testing.T) {
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		t.Fatal("handler was called")
+		return nil, nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		return testPrincipal(user, true)
+	})
+
+	c := newContextWithBasicAuth(t, "", "")
+	c.Request.Header.Set("Authorization", "Bearer token")
+
+	resp, err := AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 401)
+}
+
+func TestAuthDecoratorClientCertificateAuth(t *testing.T) {
+	cases := map[string]int{
+		"valid":   200,
+		"invalid": 401,
+		auth.ServiceAccountUsername("owner", "ci"): 401,
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		assert.Equal(t, auth.AuthenticatorClientCert, getPrincipal(c).Authenticator)
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		t.Fatal("basic authenticator was called")
+		return nil, false, nil
+	})
+
+	certificates := auth.CertificateAuthenticatorFunc(func(certificates []*x509.Certificate) (*auth.Principal, bool, error) {
+		name := certificates[0].Subject.CommonName
+		if name == "invalid" {
+			return nil, false, nil
+		}
+
+		return auth.NewPrincipal(name, auth.AuthenticatorClientCert), true, nil
+	})
+
+	handler = AuthDecorator(authenticator, nil, certificates, nil, nil, nil)(handler)
+	for name, expectedCode := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := generateContext(t, nil, nil)
+			c.Request.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: name}}},
+			}
+
+			resp, err := handler(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, expectedCode)
+		})
+	}
+}
+
+func TestAuthDecoratorPrefersBasicAuthToClientCertificate(t *testing.T) {
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		assert.Equal(t, "user", getUser(c))
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		return testPrincipal(user, true)
+	})
+
+	certificates := auth.CertificateAuthenticatorFunc(func([]*x509.Certificate) (*auth.Principal, bool, error) {
+		t.Fatal("certificate authenticator was called")
+		return nil, false, nil
+	})
+
+	c := newContextWithBasicAuth(t, "user", "pass")
+	c.Request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+
+	resp, err := AuthDecorator(authenticator, nil, certificates, nil, nil, nil)(handler)(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+}
+
+func TestAuthDecoratorEnforcesScopes(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Method       string
+		Path         string
+		ExpectedCode int
+	}{
+		{"api read", "GET", "/repository", 200},
+		{"api write", "DELETE", "/repository/owner/name", 403},
+		{"registry read", "HEAD", "/v2/owner/name/blobs/digest", 200},
+		{"registry write", "PUT", "/v2/owner/name/manifests/latest", 403},
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		principal := auth.NewPrincipal(user, auth.AuthenticatorToken)
+		principal.Scopes = []string{auth.ScopePull}
+		return principal, true, nil
+	})
+
+	handler = AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctx := newContextWithBasicAuth(t, "user", "pass")
+			ctx.Request.Method = c.Method
+			ctx.Request.URL.Path = c.Path
+
+			resp, err := handler(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, c.ExpectedCode)
+		})
+	}
+}
+
+func TestAuthDecoratorRestrictsAdminScopeToAdmins(t *testing.T) {
+	cases := []struct {
+		Name         string
+		User         string
+		Scopes       []string
+		ExpectedCode int
+	}{
+		{"admin user", "admin", auth.AllScopes, 200},
+		{"admin group", "member", auth.AllScopes, 200},
+		{"not an admin", "user", auth.AllScopes, 403},
+		{"admin token without the admin scope", "admin", []string{auth.ScopeManage}, 403},
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		principal := auth.NewPrincipal(user, auth.AuthenticatorToken)
+		principal.Scopes = strings.Split(pass, ",")
+		if user == "member" {
+			principal.Groups = []string{"admins"}
+		}
+
+		return principal, true, nil
+	})
+
+	admins, err := auth.NewAdmins([]string{"token:admin"}, []string{"token:admins"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler = AuthDecorator(authenticator, nil, nil, nil, nil, admins)(handler)
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctx := newContextWithBasicAuth(t, c.User, strings.Join(c.Scopes, ","))
+			ctx.Request.Method = "POST"
+			ctx.Request.URL.Path = "/account"
+
+			resp, err := handler(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, c.ExpectedCode)
+		})
+	}
+}
+
+func TestAuthDecoratorRestrictsServiceAccountsToTokens(t *testing.T) {
+	cases := map[string]int{
+		auth.AuthenticatorToken: 200,
+		auth.AuthenticatorLDAP:  401,
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	authenticator := auth.AuthenticatorFunc(func(user, pass string) (*auth.Principal, bool, error) {
+		return auth.NewPrincipal(auth.ServiceAccountUsername("carbon", "ci"), pass), true, nil
+	})
+
+	handler = AuthDecorator(authenticator, nil, nil, nil, nil, nil)(handler)
+	for authenticatorName, expectedCode := range cases {
+		t.Run(authenticatorName, func(t *testing.T) {
+			ctx := newContextWithBasicAuth(t, "user", authenticatorName)
+			resp, err := handler(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, expectedCode)
+		})
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	cases := []struct {
+		Method   string
+		Path     string
+		Expected string
+	}{
+		{"GET", "/v2/", auth.ScopePull},
+		{"HEAD", "/v2/owner/name/blobs/digest", auth.ScopePull},
+		{"PUT", "/v2/owner/name/manifests/latest", auth.ScopePush},
+		{"POST", "/v2/owner/name/blobs/uploads/", auth.ScopePush},
+		{"PATCH", "/v2/owner/name/blobs/uploads/id", auth.ScopePush},
+		{"DELETE", "/v2/owner/name/manifests/digest", auth.ScopePush},
+		{"GET", "/repository", auth.ScopePull},
+		{"POST", "/repository/owner", auth.ScopeManage},
+		{"DELETE", "/repository/owner/name", auth.ScopeManage},
+		{"POST", "/token", auth.ScopeManage},
+		{"DELETE", "/token/id", auth.ScopeManage},
+		{"DELETE", "/user/name/tokens", auth.ScopeAdmin},
+		{"GET", "/owner", auth.ScopePull},
+		{"POST", "/owner", auth.ScopeAdmin},
+		{"PUT", "/owner/name/member/user", auth.ScopeManage},
+		{"GET", "/account", auth.ScopePull},
+		{"POST", "/account", auth.ScopeAdmin},
+		{"DELETE", "/account/id", auth.ScopeAdmin},
+		{"GET", "/lockout", auth.ScopeAdmin},
+		{"DELETE", "/lockout/user/name", auth.ScopeAdmin},
+		{"GET", "/audit", auth.ScopeAdmin},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.Method, c.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := requiredScope(req), c.Expected; v != want {
+			t.Errorf("%s %s: scope was '%v', expected '%v'", c.Method, c.Path, v, want)
+		}
+	}
+}
+
+func TestGetPrincipalWithoutAuthDecorator(t *testing.T) {
+	c := generateContext(t, nil, nil)
+	principal := getPrincipal(c)
+
+	assert.True(t, principal.IsAnonymous())
+	assert.False(t, principal.IsAdmin())
+	for _, scope := range auth.AllScopes {
+		assert.False(t, principal.HasScope(scope), scope)
+	}
+
+	repository := auth.NewRepository("owner/name")
+	assert.False(t, repository.CanRead(principal, nil))
+}
@@ -2,15 +2,159 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/models"
+	"github.com/zpatrick/fireball"
 )
 
+// canWriteOwner returns true if the principal stored in the context can create, delete, or push to
+// the repositories of the owner. Owners which have not been registered have no members.
+func canWriteOwner(c *fireball.Context, owners auth.OwnerManager, name string) (bool, error) {
+	owner, err := owners.GetOwner(name)
+	if err != nil {
+		if err == auth.ErrOwnerNotFound {
+			log.Printf("[DEBUG] Owner '%s' has not been registered", name)
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return owner.CanWrite(getPrincipal(c)), nil
+}
+
+// canManageServiceAccount returns true if the principal stored in the context maintains
+// the owner of the service account with the username. It returns false for users who are not service accounts.
+func canManageServiceAccount(c *fireball.Context, owners auth.OwnerManager, username string) (bool, error) {
+	ownerName, _, ok := auth.ParseServiceAccountUsername(username)
+	if !ok {
+		return false, nil
+	}
+
+	owner, err := findOwner(owners, ownerName)
+	if err != nil {
+		return false, err
+	}
+
+	return owner != nil && owner.CanManage(getPrincipal(c)), nil
+}
+
+// canReadRepository returns true if the principal stored in the context can pull from the repository
+func canReadRepository(c *fireball.Context, repositories auth.RepositoryManager, owners auth.OwnerManager, name string) (bool, error) {
+	repository, err := getRepository(repositories, name)
+	if err != nil {
+		return false, err
+	}
+
+	return isReadable(c, owners, repository)
+}
+
+// isReadable returns true if the principal stored in the context can pull from the repository
+func isReadable(c *fireball.Context, owners auth.OwnerManager, repository *auth.Repository) (bool, error) {
+	if repository.Visibility != auth.VisibilityPrivate {
+		return repository.CanRead(getPrincipal(c), nil), nil
+	}
+
+	owner, err := findOwner(owners, repositoryOwner(repository.Name))
+	if err != nil {
+		return false, err
+	}
+
+	return repository.CanRead(getPrincipal(c), owner), nil
+}
+
+// getRepository returns the metadata of the repository. Repositories without metadata are internal.
+func getRepository(repositories auth.RepositoryManager, name string) (*auth.Repository, error) {
+	repository, err := repositories.GetRepository(name)
+	if err != nil {
+		if err == auth.ErrRepositoryNotFound {
+			return auth.NewRepository(name), nil
+		}
+
+		return nil, err
+	}
+
+	return repository, nil
+}
+
+// filterReadableRepositories returns the repositories the principal stored in the context can pull from
+func filterReadableRepositories(c *fireball.Context, repositories auth.RepositoryManager, owners auth.OwnerManager, names []string) ([]string, error) {
+	metadata, err := repositories.ListRepositories()
+	if err != nil {
+		return nil, err
+	}
+
+	private := map[string]*auth.Repository{}
+	for _, repository := range metadata {
+		if repository.Visibility == auth.VisibilityPrivate {
+			private[repository.Name] = repository
+		}
+	}
+
+	principal := getPrincipal(c)
+	ownersByName := map[string]*auth.Owner{}
+	readable := []string{}
+	for _, name := range names {
+		repository, ok := private[name]
+		if !ok {
+			readable = append(readable, name)
+			continue
+		}
+
+		ownerName := repositoryOwner(name)
+		owner, ok := ownersByName[ownerName]
+		if !ok {
+			owner, err = findOwner(owners, ownerName)
+			if err != nil {
+				return nil, err
+			}
+
+			ownersByName[ownerName] = owner
+		}
+
+		if repository.CanRead(principal, owner) {
+			readable = append(readable, name)
+		}
+	}
+
+	return readable, nil
+}
+
+// findOwner returns nil if the owner has not been registered
+func findOwner(owners auth.OwnerManager, name string) (*auth.Owner, error) {
+	owner, err := owners.GetOwner(name)
+	if err != nil {
+		if err == auth.ErrOwnerNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return owner, nil
+}
+
+// repositoryOwner returns the owner of a repository, e.g. 'carbon' for 'carbon/api'
+func repositoryOwner(repository string) string {
+	return strings.SplitN(repository, "/", 2)[0]
+}
+
+func newNotAdminError(user string) (*fireball.HTTPError, error) {
+	return fireball.NewJSONError(403, fmt.Errorf("User '%s' is not an admin", user))
+}
+
+func newNotWriterError(c *fireball.Context, owner string) (*fireball.HTTPError, error) {
+	return fireball.NewJSONError(403, fmt.Errorf("User '%s' is not a maintainer or developer of owner '%s'", getUser(c), owner))
+}
+
 func listRepositories(e ecriface.ECRAPI) ([]string, error) {
 	repositories := []string{}
 
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/zpatrick/fireball"
+)
+
+type LockoutController struct {
+	lockout *auth.Lockout
+}
+
+func NewLockoutController(l *auth.Lockout) *LockoutController {
+	return &LockoutController{
+		lockout: l,
+	}
+}
+
+func (l *LockoutController) Routes() []*fireball.Route {
+	return []*fireball.Route{
+		{
+			Path: "/lockout",
+			Handlers: fireball.Handlers{
+				"GET": l.ListLockouts,
+			},
+		},
+		{
+			Path: "/lockout/:type/:name",
+			Handlers: fireball.Handlers{
+				"DELETE": l.ClearLockout,
+			},
+		},
+	}
+}
+
+// ListLockouts lists the usernames and addresses with recent failures.
+// Failures are counted by each instance, so only the lockouts of the instance which handles the request are listed.
+func (l *LockoutController) ListLockouts(c *fireball.Context) (fireball.Response, error) {
+	statuses := l.lockout.Lockouts()
+	resp := models.ListLockoutsResponse{
+		Lockouts: make([]models.Lockout, len(statuses)),
+	}
+
+	for i, status := range statuses {
+		resp.Lockouts[i] = models.Lockout{
+			Type:        status.Type,
+			Name:        status.Name,
+			Address:     status.Address,
+			Failures:    status.Failures,
+			LastFailure: status.LastFailure,
+		}
+
+		if lockedUntil := status.LockedUntil; !lockedUntil.IsZero() {
+			resp.Lockouts[i].LockedUntil = &lockedUntil
+		}
+	}
+
+	return fireball.NewJSONResponse(200, resp)
+}
+
+// ClearLockout forgets the failures of a username from every address, or of an address.
+// Only the lockouts of the instance which handles the request are cleared.
+func (l *LockoutController) ClearLockout(c *fireball.Context) (fireball.Response, error) {
+	lockoutType := c.PathVariables["type"]
+	name := c.PathVariables["name"]
+
+	if lockoutType != auth.LockoutTypeUser && lockoutType != auth.LockoutTypeAddress {
+		return fireball.NewJSONError(400, fmt.Errorf("Lockout type must be '%s' or '%s'", auth.LockoutTypeUser, auth.LockoutTypeAddress))
+	}
+
+	if !l.lockout.Clear(lockoutType, name) {
+		return fireball.NewJSONError(404, fmt.Errorf("No lockout exists for %s '%s'", lockoutType, name))
+	}
+
+	return fireball.NewResponse(200, []byte("Successfully cleared lockout"), nil), nil
+}
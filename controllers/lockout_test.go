@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
+)
+
+func TestListLockouts(t *testing.T) {
+	lockout := auth.NewLockout(auth.DefaultUserLockoutPolicy, auth.DefaultAddressLockoutPolicy)
+	lockout.Fail("user", "10.0.0.1")
+
+	controller := NewLockoutController(lockout)
+	resp, err := controller.ListLockouts(&fireball.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.ListLockoutsResponse
+	recorder := unmarshalBody(t, resp, &response)
+	assert.Equal(t, 200, recorder.Code)
+
+	if assert.Len(t, response.Lockouts, 2) {
+		assert.Equal(t, auth.LockoutTypeAddress, response.Lockouts[0].Type)
+		assert.Equal(t, "10.0.0.1", response.Lockouts[0].Name)
+		assert.Equal(t, 1, response.Lockouts[0].Failures)
+		assert.Nil(t, response.Lockouts[0].LockedUntil)
+		assert.Equal(t, auth.LockoutTypeUser, response.Lockouts[1].Type)
+		assert.Equal(t, "user", response.Lockouts[1].Name)
+		assert.Equal(t, "10.0.0.1", response.Lockouts[1].Address)
+	}
+}
+
+func TestClearLockout(t *testing.T) {
+	lockout := auth.NewLockout(auth.DefaultUserLockoutPolicy, auth.DefaultAddressLockoutPolicy)
+	lockout.Fail("user", "")
+
+	controller := NewLockoutController(lockout)
+	cases := []struct {
+		Type         string
+		Name         string
+		ExpectedCode int
+	}{
+		{"invalid", "user", 400},
+		{auth.LockoutTypeUser, "user", 200},
+		{auth.LockoutTypeUser, "user", 404},
+	}
+
+	for _, c := range cases {
+		ctx := &fireball.Context{
+			PathVariables: map[string]string{
+				"type": c.Type,
+				"name": c.Name,
+			},
+		}
+
+		resp, err := controller.ClearLockout(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertResponseCode(t, resp, c.ExpectedCode)
+	}
+}
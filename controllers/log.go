@@ -3,18 +3,38 @@ package controllers
 import (
 	"log"
 
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/zpatrick/fireball"
 )
 
+// LogDecorator logs each request after it has been handled,
+// including the principal stored in the context by the AuthDecorator
 func LogDecorator() fireball.Decorator {
 	return func(handler fireball.Handler) fireball.Handler {
 		return func(c *fireball.Context) (fireball.Response, error) {
-			log.Printf("[DEBUG] %s %s %s\n",
-				c.Request.RemoteAddr,
-				c.Request.Method,
-				c.Request.URL.String())
+			resp, err := handler(c)
 
-			return handler(c)
+			if principal, ok := c.Meta[principalKey].(*auth.Principal); ok {
+				kind := "user"
+				if principal.IsServiceAccount() {
+					kind = "service account"
+				}
+
+				log.Printf("[DEBUG] %s %s %s (%s '%s' through %s)\n",
+					c.Request.RemoteAddr,
+					c.Request.Method,
+					c.Request.URL.String(),
+					kind,
+					principal.Username,
+					principal.Authenticator)
+			} else {
+				log.Printf("[DEBUG] %s %s %s\n",
+					c.Request.RemoteAddr,
+					c.Request.Method,
+					c.Request.URL.String())
+			}
+
+			return resp, err
 		}
 	}
 }
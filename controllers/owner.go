@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/zpatrick/fireball"
+)
+
+type OwnerController struct {
+	owners auth.OwnerManager
+}
+
+func NewOwnerController(o auth.OwnerManager) *OwnerController {
+	return &OwnerController{
+		owners: o,
+	}
+}
+
+func (o *OwnerController) Routes() []*fireball.Route {
+	return []*fireball.Route{
+		{
+			Path: "/owner",
+			Handlers: fireball.Handlers{
+				"GET":  o.ListOwners,
+				"POST": o.CreateOwner,
+			},
+		},
+		{
+			Path: "/owner/:owner",
+			Handlers: fireball.Handlers{
+				"GET": o.GetOwner,
+			},
+		},
+		{
+			Path: "/owner/:owner/member/:user",
+			Handlers: fireball.Handlers{
+				"PUT":    o.PutMember,
+				"DELETE": o.DeleteMember,
+			},
+		},
+		{
+			Path: "/owner/:owner/group/:group",
+			Handlers: fireball.Handlers{
+				"PUT":    o.PutGroup,
+				"DELETE": o.DeleteGroup,
+			},
+		},
+	}
+}
+
+func (o *OwnerController) ListOwners(c *fireball.Context) (fireball.Response, error) {
+	owners, err := o.owners.ListOwners()
+	if err != nil {
+		return nil, err
+	}
+
+	return fireball.NewJSONResponse(200, models.ListOwnersResponse{Owners: owners})
+}
+
+// CreateOwner registers a new owner with the requested maintainer, or the user who creates it.
+// Only admins can create owners, since the repositories of unregistered owners may already exist in ecr.
+// The AuthDecorator restricts this request to admins.
+func (o *OwnerController) CreateOwner(c *fireball.Context) (fireball.Response, error) {
+	user := getUser(c)
+	if !isAdmin(c) {
+		return newNotAdminError(user)
+	}
+
+	var req models.CreateOwnerRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	maintainer := req.Maintainer
+	if maintainer == "" {
+		maintainer = user
+	}
+
+	if _, _, ok := auth.ParseServiceAccountUsername(maintainer); ok {
+		return fireball.NewJSONError(400, fmt.Errorf("Service accounts cannot maintain owners"))
+	}
+
+	owner := auth.NewOwner(req.Name)
+	owner.Members[maintainer] = auth.RoleMaintainer
+
+	if err := o.owners.CreateOwner(owner); err != nil {
+		if err == auth.ErrOwnerExists {
+			return fireball.NewJSONError(409, fmt.Errorf("Owner '%s' already exists", req.Name))
+		}
+
+		return nil, err
+	}
+
+	log.Printf("[INFO] User '%s' created owner '%s' with maintainer '%s'", user, req.Name, maintainer)
+	return fireball.NewJSONResponse(200, ownerToModel(owner))
+}
+
+func (o *OwnerController) GetOwner(c *fireball.Context) (fireball.Response, error) {
+	name := c.PathVariables["owner"]
+	owner, err := o.owners.GetOwner(name)
+	if err != nil {
+		if err == auth.ErrOwnerNotFound {
+			return newOwnerNotFoundError(name)
+		}
+
+		return nil, err
+	}
+
+	return fireball.NewJSONResponse(200, ownerToModel(owner))
+}
+
+func (o *OwnerController) PutMember(c *fireball.Context) (fireball.Response, error) {
+	return o.putRole(c, memberRoles, c.PathVariables["user"])
+}
+
+func (o *OwnerController) DeleteMember(c *fireball.Context) (fireball.Response, error) {
+	return o.setRole(c, memberRoles, c.PathVariables["user"], "")
+}
+
+func (o *OwnerController) PutGroup(c *fireball.Context) (fireball.Response, error) {
+	return o.putRole(c, groupRoles, c.PathVariables["group"])
+}
+
+func (o *OwnerController) DeleteGroup(c *fireball.Context) (fireball.Response, error) {
+	return o.setRole(c, groupRoles, c.PathVariables["group"], "")
+}
+
+func (o *OwnerController) putRole(c *fireball.Context, roles func(*auth.Owner) map[string]string, name string) (fireball.Response, error) {
+	var req models.PutMemberRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := auth.ValidateRole(req.Role); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	return o.setRole(c, roles, name, req.Role)
+}
+
+// setRole sets the role of a user or group in the owner, or removes it if role is empty.
+// Only maintainers of the owner can change its roles, and it must be left with at least one maintainer.
+func (o *OwnerController) setRole(c *fireball.Context, roles func(*auth.Owner) map[string]string, name, role string) (fireball.Response, error) {
+	ownerName := c.PathVariables["owner"]
+	owner, err := o.owners.GetOwner(ownerName)
+	if err != nil {
+		if err == auth.ErrOwnerNotFound {
+			return newOwnerNotFoundError(ownerName)
+		}
+
+		return nil, err
+	}
+
+	user := getUser(c)
+	// admins can manage every owner, e.g. to replace maintainers who have left
+	if !owner.CanManage(getPrincipal(c)) && !isAdmin(c) {
+		return fireball.NewJSONError(403, fmt.Errorf("User '%s' is not a maintainer of owner '%s'", user, ownerName))
+	}
+
+	if role == "" {
+		if _, ok := roles(owner)[name]; !ok {
+			return fireball.NewJSONError(404, fmt.Errorf("'%s' is not a member of owner '%s'", name, ownerName))
+		}
+
+		delete(roles(owner), name)
+	} else {
+		roles(owner)[name] = role
+	}
+
+	if !owner.HasMaintainer() {
+		return fireball.NewJSONError(400, fmt.Errorf("Owner '%s' must have at least one maintainer", ownerName))
+	}
+
+	if err := o.owners.PutOwner(owner); err != nil {
+		if err == auth.ErrOwnerModified {
+			return newOwnerModifiedError(ownerName)
+		}
+
+		return nil, err
+	}
+
+	if role == "" {
+		log.Printf("[INFO] User '%s' removed '%s' from owner '%s'", user, name, ownerName)
+	} else {
+		log.Printf("[INFO] User '%s' gave '%s' the '%s' role in owner '%s'", user, name, role, ownerName)
+	}
+
+	return fireball.NewJSONResponse(200, ownerToModel(owner))
+}
+
+func memberRoles(owner *auth.Owner) map[string]string {
+	return owner.Members
+}
+
+func groupRoles(owner *auth.Owner) map[string]string {
+	return owner.Groups
+}
+
+func newOwnerNotFoundError(name string) (*fireball.HTTPError, error) {
+	return fireball.NewJSONError(404, fmt.Errorf("Owner '%s' does not exist", name))
+}
+
+func newOwnerModifiedError(name string) (*fireball.HTTPError, error) {
+	return fireball.NewJSONError(409, fmt.Errorf("Owner '%s' was modified by another request, please retry", name))
+}
+
+func ownerToModel(owner *auth.Owner) models.Owner {
+	return models.Owner{
+		Name:            owner.Name,
+		Members:         rolesToModels(owner.Members),
+		Groups:          rolesToModels(owner.Groups),
+		ServiceAccounts: serviceAccountsToModels(owner),
+	}
+}
+
+func rolesToModels(roles map[string]string) []models.OwnerMember {
+	members := make([]models.OwnerMember, 0, len(roles))
+	for name, role := range roles {
+		members = append(members, models.OwnerMember{Name: name, Role: role})
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+
+	return members
+}
+
+func serviceAccountsToModels(owner *auth.Owner) []models.ServiceAccount {
+	serviceAccounts := make([]models.ServiceAccount, 0, len(owner.ServiceAccounts))
+	for _, serviceAccount := range owner.ServiceAccounts {
+		serviceAccounts = append(serviceAccounts, serviceAccountToModel(owner.Name, serviceAccount))
+	}
+
+	sort.Slice(serviceAccounts, func(i, j int) bool {
+		return serviceAccounts[i].Name < serviceAccounts[j].Name
+	})
+
+	return serviceAccounts
+}
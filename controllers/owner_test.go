@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
+)
+
+func newOwnerContext(t *testing.T, body interface{}, pathVariables map[string]string, user string) *fireball.Context {
+	c := generateContext(t, body, pathVariables)
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal(user, auth.AuthenticatorLDAP),
+	}
+
+	return c
+}
+
+func newOwnerAdminContext(t *testing.T, body interface{}, user string) *fireball.Context {
+	c := newOwnerContext(t, body, nil, user)
+	getPrincipal(c).Admin = true
+	return c
+}
+
+func TestCreateOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewOwnerController(mockOwnerManager)
+
+	expected := auth.NewOwner("carbon")
+	expected.Members["john.doe"] = auth.RoleMaintainer
+
+	gomock.InOrder(
+		mockOwnerManager.EXPECT().
+			CreateOwner(expected).
+			Return(nil),
+		mockOwnerManager.EXPECT().
+			CreateOwner(expected).
+			Return(auth.ErrOwnerExists),
+	)
+
+	c := newOwnerAdminContext(t, models.CreateOwnerRequest{Name: "carbon"}, "john.doe")
+	resp, err := controller.CreateOwner(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.Owner
+	recorder := unmarshalBody(t, resp, &response)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, []models.OwnerMember{{Name: "john.doe", Role: auth.RoleMaintainer}}, response.Members)
+
+	c = newOwnerAdminContext(t, models.CreateOwnerRequest{Name: "carbon"}, "john.doe")
+	resp, err = controller.CreateOwner(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 409)
+}
+
+func TestCreateOwnerInputValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	controller := NewOwnerController(mock.NewMockOwnerManager(ctrl))
+
+	cases := []models.CreateOwnerRequest{
+		{Name: ""},
+		{Name: "carbon/api"},
+		{Name: "carbon", Maintainer: auth.ServiceAccountUsername("carbon", "ci")},
+	}
+
+	for _, req := range cases {
+		c := newOwnerAdminContext(t, req, "john.doe")
+		resp, err := controller.CreateOwner(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertResponseCode(t, resp, 400)
+	}
+}
+
+func TestCreateOwnerWithMaintainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewOwnerController(mockOwnerManager)
+
+	expected := auth.NewOwner("carbon")
+	expected.Members["jane.doe"] = auth.RoleMaintainer
+
+	mockOwnerManager.EXPECT().
+		CreateOwner(expected).
+		Return(nil)
+
+	c := newOwnerAdminContext(t, models.CreateOwnerRequest{Name: "carbon", Maintainer: "jane.doe"}, "admin")
+	resp, err := controller.CreateOwner(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+}
+
+func TestCreateOwnerRequiresAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the repositories of unregistered owners may already exist, so users cannot claim owners
+	controller := NewOwnerController(mock.NewMockOwnerManager(ctrl))
+
+	c := newOwnerContext(t, models.CreateOwnerRequest{Name: "carbon"}, nil, "john.doe")
+	resp, err := controller.CreateOwner(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestGetOwnerNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewOwnerController(mockOwnerManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(nil, auth.ErrOwnerNotFound)
+
+	c := newOwnerContext(t, nil, map[string]string{"owner": "carbon"}, "john.doe")
+	resp, err := controller.GetOwner(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 404)
+}
+
+func TestPutMember(t *testing.T) {
+	cases := []struct {
+		Name         string
+		User         string
+		Role         string
+		ExpectedCode int
+	}{
+		{"maintainer adds developer", "maintainer", auth.RoleDeveloper, 200},
+		{"developer cannot add members", "developer", auth.RoleDeveloper, 403},
+		{"invalid role", "maintainer", "owner", 400},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+			controller := NewOwnerController(mockOwnerManager)
+
+			owner := auth.NewOwner("carbon")
+			owner.Members["maintainer"] = auth.RoleMaintainer
+			owner.Members["developer"] = auth.RoleDeveloper
+
+			mockOwnerManager.EXPECT().
+				GetOwner("carbon").
+				Return(owner, nil).
+				AnyTimes()
+
+			if c.ExpectedCode == 200 {
+				validatePutOwner := func(owner *auth.Owner) {
+					assert.Equal(t, c.Role, owner.Members["new"])
+				}
+
+				mockOwnerManager.EXPECT().
+					PutOwner(gomock.Any()).
+					Do(validatePutOwner).
+					Return(nil)
+			}
+
+			pathVariables := map[string]string{"owner": "carbon", "user": "new"}
+			ctx := newOwnerContext(t, models.PutMemberRequest{Role: c.Role}, pathVariables, c.User)
+			resp, err := controller.PutMember(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, c.ExpectedCode)
+		})
+	}
+}
+
+func TestDeleteMember(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Member       string
+		ExpectedCode int
+	}{
+		{"developer", "developer", 200},
+		{"last maintainer", "maintainer", 400},
+		{"not a member", "other", 404},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+			controller := NewOwnerController(mockOwnerManager)
+
+			owner := auth.NewOwner("carbon")
+			owner.Members["maintainer"] = auth.RoleMaintainer
+			owner.Members["developer"] = auth.RoleDeveloper
+
+			mockOwnerManager.EXPECT().
+				GetOwner("carbon").
+				Return(owner, nil)
+
+			if c.ExpectedCode == 200 {
+				validatePutOwner := func(owner *auth.Owner) {
+					assert.Equal(t, map[string]string{"maintainer": auth.RoleMaintainer}, owner.Members)
+				}
+
+				mockOwnerManager.EXPECT().
+					PutOwner(gomock.Any()).
+					Do(validatePutOwner).
+					Return(nil)
+			}
+
+			pathVariables := map[string]string{"owner": "carbon", "user": c.Member}
+			resp, err := controller.DeleteMember(newOwnerContext(t, nil, pathVariables, "maintainer"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, c.ExpectedCode)
+		})
+	}
+}
+
+func TestPutMemberOwnerModified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewOwnerController(mockOwnerManager)
+
+	owner := auth.NewOwner("carbon")
+	owner.Members["maintainer"] = auth.RoleMaintainer
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(owner, nil)
+
+	mockOwnerManager.EXPECT().
+		PutOwner(gomock.Any()).
+		Return(auth.ErrOwnerModified)
+
+	pathVariables := map[string]string{"owner": "carbon", "user": "new"}
+	ctx := newOwnerContext(t, models.PutMemberRequest{Role: auth.RoleDeveloper}, pathVariables, "maintainer")
+	resp, err := controller.PutMember(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 409)
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/controllers/proxy"
 	"github.com/zpatrick/fireball"
 	"github.com/zpatrick/go-cache"
@@ -18,20 +19,73 @@ import (
 const TokenExpiry = time.Hour * 12
 
 type ProxyController struct {
-	ecr   ecriface.ECRAPI
-	proxy proxy.Proxy
-	cache *cache.Cache
+	ecr          ecriface.ECRAPI
+	proxy        proxy.Proxy
+	owners       auth.OwnerManager
+	repositories auth.RepositoryManager
+	cache        *cache.Cache
 }
 
-func NewProxyController(ecr ecriface.ECRAPI, p proxy.Proxy) *ProxyController {
+func NewProxyController(ecr ecriface.ECRAPI, p proxy.Proxy, o auth.OwnerManager, r auth.RepositoryManager) *ProxyController {
 	return &ProxyController{
-		ecr:   ecr,
-		proxy: p,
-		cache: cache.New(),
+		ecr:          ecr,
+		proxy:        p,
+		owners:       o,
+		repositories: r,
+		cache:        cache.New(),
 	}
 }
 
 func (p *ProxyController) DoProxy(c *fireball.Context) (fireball.Response, error) {
+	// registry api requests which are not scoped to a repository cannot be checked against read acls,
+	// and the catalog lists every repository, so only admins and registry tokens with catalog access can list it
+	path := c.Request.URL.Path
+	repository, hasRepository := parseRegistryRepository(path)
+	if !hasRepository && isRegistryRequest(c.Request) && !isRegistryBase(path) {
+		principal := getPrincipal(c)
+		canList := principal.IsAdmin() || principal.Authenticator == auth.AuthenticatorRegistryToken
+		if !isRegistryCatalog(path) || !canList {
+			log.Printf("[DEBUG] Denying user '%s' %s %s", principal.Username, c.Request.Method, path)
+			return newUnscopedDeniedError()
+		}
+	}
+
+	if hasRepository {
+		principal := getPrincipal(c)
+		if !principal.CanAccessRepository(repository) {
+			log.Printf("[DEBUG] Denying user '%s' access to repository '%s'", principal.Username, repository)
+			return newDeniedError(repository)
+		}
+
+		// registry tokens are only issued with the pull action to users who can read the repository,
+		// and with the push action to maintainers and developers of the owner
+		isRead := c.Request.Method == "GET" || c.Request.Method == "HEAD"
+		if isRead && principal.Authenticator != auth.AuthenticatorRegistryToken {
+			canRead, err := canReadRepository(c, p.repositories, p.owners, repository)
+			if err != nil {
+				return nil, err
+			}
+
+			if !canRead {
+				log.Printf("[DEBUG] Denying user '%s' pull from private repository '%s'", principal.Username, repository)
+				return newDeniedError(repository)
+			}
+		}
+
+		if !isRead && principal.Authenticator != auth.AuthenticatorRegistryToken {
+			owner := repositoryOwner(repository)
+			canWrite, err := canWriteOwner(c, p.owners, owner)
+			if err != nil {
+				return nil, err
+			}
+
+			if !canWrite {
+				log.Printf("[DEBUG] Denying user '%s' push to repository '%s': cannot write to owner '%s'", principal.Username, repository, owner)
+				return newDeniedError(repository)
+			}
+		}
+	}
+
 	token, err := p.getRegistryAuthToken()
 	if err != nil {
 		log.Printf("[ERROR] Failed to get auth token for registry: %v", err)
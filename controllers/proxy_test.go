@@ -2,13 +2,17 @@ package controllers
 
 import (
 	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/controllers/proxy"
 	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
 )
 
 func TestProxy(t *testing.T) {
@@ -22,7 +26,7 @@ func TestProxy(t *testing.T) {
 	})
 
 	mockECR := mock.NewMockECRAPI(ctrl)
-	controller := NewProxyController(mockECR, testProxy)
+	controller := NewProxyController(mockECR, testProxy, mock.NewMockOwnerManager(ctrl), mock.NewMockRepositoryManager(ctrl))
 
 	authData := []*ecr.AuthorizationData{
 		{AuthorizationToken: aws.String("token")},
@@ -41,3 +45,260 @@ func TestProxy(t *testing.T) {
 	// run the test proxy
 	resp.Write(nil, nil)
 }
+
+func TestProxyDeniesRestrictedRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testProxy := proxy.ProxyFunc(func(token string, w http.ResponseWriter, r *http.Request) {
+		t.Fatal("proxy was called")
+	})
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	controller := NewProxyController(mockECR, testProxy, mock.NewMockOwnerManager(ctrl), mock.NewMockRepositoryManager(ctrl))
+
+	c := generateContext(t, nil, nil)
+	c.Request.URL = &url.URL{Path: "/v2/other/name/manifests/latest"}
+	c.Meta = map[string]interface{}{
+		principalKey: &auth.Principal{Permissions: auth.Permissions{Repositories: []string{"owner/*"}}},
+	}
+
+	resp, err := controller.DoProxy(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestProxyPushRequiresMembership(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var proxyCalls int
+	testProxy := proxy.ProxyFunc(func(token string, w http.ResponseWriter, r *http.Request) {
+		proxyCalls++
+	})
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewProxyController(mockECR, testProxy, mockOwnerManager, mockRepositoryManager)
+
+	mockECR.EXPECT().
+		GetAuthorizationToken(gomock.Any()).
+		Return(&ecr.GetAuthorizationTokenOutput{
+			AuthorizationData: []*ecr.AuthorizationData{{AuthorizationToken: aws.String("token")}},
+		}, nil).
+		AnyTimes()
+
+	mockOwnerManager.EXPECT().
+		GetOwner("owner").
+		Return(&auth.Owner{Name: "owner", Members: map[string]string{"member": auth.RoleDeveloper}}, nil).
+		Times(2)
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("owner/name").
+		Return(nil, auth.ErrRepositoryNotFound)
+
+	cases := []struct {
+		Method       string
+		User         string
+		ExpectedCode int
+	}{
+		{"PUT", "member", 200},
+		{"PUT", "other", 403},
+		{"GET", "other", 200},
+	}
+
+	for _, c := range cases {
+		ctx := generateContext(t, nil, nil)
+		ctx.Request.Method = c.Method
+		ctx.Request.URL = &url.URL{Path: "/v2/owner/name/manifests/latest"}
+		ctx.Meta = map[string]interface{}{
+			principalKey: auth.NewPrincipal(c.User, auth.AuthenticatorLDAP),
+		}
+
+		resp, err := controller.DoProxy(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.ExpectedCode == 200 {
+			resp.Write(nil, nil)
+			continue
+		}
+
+		assertResponseCode(t, resp, c.ExpectedCode)
+	}
+
+	assert.Equal(t, 2, proxyCalls)
+}
+
+func TestProxyPullRequiresReadAccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var proxyCalls int
+	testProxy := proxy.ProxyFunc(func(token string, w http.ResponseWriter, r *http.Request) {
+		proxyCalls++
+	})
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewProxyController(mockECR, testProxy, mockOwnerManager, mockRepositoryManager)
+
+	mockECR.EXPECT().
+		GetAuthorizationToken(gomock.Any()).
+		Return(&ecr.GetAuthorizationTokenOutput{
+			AuthorizationData: []*ecr.AuthorizationData{{AuthorizationToken: aws.String("token")}},
+		}, nil).
+		AnyTimes()
+
+	repository := auth.NewRepository("owner/name")
+	repository.Visibility = auth.VisibilityPrivate
+	repository.ReadUsers = []string{"reader"}
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("owner/name").
+		Return(repository, nil).
+		Times(3)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("owner").
+		Return(&auth.Owner{Name: "owner", Members: map[string]string{"member": auth.RoleReader}}, nil).
+		Times(3)
+
+	cases := []struct {
+		Principal    *auth.Principal
+		ExpectedCode int
+	}{
+		{auth.NewPrincipal("member", auth.AuthenticatorLDAP), 200},
+		{auth.NewPrincipal("reader", auth.AuthenticatorLDAP), 200},
+		{auth.NewPrincipal("other", auth.AuthenticatorLDAP), 403},
+		// registry tokens are only issued with the pull action to users who can read the repository
+		{auth.NewPrincipal("other", auth.AuthenticatorRegistryToken), 200},
+	}
+
+	for _, c := range cases {
+		ctx := generateContext(t, nil, nil)
+		ctx.Request.URL = &url.URL{Path: "/v2/owner/name/blobs/sha256:digest"}
+		ctx.Meta = map[string]interface{}{
+			principalKey: c.Principal,
+		}
+
+		resp, err := controller.DoProxy(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.ExpectedCode == 200 {
+			resp.Write(nil, nil)
+			continue
+		}
+
+		assertResponseCode(t, resp, c.ExpectedCode)
+	}
+
+	assert.Equal(t, 3, proxyCalls)
+}
+
+func TestProxyChecksRepositoriesNamedAfterRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testProxy := proxy.ProxyFunc(func(token string, w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request was proxied")
+	})
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewProxyController(mock.NewMockECRAPI(ctrl), testProxy, mockOwnerManager, mockRepositoryManager)
+
+	// '/v2/team/tags/manifests/latest' reads the 'team/tags' repository, not a 'team' repository
+	repository := auth.NewRepository("team/tags")
+	repository.Visibility = auth.VisibilityPrivate
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("team/tags").
+		Return(repository, nil)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("team").
+		Return(auth.NewOwner("team"), nil)
+
+	ctx := generateContext(t, nil, nil)
+	ctx.Request.Method = "GET"
+	ctx.Request.URL = &url.URL{Path: "/v2/team/tags/manifests/latest"}
+	ctx.Meta = map[string]interface{}{principalKey: auth.NewPrincipal("other", auth.AuthenticatorLDAP)}
+
+	resp, err := controller.DoProxy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestProxyDeniesPathsWithoutRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockECR.EXPECT().
+		GetAuthorizationToken(gomock.Any()).
+		Return(&ecr.GetAuthorizationTokenOutput{AuthorizationData: []*ecr.AuthorizationData{{AuthorizationToken: aws.String("token")}}}, nil).
+		AnyTimes()
+
+	testProxy := proxy.ProxyFunc(func(token string, w http.ResponseWriter, r *http.Request) {})
+	controller := NewProxyController(mockECR, testProxy, mock.NewMockOwnerManager(ctrl), mock.NewMockRepositoryManager(ctrl))
+
+	admin := auth.NewPrincipal("admin", auth.AuthenticatorLDAP)
+	admin.Admin = true
+
+	user := auth.NewPrincipal("user", auth.AuthenticatorLDAP)
+
+	// registry tokens are only allowed on the catalog by the RegistryAuthDecorator if they have catalog access
+	registryToken := auth.NewPrincipal("user", auth.AuthenticatorRegistryToken)
+
+	cases := []struct {
+		Name         string
+		Path         string
+		Principal    *auth.Principal
+		ExpectedCode int
+	}{
+		{"base endpoint", "/v2/", user, 200},
+		{"base endpoint anonymous", "/v2/", auth.NewAnonymousPrincipal(), 200},
+		{"catalog admin", "/v2/_catalog", admin, 200},
+		{"catalog registry token", "/v2/_catalog", registryToken, 200},
+		{"catalog user", "/v2/_catalog", user, 403},
+		{"catalog anonymous", "/v2/_catalog", auth.NewAnonymousPrincipal(), 403},
+		{"other path", "/v2/owner", admin, 403},
+		{"nested repository", "/v2/owner/name/other/manifests/latest", admin, 403},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctx := generateContext(t, nil, nil)
+			ctx.Request.Method = "GET"
+			ctx.Request.URL = &url.URL{Path: c.Path}
+			ctx.Meta = map[string]interface{}{principalKey: c.Principal}
+
+			resp, err := controller.DoProxy(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.ExpectedCode == 200 {
+				if _, ok := resp.(*fireball.HTTPError); ok {
+					t.Fatal("request was denied")
+				}
+
+				return
+			}
+
+			assertResponseCode(t, resp, c.ExpectedCode)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zpatrick/fireball"
+)
+
+// registryErrors follows the error format of the docker registry api
+// see: https://docs.docker.com/registry/spec/api/#errors
+type registryErrors struct {
+	Errors []registryError `json:"errors"`
+}
+
+type registryError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+func newRegistryError(status int, code, message string, detail interface{}) (*fireball.HTTPError, error) {
+	body := registryErrors{
+		Errors: []registryError{
+			{
+				Code:    code,
+				Message: message,
+				Detail:  detail,
+			},
+		},
+	}
+
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// copy the headers so callers can add to them, e.g. 'WWW-Authenticate'
+	headers := map[string]string{}
+	for k, v := range fireball.JSONHeaders {
+		headers[k] = v
+	}
+
+	response := &fireball.HTTPError{
+		HTTPResponse: fireball.NewResponse(status, bytes, headers),
+		Err:          fmt.Errorf("%s: %s", code, message),
+	}
+
+	return response, nil
+}
+
+func newDeniedError(repository string) (*fireball.HTTPError, error) {
+	detail := map[string]string{"repository": repository}
+	return newRegistryError(403, "DENIED", "requested access to the resource is denied", detail)
+}
+
+// newUnscopedDeniedError is returned to registry api requests which are not scoped to a repository
+// and which cannot be allowed, e.g. requests to the catalog api by users who cannot read every repository
+func newUnscopedDeniedError() (*fireball.HTTPError, error) {
+	return newRegistryError(403, "DENIED", "requested access to the resource is denied", nil)
+}
+
+// isRegistryBase returns true for the base endpoint of the registry api, which clients use to check the api version
+func isRegistryBase(path string) bool {
+	return path == "/v2" || path == "/v2/"
+}
+
+// isRegistryCatalog returns true for the catalog endpoint of the registry api, which lists every repository
+func isRegistryCatalog(path string) bool {
+	return path == "/v2/_catalog"
+}
+
+// parseRegistryRepository returns the '<owner>/<name>' repository from a registry api path
+// such as '/v2/<owner>/<name>/manifests/<reference>'.
+// The path is parsed by the routes of the registry api rather than by searching for their sections,
+// since repository names may themselves contain 'manifests', 'blobs', or 'tags',
+// e.g. '/v2/owner/tags/manifests/latest' is the 'owner/tags' repository.
+// Paths which are not scoped to a repository, such as '/v2/', and paths which do not
+// match a route of a '<owner>/<name>' repository return false.
+func parseRegistryRepository(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/v2/") {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/v2/"), "/")
+	if len(parts) < 4 || !isRepositoryComponent(parts[0]) || !isRepositoryComponent(parts[1]) {
+		return "", false
+	}
+
+	if !isRegistryRoute(parts[2:]) {
+		return "", false
+	}
+
+	return parts[0] + "/" + parts[1], true
+}
+
+// isRegistryRoute returns true if the parts of a path following the repository match a route of the registry api:
+// 'tags/list', 'manifests/<reference>', 'blobs/<digest>', 'blobs/uploads/', or 'blobs/uploads/<uuid>'
+func isRegistryRoute(parts []string) bool {
+	switch {
+	case len(parts) == 2 && parts[0] == "tags":
+		return parts[1] == "list"
+	case len(parts) == 2 && parts[0] == "manifests":
+		return parts[1] != ""
+	case len(parts) == 2 && parts[0] == "blobs":
+		return parts[1] != ""
+	case len(parts) == 3 && parts[0] == "blobs":
+		return parts[1] == "uploads"
+	default:
+		return false
+	}
+}
+
+func isRepositoryComponent(component string) bool {
+	return component != "" && component != "." && component != ".."
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/zpatrick/fireball"
+)
+
+// RegistryAuthDecorator authenticates registry api requests with the registry tokens issued by the RegistryTokenController.
+// Requests without a valid token receive a bearer challenge pointing clients at realm, the url of the token endpoint.
+// If realm is empty, the token endpoint of the requested host is used.
+// Requests which use basic auth, or which have a tls client certificate and no bearer token,
+// are handled by the basic decorator, e.g. the AuthDecorator.
+func RegistryAuthDecorator(tokens *auth.RegistryTokenService, realm string, basic fireball.Decorator) fireball.Decorator {
+	return func(handler fireball.Handler) fireball.Handler {
+		basicHandler := basic(handler)
+		return func(c *fireball.Context) (fireball.Response, error) {
+			if _, _, ok := c.Request.BasicAuth(); ok {
+				return basicHandler(c)
+			}
+
+			repository, hasRepository := parseRegistryRepository(c.Request.URL.Path)
+			action := requiredScope(c.Request)
+
+			challenge := func(message, errorCode string) (fireball.Response, error) {
+				header := fmt.Sprintf("Bearer realm=%q,service=%q", tokenRealm(c.Request, realm), tokens.Service())
+				if hasRepository {
+					scope := auth.ResourceAccess{Type: "repository", Name: repository, Actions: []string{action}}
+					header += fmt.Sprintf(",scope=%q", scope.String())
+				} else if isRegistryCatalog(c.Request.URL.Path) {
+					header += fmt.Sprintf(",scope=%q", auth.CatalogAccess.String())
+				}
+
+				if errorCode != "" {
+					header += fmt.Sprintf(",error=%q", errorCode)
+				}
+
+				resp, err := newRegistryError(401, "UNAUTHORIZED", message, nil)
+				if err != nil {
+					return nil, err
+				}
+
+				resp.Headers["WWW-Authenticate"] = header
+				return resp, nil
+			}
+
+			token, ok := bearerToken(c.Request)
+			if !ok && hasClientCertificate(c.Request) {
+				return basicHandler(c)
+			}
+
+			if !ok {
+				return challenge("authentication required", "")
+			}
+
+			claims, err := tokens.ValidateToken(token)
+			if err != nil {
+				log.Printf("[DEBUG] Request %s %s contained an invalid registry token: %v", c.Request.Method, c.Request.URL.String(), err)
+				return challenge("authentication required", "invalid_token")
+			}
+
+			// the registry token has already been authorized against the user's permissions
+			principal := auth.NewPrincipal(claims.Subject, auth.AuthenticatorRegistryToken)
+			principal.Permissions = auth.Permissions{Scopes: []string{auth.ScopePull}}
+			switch path := c.Request.URL.Path; {
+			case hasRepository:
+				if !claims.Allows(repository, action) {
+					log.Printf("[DEBUG] Registry token for user '%s' does not allow '%s' on '%s'", claims.Subject, action, repository)
+					return challenge("insufficient scope", "insufficient_scope")
+				}
+
+				principal.Permissions = auth.Permissions{
+					Scopes:       []string{action},
+					Repositories: []string{repository},
+				}
+			case isRegistryBase(path):
+				// clients check the api version before they request access to repositories
+			case isRegistryCatalog(path) && claims.AllowsCatalog():
+				// catalog access is only granted to admins
+			default:
+				log.Printf("[DEBUG] Registry token for user '%s' does not allow %s %s", claims.Subject, c.Request.Method, path)
+				return newUnscopedDeniedError()
+			}
+
+			return authorize(c, handler, principal)
+		}
+	}
+}
+
+func tokenRealm(r *http.Request, realm string) string {
+	if realm != "" {
+		return realm
+	}
+
+	scheme := "https"
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	} else if r.TLS == nil {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s/auth/token", scheme, r.Host)
+}
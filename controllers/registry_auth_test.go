@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
+)
+
+func TestRegistryAuthDecorator(t *testing.T) {
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	access := []auth.ResourceAccess{
+		{Type: "repository", Name: "owner/name", Actions: []string{"pull"}},
+	}
+
+	token, _, err := tokens.IssueToken("user", access)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalogToken, _, err := tokens.IssueToken("admin", []auth.ResourceAccess{auth.CatalogAccess})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// e.g. the tokens issued to anonymous users
+	emptyToken, _, err := tokens.IssueToken("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name              string
+		Method            string
+		Path              string
+		Token             string
+		ExpectedCode      int
+		ExpectedChallenge string
+	}{
+		{
+			Name:              "no token",
+			Method:            "GET",
+			Path:              "/v2/",
+			ExpectedCode:      401,
+			ExpectedChallenge: `Bearer realm="https://d.ims.io/auth/token",service="d.ims.io"`,
+		},
+		{
+			Name:              "no token for repository",
+			Method:            "GET",
+			Path:              "/v2/owner/name/manifests/latest",
+			ExpectedCode:      401,
+			ExpectedChallenge: `Bearer realm="https://d.ims.io/auth/token",service="d.ims.io",scope="repository:owner/name:pull"`,
+		},
+		{
+			Name:              "invalid token",
+			Method:            "GET",
+			Path:              "/v2/",
+			Token:             "invalid",
+			ExpectedCode:      401,
+			ExpectedChallenge: `Bearer realm="https://d.ims.io/auth/token",service="d.ims.io",error="invalid_token"`,
+		},
+		{
+			Name:         "base endpoint",
+			Method:       "GET",
+			Path:         "/v2/",
+			Token:        token,
+			ExpectedCode: 200,
+		},
+		{
+			Name:         "allowed action",
+			Method:       "HEAD",
+			Path:         "/v2/owner/name/blobs/digest",
+			Token:        token,
+			ExpectedCode: 200,
+		},
+		{
+			Name:              "insufficient scope",
+			Method:            "PUT",
+			Path:              "/v2/owner/name/manifests/latest",
+			Token:             token,
+			ExpectedCode:      401,
+			ExpectedChallenge: `Bearer realm="https://d.ims.io/auth/token",service="d.ims.io",scope="repository:owner/name:push",error="insufficient_scope"`,
+		},
+		{
+			Name:              "other repository",
+			Method:            "GET",
+			Path:              "/v2/owner/other/manifests/latest",
+			Token:             token,
+			ExpectedCode:      401,
+			ExpectedChallenge: `Bearer realm="https://d.ims.io/auth/token",service="d.ims.io",scope="repository:owner/other:pull",error="insufficient_scope"`,
+		},
+		{
+			Name:         "base endpoint without access",
+			Method:       "GET",
+			Path:         "/v2/",
+			Token:        emptyToken,
+			ExpectedCode: 200,
+		},
+		{
+			Name:              "no token for catalog",
+			Method:            "GET",
+			Path:              "/v2/_catalog",
+			ExpectedCode:      401,
+			ExpectedChallenge: `Bearer realm="https://d.ims.io/auth/token",service="d.ims.io",scope="registry:catalog:*"`,
+		},
+		{
+			Name:         "catalog",
+			Method:       "GET",
+			Path:         "/v2/_catalog",
+			Token:        catalogToken,
+			ExpectedCode: 200,
+		},
+		{
+			Name:         "catalog without access",
+			Method:       "GET",
+			Path:         "/v2/_catalog",
+			Token:        emptyToken,
+			ExpectedCode: 403,
+		},
+		{
+			Name:         "catalog with repository access",
+			Method:       "GET",
+			Path:         "/v2/_catalog",
+			Token:        token,
+			ExpectedCode: 403,
+		},
+		{
+			Name:         "other path without repository",
+			Method:       "GET",
+			Path:         "/v2/owner",
+			Token:        catalogToken,
+			ExpectedCode: 403,
+		},
+	}
+
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		return fireball.NewResponse(200, nil, nil), nil
+	}
+
+	basic := func(fireball.Handler) fireball.Handler {
+		return func(c *fireball.Context) (fireball.Response, error) {
+			t.Fatal("basic decorator was called")
+			return nil, nil
+		}
+	}
+
+	handler = RegistryAuthDecorator(tokens, "https://d.ims.io/auth/token", basic)(handler)
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctx := generateContext(t, nil, nil)
+			ctx.Request.Method = c.Method
+			ctx.Request.URL.Path = c.Path
+			if c.Token != "" {
+				ctx.Request.Header.Set("Authorization", "Bearer "+c.Token)
+			}
+
+			resp, err := handler(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			resp.Write(recorder, nil)
+
+			assert.Equal(t, c.ExpectedCode, recorder.Code)
+			assert.Equal(t, c.ExpectedChallenge, recorder.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestRegistryAuthDecoratorUsesBasicAuth(t *testing.T) {
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		t.Fatal("handler was called")
+		return nil, nil
+	}
+
+	var basicCalled bool
+	basic := func(fireball.Handler) fireball.Handler {
+		return func(c *fireball.Context) (fireball.Response, error) {
+			basicCalled = true
+			return fireball.NewResponse(200, nil, nil), nil
+		}
+	}
+
+	c := newContextWithBasicAuth(t, "user", "pass")
+	c.Request.URL.Path = "/v2/"
+
+	if _, err := RegistryAuthDecorator(tokens, "", basic)(handler)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, basicCalled)
+}
+
+func TestRegistryAuthDecoratorUsesClientCertificate(t *testing.T) {
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	handler := func(c *fireball.Context) (fireball.Response, error) {
+		t.Fatal("handler was called")
+		return nil, nil
+	}
+
+	var basicCalled bool
+	basic := func(fireball.Handler) fireball.Handler {
+		return func(c *fireball.Context) (fireball.Response, error) {
+			basicCalled = true
+			return fireball.NewResponse(200, nil, nil), nil
+		}
+	}
+
+	c := generateContext(t, nil, nil)
+	c.Request.URL.Path = "/v2/"
+	c.Request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+
+	if _, err := RegistryAuthDecorator(tokens, "", basic)(handler)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, basicCalled)
+}
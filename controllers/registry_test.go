@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegistryRepository(t *testing.T) {
+	cases := map[string]string{
+		"/v2/owner/name/manifests/latest":      "owner/name",
+		"/v2/owner/name/blobs/sha256:abc":      "owner/name",
+		"/v2/owner/name/blobs/uploads/":        "owner/name",
+		"/v2/owner/name/blobs/uploads/some-id": "owner/name",
+		"/v2/owner/name/tags/list":             "owner/name",
+		"/v2/":                                 "",
+		"/v2/_catalog":                         "",
+		"/repository/owner/name":               "",
+		// repository names which contain the sections of registry api routes
+		"/v2/team/tags/manifests/latest":              "team/tags",
+		"/v2/team/manifests/tags/list":                "team/manifests",
+		"/v2/blobs/tags/blobs/sha256:abc":             "blobs/tags",
+		"/v2/team/blobs/blobs/uploads/":               "team/blobs",
+		"/v2/manifests/blobs/manifests/blobs":         "manifests/blobs",
+		"/v2/team/tags/manifests/tags/list":           "",
+		"/v2/team/private/manifests/public/tags/list": "",
+		// paths which are not routes of an '<owner>/<name>' repository
+		"/v2/owner/manifests/latest":            "",
+		"/v2/owner/name/other/manifests/latest": "",
+		"/v2/owner/name/manifests/":             "",
+		"/v2/owner/name/tags/other":             "",
+		"/v2/owner/name/blobs/uploads/id/more":  "",
+		"/v2/owner//manifests/latest":           "",
+		"/v2/owner/../manifests/latest":         "",
+	}
+
+	for path, expected := range cases {
+		repository, ok := parseRegistryRepository(path)
+		assert.Equal(t, expected, repository, path)
+		assert.Equal(t, expected != "", ok, path)
+	}
+}
+
+func TestDeniedErrorFormat(t *testing.T) {
+	resp, err := newDeniedError("owner/name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body registryErrors
+	recorder := unmarshalBody(t, resp, &body)
+
+	assert.Equal(t, 403, recorder.Code)
+	assert.Len(t, body.Errors, 1)
+	assert.Equal(t, "DENIED", body.Errors[0].Code)
+}
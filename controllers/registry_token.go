@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/zpatrick/fireball"
+)
+
+// RegistryTokenController implements the token endpoint of the docker registry token authentication flow,
+// see: https://docs.docker.com/registry/spec/auth/token/
+type RegistryTokenController struct {
+	tokens       *auth.RegistryTokenService
+	owners       auth.OwnerManager
+	repositories auth.RepositoryManager
+}
+
+func NewRegistryTokenController(tokens *auth.RegistryTokenService, owners auth.OwnerManager, repositories auth.RepositoryManager) *RegistryTokenController {
+	return &RegistryTokenController{
+		tokens:       tokens,
+		owners:       owners,
+		repositories: repositories,
+	}
+}
+
+func (r *RegistryTokenController) Routes() []*fireball.Route {
+	return []*fireball.Route{
+		{
+			Path: "/auth/token",
+			Handlers: fireball.Handlers{
+				"GET": r.GetToken,
+			},
+		},
+	}
+}
+
+// GetToken issues a registry token for the authenticated user.
+// The token grants the requested pull and push actions on repositories that the user's permissions allow;
+// other actions are dropped.
+// The pull action is only granted to users who can read the repository,
+// and the push action is only granted to maintainers and developers of the repository's owner.
+// Access to the catalog is only granted to admins.
+func (r *RegistryTokenController) GetToken(c *fireball.Context) (fireball.Response, error) {
+	query := c.Request.URL.Query()
+	if service := query.Get("service"); service != "" && service != r.tokens.Service() {
+		return fireball.NewJSONError(400, fmt.Errorf("Unknown service '%s'", service))
+	}
+
+	permissions := getPermissions(c)
+	access := []auth.ResourceAccess{}
+	for _, param := range query["scope"] {
+		// clients may send multiple scopes in a single parameter
+		for _, scope := range strings.Fields(param) {
+			requested, err := auth.ParseRegistryScope(scope)
+			if err != nil {
+				return fireball.NewJSONError(400, err)
+			}
+
+			// the catalog lists every repository, including private repositories, so only admins can access it
+			if requested.Type == auth.CatalogAccess.Type && requested.Name == auth.CatalogAccess.Name {
+				if isAdmin(c) {
+					access = append(access, auth.CatalogAccess)
+				}
+
+				continue
+			}
+
+			if requested.Type != "repository" {
+				continue
+			}
+
+			granted := auth.ResourceAccess{
+				Type:    requested.Type,
+				Name:    requested.Name,
+				Actions: []string{},
+			}
+
+			for _, action := range requested.Actions {
+				// the registry only understands pull and push; other actions, e.g. '*', would be granted by scopes such as 'admin'
+				if action != auth.ScopePull && action != auth.ScopePush {
+					continue
+				}
+
+				if !permissions.HasScope(action) || !permissions.CanAccessRepository(requested.Name) {
+					continue
+				}
+
+				if action == auth.ScopePull {
+					canRead, err := canReadRepository(c, r.repositories, r.owners, requested.Name)
+					if err != nil {
+						return nil, err
+					}
+
+					if !canRead {
+						continue
+					}
+				}
+
+				if action == auth.ScopePush {
+					owner := repositoryOwner(requested.Name)
+					canWrite, err := canWriteOwner(c, r.owners, owner)
+					if err != nil {
+						return nil, err
+					}
+
+					if !canWrite {
+						continue
+					}
+				}
+
+				granted.Actions = append(granted.Actions, action)
+			}
+
+			access = append(access, granted)
+		}
+	}
+
+	user := getUser(c)
+	token, issuedAt, err := r.tokens.IssueToken(user, access)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DEBUG] Issued registry token to user '%s' with access %v", user, access)
+	resp := models.RegistryTokenResponse{
+		Token:       token,
+		AccessToken: token,
+		ExpiresIn:   int64(r.tokens.Expiry().Seconds()),
+		IssuedAt:    issuedAt.UTC(),
+	}
+
+	return fireball.NewJSONResponse(200, resp)
+}
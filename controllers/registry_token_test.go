@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRegistryToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	controller := NewRegistryTokenController(tokens, mockOwnerManager, mockRepositoryManager)
+
+	private := auth.NewRepository("other/private")
+	private.Visibility = auth.VisibilityPrivate
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("owner/name").
+		Return(nil, auth.ErrRepositoryNotFound)
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("other/name").
+		Return(nil, auth.ErrRepositoryNotFound)
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("other/private").
+		Return(private, nil)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("owner").
+		Return(&auth.Owner{Name: "owner", Members: map[string]string{"user": auth.RoleDeveloper}}, nil)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("other").
+		Return(nil, auth.ErrOwnerNotFound).
+		Times(2)
+
+	c := newContextWithBasicAuth(t, "user", "pass")
+	c.Request.URL.RawQuery = url.Values{
+		"service": {"d.ims.io"},
+		"scope": {
+			"repository:owner/name:pull,push repository:other/name:pull,push",
+			"repository:restricted/name:pull registry:catalog:*",
+			"repository:other/private:pull",
+		},
+	}.Encode()
+
+	c.Meta = map[string]interface{}{
+		principalKey: &auth.Principal{
+			Permissions: auth.Permissions{
+				Scopes:       []string{auth.ScopePush},
+				Repositories: []string{"owner/*", "other/*"},
+			},
+			Username:      "user",
+			Authenticator: auth.AuthenticatorToken,
+		},
+	}
+
+	resp, err := controller.GetToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.RegistryTokenResponse
+	recorder := unmarshalBody(t, resp, &response)
+	assert.Equal(t, 200, recorder.Code)
+
+	claims, err := tokens.ValidateToken(response.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the actions allowed by the user's permissions, owner memberships, and read acls are granted
+	expected := []auth.ResourceAccess{
+		{Type: "repository", Name: "owner/name", Actions: []string{"pull", "push"}},
+		{Type: "repository", Name: "other/name", Actions: []string{"pull"}},
+		{Type: "repository", Name: "restricted/name", Actions: []string{}},
+		{Type: "repository", Name: "other/private", Actions: []string{}},
+	}
+
+	assert.Equal(t, "user", claims.Subject)
+	assert.Equal(t, expected, claims.Access)
+	assert.Equal(t, response.Token, response.AccessToken)
+	assert.Equal(t, int64(60), response.ExpiresIn)
+}
+
+func TestGetRegistryTokenUnknownService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	controller := NewRegistryTokenController(tokens, mock.NewMockOwnerManager(ctrl), mock.NewMockRepositoryManager(ctrl))
+
+	c := newContextWithBasicAuth(t, "user", "pass")
+	c.Request.URL.RawQuery = "service=other"
+
+	resp, err := controller.GetToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 400)
+}
+
+func TestGetRegistryTokenAnonymous(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	controller := NewRegistryTokenController(tokens, mock.NewMockOwnerManager(ctrl), mockRepositoryManager)
+
+	public := auth.NewRepository("carbon/base")
+	public.Visibility = auth.VisibilityPublic
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("carbon/base").
+		Return(public, nil)
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("carbon/api").
+		Return(nil, auth.ErrRepositoryNotFound)
+
+	c := generateContext(t, nil, nil)
+	c.Request.URL.RawQuery = url.Values{
+		"scope": {"repository:carbon/base:pull,push repository:carbon/api:pull"},
+	}.Encode()
+
+	c.Meta = map[string]interface{}{principalKey: auth.NewAnonymousPrincipal()}
+
+	resp, err := controller.GetToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.RegistryTokenResponse
+	unmarshalBody(t, resp, &response)
+
+	claims, err := tokens.ValidateToken(response.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// anonymous users can only pull from public repositories
+	expected := []auth.ResourceAccess{
+		{Type: "repository", Name: "carbon/base", Actions: []string{"pull"}},
+		{Type: "repository", Name: "carbon/api", Actions: []string{}},
+	}
+
+	assert.Equal(t, expected, claims.Access)
+}
+
+func TestGetRegistryTokenOnlyGrantsPullAndPush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	controller := NewRegistryTokenController(tokens, mockOwnerManager, mockRepositoryManager)
+
+	mockRepositoryManager.EXPECT().
+		GetRepository("owner/name").
+		Return(nil, auth.ErrRepositoryNotFound)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("owner").
+		Return(&auth.Owner{Name: "owner", Members: map[string]string{"admin": auth.RoleMaintainer}}, nil).
+		AnyTimes()
+
+	admin := auth.NewPrincipal("admin", auth.AuthenticatorLDAP)
+	admin.Admin = true
+
+	c := generateContext(t, nil, nil)
+	c.Request.URL.RawQuery = url.Values{"scope": {"repository:owner/name:pull,push,delete,*,admin"}}.Encode()
+	c.Meta = map[string]interface{}{principalKey: admin}
+
+	resp, err := controller.GetToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.RegistryTokenResponse
+	unmarshalBody(t, resp, &response)
+
+	claims, err := tokens.ValidateToken(response.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []auth.ResourceAccess{
+		{Type: "repository", Name: "owner/name", Actions: []string{"pull", "push"}},
+	}
+
+	assert.Equal(t, expected, claims.Access)
+}
+
+func TestGetRegistryTokenCatalog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := auth.NewRegistryTokenService("d.ims.io", "secret", time.Minute)
+	controller := NewRegistryTokenController(tokens, mock.NewMockOwnerManager(ctrl), mock.NewMockRepositoryManager(ctrl))
+
+	admin := auth.NewPrincipal("admin", auth.AuthenticatorLDAP)
+	admin.Admin = true
+
+	// the catalog lists private repositories, so only admins can access it
+	cases := map[string]*auth.Principal{
+		"admin":     admin,
+		"user":      auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+		"anonymous": auth.NewAnonymousPrincipal(),
+	}
+
+	for name, principal := range cases {
+		c := generateContext(t, nil, nil)
+		c.Request.URL.RawQuery = url.Values{"scope": {"registry:catalog:*"}}.Encode()
+		c.Meta = map[string]interface{}{principalKey: principal}
+
+		resp, err := controller.GetToken(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var response models.RegistryTokenResponse
+		unmarshalBody(t, resp, &response)
+
+		claims, err := tokens.ValidateToken(response.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, want := claims.AllowsCatalog(), principal.IsAdmin(); v != want {
+			t.Errorf("case %s: catalog access was %v, expected %v", name, v, want)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
 	"github.com/quintilesims/d.ims.io/auth"
@@ -15,14 +17,18 @@ import (
 )
 
 type RepositoryController struct {
-	ecr     ecriface.ECRAPI
-	account auth.AccountManager
+	ecr          ecriface.ECRAPI
+	account      auth.AccountManager
+	owners       auth.OwnerManager
+	repositories auth.RepositoryManager
 }
 
-func NewRepositoryController(e ecriface.ECRAPI, a auth.AccountManager) *RepositoryController {
+func NewRepositoryController(e ecriface.ECRAPI, a auth.AccountManager, o auth.OwnerManager, r auth.RepositoryManager) *RepositoryController {
 	return &RepositoryController{
-		ecr:     e,
-		account: a,
+		ecr:          e,
+		account:      a,
+		owners:       o,
+		repositories: r,
 	}
 }
 
@@ -48,6 +54,12 @@ func (r *RepositoryController) Routes() []*fireball.Route {
 				"DELETE": r.DeleteRepository,
 			},
 		},
+		{
+			Path: "/repository/:owner/:name/access",
+			Handlers: fireball.Handlers{
+				"PUT": r.PutRepositoryAccess,
+			},
+		},
 		{
 			Path: "/repository/:owner/:name/image",
 			Handlers: fireball.Handlers{
@@ -77,13 +89,61 @@ func (r *RepositoryController) CreateRepository(c *fireball.Context) (fireball.R
 	}
 
 	repo := fmt.Sprintf("%s/%s", owner, req.Name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	canWrite, err := canWriteOwner(c, r.owners, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canWrite {
+		return newNotWriterError(c, owner)
+	}
+
 	input := &ecr.CreateRepositoryInput{}
 	input.SetRepositoryName(repo)
 	if err := input.Validate(); err != nil {
 		return nil, fireball.NewError(400, err, nil)
 	}
 
+	// the metadata is stored first so private repositories are never readable by everyone;
+	// it is only stored if none exists, so the acl of an existing repository is never replaced
+	hasMetadata := req.Visibility != "" || len(req.ReadUsers) > 0 || len(req.ReadGroups) > 0
+	if hasMetadata {
+		repository := auth.NewRepository(repo)
+		if req.Visibility != "" {
+			repository.Visibility = req.Visibility
+		}
+
+		if err := auth.ValidateVisibility(repository.Visibility); err != nil {
+			return fireball.NewJSONError(400, err)
+		}
+
+		repository.ReadUsers = append(repository.ReadUsers, req.ReadUsers...)
+		repository.ReadGroups = append(repository.ReadGroups, req.ReadGroups...)
+		if err := r.repositories.CreateRepository(repository); err != nil {
+			if err == auth.ErrRepositoryExists {
+				return newRepositoryExistsError(repo)
+			}
+
+			return nil, err
+		}
+	}
+
 	if _, err := r.ecr.CreateRepository(input); err != nil {
+		// the metadata was created by this request, so it belongs to no repository
+		if hasMetadata {
+			if err := r.repositories.DeleteRepository(repo); err != nil {
+				log.Printf("[ERROR] Failed to delete the metadata of repository '%s': %v", repo, err)
+			}
+		}
+
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecr.ErrCodeRepositoryAlreadyExistsException {
+			return newRepositoryExistsError(repo)
+		}
+
 		return nil, err
 	}
 
@@ -108,6 +168,19 @@ func (r *RepositoryController) DeleteRepository(c *fireball.Context) (fireball.R
 	owner := c.PathVariables["owner"]
 	name := c.PathVariables["name"]
 	repo := fmt.Sprintf("%s/%s", owner, name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	canWrite, err := canWriteOwner(c, r.owners, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// admins can delete the repositories and images of every owner
+	if !canWrite && !isAdmin(c) {
+		return newNotWriterError(c, owner)
+	}
 
 	input := &ecr.DeleteRepositoryInput{}
 	input.SetRepositoryName(repo)
@@ -121,6 +194,10 @@ func (r *RepositoryController) DeleteRepository(c *fireball.Context) (fireball.R
 		return nil, err
 	}
 
+	if err := r.repositories.DeleteRepository(repo); err != nil {
+		return nil, err
+	}
+
 	return fireball.NewResponse(200, []byte("Successfully deleted repository"), nil), nil
 }
 
@@ -128,6 +205,23 @@ func (r *RepositoryController) GetRepository(c *fireball.Context) (fireball.Resp
 	owner := c.PathVariables["owner"]
 	name := c.PathVariables["name"]
 	repo := fmt.Sprintf("%s/%s", owner, name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	repository, err := getRepository(r.repositories, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	canRead, err := isReadable(c, r.owners, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canRead {
+		return newDeniedError(repo)
+	}
 
 	input := &ecr.DescribeRepositoriesInput{}
 	input.SetRepositoryNames([]*string{aws.String(repo)})
@@ -142,22 +236,93 @@ func (r *RepositoryController) GetRepository(c *fireball.Context) (fireball.Resp
 	}
 
 	resp := models.Repository{
-		Owner:     owner,
-		Name:      name,
-		CreatedAt: aws.TimeValue(output.Repositories[0].CreatedAt),
+		Owner:      owner,
+		Name:       name,
+		CreatedAt:  aws.TimeValue(output.Repositories[0].CreatedAt),
+		Visibility: repository.Visibility,
+		ReadUsers:  repository.ReadUsers,
+		ReadGroups: repository.ReadGroups,
 	}
 
 	return fireball.NewJSONResponse(200, resp)
 }
 
+// PutRepositoryAccess replaces the visibility and read acl of a repository
+func (r *RepositoryController) PutRepositoryAccess(c *fireball.Context) (fireball.Response, error) {
+	owner := c.PathVariables["owner"]
+	name := c.PathVariables["name"]
+	repo := fmt.Sprintf("%s/%s", owner, name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	var req models.PutRepositoryAccessRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := auth.ValidateVisibility(req.Visibility); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	canWrite, err := canWriteOwner(c, r.owners, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canWrite {
+		return newNotWriterError(c, owner)
+	}
+
+	// make sure the repository exists before storing its metadata
+	input := &ecr.DescribeRepositoriesInput{}
+	input.SetRepositoryNames([]*string{aws.String(repo)})
+
+	if err := input.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if _, err := r.ecr.DescribeRepositories(input); err != nil {
+		return nil, err
+	}
+
+	repository := auth.NewRepository(repo)
+	repository.Visibility = req.Visibility
+	repository.ReadUsers = append(repository.ReadUsers, req.ReadUsers...)
+	repository.ReadGroups = append(repository.ReadGroups, req.ReadGroups...)
+	if err := r.repositories.PutRepository(repository); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] User '%s' set the visibility of repository '%s' to '%s'", getUser(c), repo, req.Visibility)
+	return fireball.NewResponse(200, []byte("Successfully updated repository access"), nil), nil
+}
+
 func (r *RepositoryController) ListRepositories(c *fireball.Context) (fireball.Response, error) {
 	repositories, err := listRepositories(r.ecr)
 	if err != nil {
 		return nil, err
 	}
 
+	permissions := getPermissions(c)
+	accessible := []string{}
+	for _, repository := range repositories {
+		if permissions.CanAccessRepository(repository) {
+			accessible = append(accessible, repository)
+		}
+	}
+
+	readable, err := filterReadableRepositories(c, r.repositories, r.owners, accessible)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := models.ListRepositoriesResponse{
-		Repositories: repositories,
+		Repositories: readable,
 	}
 
 	return fireball.NewJSONResponse(200, resp)
@@ -165,15 +330,16 @@ func (r *RepositoryController) ListRepositories(c *fireball.Context) (fireball.R
 
 func (r *RepositoryController) ListOwnerRepositories(c *fireball.Context) (fireball.Response, error) {
 	owner := c.PathVariables["owner"]
+	permissions := getPermissions(c)
+	prefix := fmt.Sprintf("%s/", owner)
 
-	repositories := []string{}
+	accessible := []string{}
 	fn := func(output *ecr.DescribeRepositoriesOutput, lastPage bool) bool {
 		for _, repository := range output.Repositories {
-			prefix := fmt.Sprintf("%s/", owner)
 			repositoryName := aws.StringValue(repository.RepositoryName)
 
-			if strings.HasPrefix(repositoryName, prefix) {
-				repositories = append(repositories, strings.TrimPrefix(repositoryName, prefix))
+			if strings.HasPrefix(repositoryName, prefix) && permissions.CanAccessRepository(repositoryName) {
+				accessible = append(accessible, repositoryName)
 			}
 		}
 
@@ -184,6 +350,16 @@ func (r *RepositoryController) ListOwnerRepositories(c *fireball.Context) (fireb
 		return nil, err
 	}
 
+	readable, err := filterReadableRepositories(c, r.repositories, r.owners, accessible)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]string, len(readable))
+	for i, repositoryName := range readable {
+		repositories[i] = strings.TrimPrefix(repositoryName, prefix)
+	}
+
 	resp := models.ListRepositoriesResponse{
 		Repositories: repositories,
 	}
@@ -195,6 +371,18 @@ func (r *RepositoryController) ListRepositoryImages(c *fireball.Context) (fireba
 	owner := c.PathVariables["owner"]
 	name := c.PathVariables["name"]
 	repo := fmt.Sprintf("%s/%s", owner, name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	canRead, err := canReadRepository(c, r.repositories, r.owners, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canRead {
+		return newDeniedError(repo)
+	}
 
 	filter := &ecr.ListImagesFilter{}
 	filter.SetTagStatus("TAGGED")
@@ -232,6 +420,18 @@ func (r *RepositoryController) GetRepositoryImage(c *fireball.Context) (fireball
 	name := c.PathVariables["name"]
 	tag := c.PathVariables["tag"]
 	repo := fmt.Sprintf("%s/%s", owner, name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	canRead, err := canReadRepository(c, r.repositories, r.owners, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canRead {
+		return newDeniedError(repo)
+	}
 
 	imageID := &ecr.ImageIdentifier{}
 	imageID.SetImageTag(tag)
@@ -266,6 +466,19 @@ func (r *RepositoryController) DeleteRepositoryImage(c *fireball.Context) (fireb
 	name := c.PathVariables["name"]
 	tag := c.PathVariables["tag"]
 	repo := fmt.Sprintf("%s/%s", owner, name)
+	if !getPermissions(c).CanAccessRepository(repo) {
+		return newDeniedError(repo)
+	}
+
+	canWrite, err := canWriteOwner(c, r.owners, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// admins can delete the repositories and images of every owner
+	if !canWrite && !isAdmin(c) {
+		return newNotWriterError(c, owner)
+	}
 
 	imageID := &ecr.ImageIdentifier{}
 	imageID.SetImageTag(tag)
@@ -284,9 +497,13 @@ func (r *RepositoryController) DeleteRepositoryImage(c *fireball.Context) (fireb
 	}
 
 	if failures := output.Failures; len(failures) > 0 {
-		return nil, fmt.Errorf(failures[0].String())
+		return nil, fmt.Errorf("%s", failures[0].String())
 	}
 
 	message := fmt.Sprintf("Image '%s:%s' successfully deleted.", repo, tag)
 	return fireball.NewJSONResponse(200, message)
 }
+
+func newRepositoryExistsError(name string) (*fireball.HTTPError, error) {
+	return fireball.NewJSONError(409, fmt.Errorf("Repository '%s' already exists", name))
+}
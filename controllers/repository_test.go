@@ -5,10 +5,13 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/mock"
 	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateRepository(t *testing.T) {
@@ -17,7 +20,13 @@ func TestCreateRepository(t *testing.T) {
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("user").
+		Return(&auth.Owner{Name: "user", Members: map[string]string{"user": auth.RoleDeveloper}}, nil)
 
 	validateCreateRepositoryInput := func(input *ecr.CreateRepositoryInput) {
 		if v, want := aws.StringValue(input.RepositoryName), "user/test"; v != want {
@@ -77,18 +86,89 @@ func TestCreateRepository(t *testing.T) {
 		Return(&ecr.SetRepositoryPolicyOutput{}, nil)
 
 	c := generateContext(t, models.CreateRepositoryRequest{Name: "test"}, map[string]string{"owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
 	if _, err := controller.CreateRepository(c); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestCreateRepositoryExists(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Request models.CreateRepositoryRequest
+		// MetadataExists is true if metadata is already stored for the repository
+		MetadataExists bool
+	}{
+		{"ecr", models.CreateRepositoryRequest{Name: "test"}, false},
+		{"ecr with metadata", models.CreateRepositoryRequest{Name: "test", Visibility: auth.VisibilityPrivate}, false},
+		{"metadata", models.CreateRepositoryRequest{Name: "test", Visibility: auth.VisibilityPublic}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECR := mock.NewMockECRAPI(ctrl)
+			mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+			mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+			controller := NewRepositoryController(mockECR, mock.NewMockAccountManager(ctrl), mockOwnerManager, mockRepositoryManager)
+
+			mockOwnerManager.EXPECT().
+				GetOwner("user").
+				Return(&auth.Owner{Name: "user", Members: map[string]string{"user": auth.RoleDeveloper}}, nil)
+
+			switch {
+			case c.MetadataExists:
+				// the acl of the existing repository is not replaced, and ecr is not called
+				mockRepositoryManager.EXPECT().
+					CreateRepository(gomock.Any()).
+					Return(auth.ErrRepositoryExists)
+			case c.Request.Visibility != "":
+				// the metadata created for the request is removed again
+				mockRepositoryManager.EXPECT().
+					CreateRepository(gomock.Any()).
+					Return(nil)
+
+				mockECR.EXPECT().
+					CreateRepository(gomock.Any()).
+					Return(nil, awserr.New(ecr.ErrCodeRepositoryAlreadyExistsException, "", nil))
+
+				mockRepositoryManager.EXPECT().
+					DeleteRepository("user/test").
+					Return(nil)
+			default:
+				mockECR.EXPECT().
+					CreateRepository(gomock.Any()).
+					Return(nil, awserr.New(ecr.ErrCodeRepositoryAlreadyExistsException, "", nil))
+			}
+
+			ctx := generateContext(t, c.Request, map[string]string{"owner": "user"})
+			ctx.Meta = map[string]interface{}{
+				principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+			}
+
+			resp, err := controller.CreateRepository(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, 409)
+		})
+	}
+}
+
 func TestCreateRepositoryFailsWithSlashes(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mock.NewMockOwnerManager(ctrl), mockRepositoryManager)
 
 	c := generateContext(t, models.CreateRepositoryRequest{Name: "slash/test"}, map[string]string{"owner": "user"})
 	_, err := controller.CreateRepository(c)
@@ -103,7 +183,13 @@ func TestDeleteRepository(t *testing.T) {
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("user").
+		Return(&auth.Owner{Name: "user", Members: map[string]string{"user": auth.RoleDeveloper}}, nil)
 
 	validateDeleteRepositoryInput := func(input *ecr.DeleteRepositoryInput) {
 		if v, want := aws.StringValue(input.RepositoryName), "user/test"; v != want {
@@ -120,19 +206,124 @@ func TestDeleteRepository(t *testing.T) {
 		Do(validateDeleteRepositoryInput).
 		Return(&ecr.DeleteRepositoryOutput{}, nil)
 
+	mockRepositoryManager.EXPECT().
+		DeleteRepository("user/test").
+		Return(nil)
+
 	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
 	if _, err := controller.DeleteRepository(c); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestDeleteRepositoryDeniedByRepositoryRestriction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockAccountManager := mock.NewMockAccountManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mock.NewMockOwnerManager(ctrl), mockRepositoryManager)
+
+	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: &auth.Principal{Permissions: auth.Permissions{Repositories: []string{"user/other"}}},
+	}
+
+	resp, err := controller.DeleteRepository(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestDeleteRepositoryDeniedToNonMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockAccountManager := mock.NewMockAccountManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	cases := map[string]error{
+		"not a member":   nil,
+		"not registered": auth.ErrOwnerNotFound,
+	}
+
+	for name, err := range cases {
+		t.Run(name, func(t *testing.T) {
+			mockOwnerManager.EXPECT().
+				GetOwner("user").
+				Return(&auth.Owner{Name: "user", Members: map[string]string{"other": auth.RoleDeveloper}}, err)
+
+			c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user"})
+			c.Meta = map[string]interface{}{
+				principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+			}
+
+			resp, err := controller.DeleteRepository(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, 403)
+		})
+	}
+}
+
+func TestDeleteRepositoryByAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockAccountManager := mock.NewMockAccountManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("user").
+		Return(&auth.Owner{Name: "user", Members: map[string]string{"other": auth.RoleDeveloper}}, nil)
+
+	mockECR.EXPECT().
+		DeleteRepository(gomock.Any()).
+		Return(&ecr.DeleteRepositoryOutput{}, nil)
+
+	mockRepositoryManager.EXPECT().
+		DeleteRepository("user/test").
+		Return(nil)
+
+	principal := auth.NewPrincipal("admin", auth.AuthenticatorLDAP)
+	principal.Admin = true
+
+	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: principal,
+	}
+
+	resp, err := controller.DeleteRepository(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+}
+
 func TestGetRepository(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mock.NewMockOwnerManager(ctrl), mockRepositoryManager)
 
 	validateDescribeRepositoriesInput := func(input *ecr.DescribeRepositoriesInput) {
 		if v, want := aws.StringValue(input.RepositoryNames[0]), "user/test"; v != want {
@@ -149,7 +340,15 @@ func TestGetRepository(t *testing.T) {
 		Do(validateDescribeRepositoriesInput).
 		Return(output, nil)
 
+	mockRepositoryManager.EXPECT().
+		GetRepository("user/test").
+		Return(nil, auth.ErrRepositoryNotFound)
+
 	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
 	if _, err := controller.GetRepository(c); err != nil {
 		t.Fatal(err)
 	}
@@ -161,16 +360,104 @@ func TestListRepositories(t *testing.T) {
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	fnListRepos := func(input *ecr.DescribeRepositoriesInput, fn func(output *ecr.DescribeRepositoriesOutput, lastPage bool) bool) error {
+		output := &ecr.DescribeRepositoriesOutput{
+			Repositories: []*ecr.Repository{
+				{RepositoryName: aws.String("user/test")},
+				{RepositoryName: aws.String("user/private")},
+				{RepositoryName: aws.String("other/test")},
+			},
+		}
+
+		fn(output, true)
+		return nil
+	}
 
 	mockECR.EXPECT().
 		DescribeRepositoriesPages(gomock.Any(), gomock.Any()).
+		Do(fnListRepos).
 		Return(nil)
 
+	private := auth.NewRepository("user/private")
+	private.Visibility = auth.VisibilityPrivate
+
+	mockRepositoryManager.EXPECT().
+		ListRepositories().
+		Return([]*auth.Repository{private, auth.NewRepository("user/test")}, nil)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("user").
+		Return(nil, auth.ErrOwnerNotFound)
+
 	c := generateContext(t, nil, nil)
-	if _, err := controller.ListRepositories(c); err != nil {
+	c.Meta = map[string]interface{}{
+		principalKey: &auth.Principal{Permissions: auth.Permissions{Repositories: []string{"user/*"}}},
+	}
+
+	resp, err := controller.ListRepositories(c)
+	if err != nil {
 		t.Fatal(err)
 	}
+
+	var response models.ListRepositoriesResponse
+	unmarshalBody(t, resp, &response)
+	assert.Equal(t, []string{"user/test"}, response.Repositories)
+}
+
+func TestPutRepositoryAccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockECR := mock.NewMockECRAPI(ctrl)
+	mockAccountManager := mock.NewMockAccountManager(ctrl)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("user").
+		Return(&auth.Owner{Name: "user", Members: map[string]string{"user": auth.RoleDeveloper}}, nil)
+
+	mockECR.EXPECT().
+		DescribeRepositories(gomock.Any()).
+		Return(&ecr.DescribeRepositoriesOutput{}, nil)
+
+	expected := auth.NewRepository("user/test")
+	expected.Visibility = auth.VisibilityPrivate
+	expected.ReadGroups = []string{"qa"}
+
+	mockRepositoryManager.EXPECT().
+		PutRepository(expected).
+		Return(nil)
+
+	req := models.PutRepositoryAccessRequest{
+		Visibility: auth.VisibilityPrivate,
+		ReadGroups: []string{"qa"},
+	}
+
+	c := generateContext(t, req, map[string]string{"name": "test", "owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
+	resp, err := controller.PutRepositoryAccess(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+
+	c = generateContext(t, models.PutRepositoryAccessRequest{Visibility: "secret"}, map[string]string{"name": "test", "owner": "user"})
+	resp, err = controller.PutRepositoryAccess(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 400)
 }
 
 func TestListRepositoryImages(t *testing.T) {
@@ -179,7 +466,8 @@ func TestListRepositoryImages(t *testing.T) {
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mock.NewMockOwnerManager(ctrl), mockRepositoryManager)
 
 	validateListImagesInput := func(input *ecr.ListImagesInput, fn func(output *ecr.ListImagesOutput, lastPage bool) bool) {
 		if v, want := aws.StringValue(input.RepositoryName), "user/test"; v != want {
@@ -192,7 +480,15 @@ func TestListRepositoryImages(t *testing.T) {
 		Do(validateListImagesInput).
 		Return(nil)
 
+	mockRepositoryManager.EXPECT().
+		GetRepository("user/test").
+		Return(nil, auth.ErrRepositoryNotFound)
+
 	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
 	if _, err := controller.ListRepositoryImages(c); err != nil {
 		t.Fatal(err)
 	}
@@ -204,7 +500,8 @@ func TestGetRepositoryImage(t *testing.T) {
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mock.NewMockOwnerManager(ctrl), mockRepositoryManager)
 
 	validateDescribeImagesInput := func(input *ecr.DescribeImagesInput) {
 		if v, want := aws.StringValue(input.RepositoryName), "user/test"; v != want {
@@ -225,7 +522,15 @@ func TestGetRepositoryImage(t *testing.T) {
 		Do(validateDescribeImagesInput).
 		Return(output, nil)
 
+	mockRepositoryManager.EXPECT().
+		GetRepository("user/test").
+		Return(nil, auth.ErrRepositoryNotFound)
+
 	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user", "tag": "latest"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
 	if _, err := controller.GetRepositoryImage(c); err != nil {
 		t.Fatal(err)
 	}
@@ -237,7 +542,13 @@ func TestDeleteRepositoryImage(t *testing.T) {
 
 	mockECR := mock.NewMockECRAPI(ctrl)
 	mockAccountManager := mock.NewMockAccountManager(ctrl)
-	controller := NewRepositoryController(mockECR, mockAccountManager)
+	mockRepositoryManager := mock.NewMockRepositoryManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewRepositoryController(mockECR, mockAccountManager, mockOwnerManager, mockRepositoryManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("user").
+		Return(&auth.Owner{Name: "user", Members: map[string]string{"user": auth.RoleDeveloper}}, nil)
 
 	validateBatchDeleteImageInput := func(input *ecr.BatchDeleteImageInput) {
 		if v, want := aws.StringValue(input.RepositoryName), "user/test"; v != want {
@@ -255,6 +566,10 @@ func TestDeleteRepositoryImage(t *testing.T) {
 		Return(&ecr.BatchDeleteImageOutput{}, nil)
 
 	c := generateContext(t, nil, map[string]string{"name": "test", "owner": "user", "tag": "latest"})
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
 	if _, err := controller.DeleteRepositoryImage(c); err != nil {
 		t.Fatal(err)
 	}
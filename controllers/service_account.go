@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/zpatrick/fireball"
+)
+
+// ServiceAccountController manages the service accounts of owners and the tokens issued to them.
+// Only maintainers of an owner and admins can manage its service accounts.
+type ServiceAccountController struct {
+	owners       auth.OwnerManager
+	tokenManager auth.TokenManager
+}
+
+func NewServiceAccountController(o auth.OwnerManager, t auth.TokenManager) *ServiceAccountController {
+	return &ServiceAccountController{
+		owners:       o,
+		tokenManager: t,
+	}
+}
+
+func (s *ServiceAccountController) Routes() []*fireball.Route {
+	return []*fireball.Route{
+		{
+			Path: "/owner/:owner/service-account/:name",
+			Handlers: fireball.Handlers{
+				"PUT":    s.PutServiceAccount,
+				"DELETE": s.DeleteServiceAccount,
+			},
+		},
+		{
+			Path: "/owner/:owner/service-account/:name/token",
+			Handlers: fireball.Handlers{
+				"GET":  s.ListServiceAccountTokens,
+				"POST": s.CreateServiceAccountToken,
+			},
+		},
+	}
+}
+
+// PutServiceAccount creates a service account or updates its role and description
+func (s *ServiceAccountController) PutServiceAccount(c *fireball.Context) (fireball.Response, error) {
+	var req models.PutServiceAccountRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := auth.ValidateServiceAccountRole(req.Role); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	name := c.PathVariables["name"]
+	if err := auth.ValidateServiceAccountName(name); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	owner, resp, err := s.getManagedOwner(c)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	serviceAccount := &auth.ServiceAccount{
+		Name:        name,
+		Role:        req.Role,
+		Description: req.Description,
+	}
+
+	owner.ServiceAccounts[name] = serviceAccount
+	if err := s.owners.PutOwner(owner); err != nil {
+		if err == auth.ErrOwnerModified {
+			return newOwnerModifiedError(owner.Name)
+		}
+
+		return nil, err
+	}
+
+	log.Printf("[INFO] User '%s' gave service account '%s' the '%s' role in owner '%s'", getUser(c), name, req.Role, owner.Name)
+	return fireball.NewJSONResponse(200, serviceAccountToModel(owner.Name, serviceAccount))
+}
+
+// DeleteServiceAccount deletes a service account and every token issued to it
+func (s *ServiceAccountController) DeleteServiceAccount(c *fireball.Context) (fireball.Response, error) {
+	owner, serviceAccount, resp, err := s.getManagedServiceAccount(c)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	delete(owner.ServiceAccounts, serviceAccount.Name)
+	if err := s.owners.PutOwner(owner); err != nil {
+		if err == auth.ErrOwnerModified {
+			return newOwnerModifiedError(owner.Name)
+		}
+
+		return nil, err
+	}
+
+	count, err := s.tokenManager.DeleteUserTokens(auth.ServiceAccountUsername(owner.Name, serviceAccount.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] User '%s' deleted service account '%s' of owner '%s' and %d of its tokens", getUser(c), serviceAccount.Name, owner.Name, count)
+	return fireball.NewResponse(200, []byte("Successfully deleted service account"), nil), nil
+}
+
+func (s *ServiceAccountController) ListServiceAccountTokens(c *fireball.Context) (fireball.Response, error) {
+	owner, serviceAccount, resp, err := s.getManagedServiceAccount(c)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return listTokens(s.tokenManager, auth.ServiceAccountUsername(owner.Name, serviceAccount.Name))
+}
+
+// CreateServiceAccountToken issues a token to a service account.
+// The token cannot be given more access than the maintainer who creates it has.
+func (s *ServiceAccountController) CreateServiceAccountToken(c *fireball.Context) (fireball.Response, error) {
+	owner, serviceAccount, resp, err := s.getManagedServiceAccount(c)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	username := auth.ServiceAccountUsername(owner.Name, serviceAccount.Name)
+	log.Printf("[INFO] User '%s' is creating a token for service account '%s'", getUser(c), username)
+	return createToken(c, s.tokenManager, username)
+}
+
+// getManagedOwner returns the owner in the path if the principal stored in the context can manage it.
+// A non-nil response is returned if the owner cannot be managed.
+func (s *ServiceAccountController) getManagedOwner(c *fireball.Context) (*auth.Owner, fireball.Response, error) {
+	ownerName := c.PathVariables["owner"]
+	owner, err := s.owners.GetOwner(ownerName)
+	if err != nil {
+		if err == auth.ErrOwnerNotFound {
+			resp, err := newOwnerNotFoundError(ownerName)
+			return nil, resp, err
+		}
+
+		return nil, nil, err
+	}
+
+	if !owner.CanManage(getPrincipal(c)) && !isAdmin(c) {
+		resp, err := fireball.NewJSONError(403, fmt.Errorf("User '%s' is not a maintainer of owner '%s'", getUser(c), ownerName))
+		return nil, resp, err
+	}
+
+	return owner, nil, nil
+}
+
+// getManagedServiceAccount returns the service account in the path if the principal stored in the context can manage it.
+// A non-nil response is returned if the service account does not exist or cannot be managed.
+func (s *ServiceAccountController) getManagedServiceAccount(c *fireball.Context) (*auth.Owner, *auth.ServiceAccount, fireball.Response, error) {
+	owner, resp, err := s.getManagedOwner(c)
+	if resp != nil || err != nil {
+		return nil, nil, resp, err
+	}
+
+	name := c.PathVariables["name"]
+	serviceAccount, ok := owner.ServiceAccounts[name]
+	if !ok {
+		resp, err := fireball.NewJSONError(404, fmt.Errorf("Service account '%s' does not exist in owner '%s'", name, owner.Name))
+		return nil, nil, resp, err
+	}
+
+	return owner, serviceAccount, nil, nil
+}
+
+func serviceAccountToModel(owner string, serviceAccount *auth.ServiceAccount) models.ServiceAccount {
+	return models.ServiceAccount{
+		Name:        serviceAccount.Name,
+		Username:    auth.ServiceAccountUsername(owner, serviceAccount.Name),
+		Role:        serviceAccount.Role,
+		Description: serviceAccount.Description,
+	}
+}
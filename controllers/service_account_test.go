@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
+	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newServiceAccountTestOwner() *auth.Owner {
+	owner := auth.NewOwner("carbon")
+	owner.Members["maintainer"] = auth.RoleMaintainer
+	owner.Members["developer"] = auth.RoleDeveloper
+	owner.ServiceAccounts["ci"] = &auth.ServiceAccount{Name: "ci", Role: auth.RoleDeveloper}
+	return owner
+}
+
+func TestPutServiceAccount(t *testing.T) {
+	cases := []struct {
+		Name         string
+		User         string
+		Request      models.PutServiceAccountRequest
+		ExpectedCode int
+	}{
+		{"maintainer", "maintainer", models.PutServiceAccountRequest{Role: auth.RoleReader, Description: "deploys"}, 200},
+		{"developer", "developer", models.PutServiceAccountRequest{Role: auth.RoleReader}, 403},
+		{"maintainer role", "maintainer", models.PutServiceAccountRequest{Role: auth.RoleMaintainer}, 400},
+		{"missing role", "maintainer", models.PutServiceAccountRequest{}, 400},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+			controller := NewServiceAccountController(mockOwnerManager, mock.NewMockTokenManager(ctrl))
+
+			if c.ExpectedCode != 400 {
+				mockOwnerManager.EXPECT().
+					GetOwner("carbon").
+					Return(newServiceAccountTestOwner(), nil)
+			}
+
+			if c.ExpectedCode == 200 {
+				validateOwner := func(owner *auth.Owner) {
+					expected := &auth.ServiceAccount{Name: "deploy", Role: c.Request.Role, Description: c.Request.Description}
+					assert.Equal(t, expected, owner.ServiceAccounts["deploy"])
+				}
+
+				mockOwnerManager.EXPECT().
+					PutOwner(gomock.Any()).
+					Do(validateOwner).
+					Return(nil)
+			}
+
+			pathVariables := map[string]string{"owner": "carbon", "name": "deploy"}
+			resp, err := controller.PutServiceAccount(newOwnerContext(t, c.Request, pathVariables, c.User))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, c.ExpectedCode)
+			if c.ExpectedCode == 200 {
+				var response models.ServiceAccount
+				unmarshalBody(t, resp, &response)
+				assert.Equal(t, "serviceaccount:carbon/deploy", response.Username)
+			}
+		})
+	}
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewServiceAccountController(mockOwnerManager, mockTokenManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(newServiceAccountTestOwner(), nil)
+
+	validateOwner := func(owner *auth.Owner) {
+		assert.NotContains(t, owner.ServiceAccounts, "ci")
+	}
+
+	mockOwnerManager.EXPECT().
+		PutOwner(gomock.Any()).
+		Do(validateOwner).
+		Return(nil)
+
+	// the tokens of deleted service accounts are revoked
+	mockTokenManager.EXPECT().
+		DeleteUserTokens("serviceaccount:carbon/ci").
+		Return(2, nil)
+
+	pathVariables := map[string]string{"owner": "carbon", "name": "ci"}
+	resp, err := controller.DeleteServiceAccount(newOwnerContext(t, nil, pathVariables, "maintainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+}
+
+func TestDeleteServiceAccountNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewServiceAccountController(mockOwnerManager, mock.NewMockTokenManager(ctrl))
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(newServiceAccountTestOwner(), nil)
+
+	pathVariables := map[string]string{"owner": "carbon", "name": "other"}
+	resp, err := controller.DeleteServiceAccount(newOwnerContext(t, nil, pathVariables, "maintainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 404)
+}
+
+func TestDeleteServiceAccountOwnerModified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewServiceAccountController(mockOwnerManager, mock.NewMockTokenManager(ctrl))
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(newServiceAccountTestOwner(), nil)
+
+	mockOwnerManager.EXPECT().
+		PutOwner(gomock.Any()).
+		Return(auth.ErrOwnerModified)
+
+	pathVariables := map[string]string{"owner": "carbon", "name": "ci"}
+	resp, err := controller.DeleteServiceAccount(newOwnerContext(t, nil, pathVariables, "maintainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 409)
+}
+
+func TestCreateServiceAccountToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewServiceAccountController(mockOwnerManager, mockTokenManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(newServiceAccountTestOwner(), nil)
+
+	validateOptions := func(user string, options auth.TokenOptions) {
+		assert.Equal(t, "ci", options.Name)
+		assert.Equal(t, []string{auth.ScopePush}, options.Scopes)
+	}
+
+	mockTokenManager.EXPECT().
+		CreateToken("serviceaccount:carbon/ci", gomock.Any()).
+		Do(validateOptions).
+		Return("token", nil)
+
+	req := models.CreateTokenRequest{
+		Name:   "ci",
+		Scopes: []string{auth.ScopePush},
+	}
+
+	pathVariables := map[string]string{"owner": "carbon", "name": "ci"}
+	resp, err := controller.CreateServiceAccountToken(newOwnerContext(t, req, pathVariables, "maintainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.CreateTokenResponse
+	unmarshalBody(t, resp, &response)
+	assert.Equal(t, "token", response.Token)
+}
+
+func TestListServiceAccountTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewServiceAccountController(mockOwnerManager, mockTokenManager)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(newServiceAccountTestOwner(), nil)
+
+	tokens := []auth.Token{
+		{ID: "id", User: "serviceaccount:carbon/ci", Name: "ci"},
+	}
+
+	mockTokenManager.EXPECT().
+		ListTokens("serviceaccount:carbon/ci").
+		Return(tokens, nil)
+
+	pathVariables := map[string]string{"owner": "carbon", "name": "ci"}
+	resp, err := controller.ListServiceAccountTokens(newOwnerContext(t, nil, pathVariables, "maintainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.ListTokensResponse
+	unmarshalBody(t, resp, &response)
+	assert.Len(t, response.Tokens, 1)
+	assert.Equal(t, "serviceaccount:carbon/ci", response.Tokens[0].User)
+}
@@ -53,14 +53,39 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 				Name:        "Account",
 				Description: "Methods for Account Access",
 			},
+			{
+				Name:        "Owner",
+				Description: "Methods for Owners and their Members",
+			},
+			{
+				Name:        "Lockout",
+				Description: "Methods for Authentication Lockouts",
+			},
+			{
+				Name:        "Audit",
+				Description: "Methods for Audit Events",
+			},
 		},
 		Paths: map[string]swagger.Path{
 			"/token": map[string]swagger.Method{
+				"get": {
+					Tags:     []string{"Token"},
+					Summary:  "List your Tokens",
+					Security: swagger.BasicAuthSecurity("login"),
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("ListTokensResponse"),
+						},
+					},
+				},
 				"post": {
-					Tags:       []string{"Token"},
-					Summary:    "Create a new Token",
-					Security:   swagger.BasicAuthSecurity("login"),
-					Parameters: []swagger.Parameter{},
+					Tags:     []string{"Token"},
+					Summary:  "Create a new Token",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewBodyParam("CreateTokenRequest", "none", false),
+					},
 					Responses: map[string]swagger.Response{
 						"200": {
 							Description: "success",
@@ -69,13 +94,13 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 					},
 				},
 			},
-			"/token/{token}": map[string]swagger.Method{
+			"/token/{id}": map[string]swagger.Method{
 				"delete": {
 					Tags:     []string{"Token"},
-					Summary:  "Delete a Token",
+					Summary:  "Delete one of your Tokens; admins can delete any Token",
 					Security: swagger.BasicAuthSecurity("login"),
 					Parameters: []swagger.Parameter{
-						swagger.NewStringPathParam("token", "The token to delete", true),
+						swagger.NewStringPathParam("id", "The id of the token to delete", true),
 					},
 					Responses: map[string]swagger.Response{
 						"200": {
@@ -84,6 +109,55 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 					},
 				},
 			},
+			"/token/{id}/rotate": map[string]swagger.Method{
+				"post": {
+					Tags:     []string{"Token"},
+					Summary:  "Replace one of your Tokens, expiring the current one after a grace period",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("id", "The id of the token to rotate", true),
+						swagger.NewBodyParam("RotateTokenRequest", "none", false),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("CreateTokenResponse"),
+						},
+					},
+				},
+			},
+			"/user/{user}/tokens": map[string]swagger.Method{
+				"delete": {
+					Tags:     []string{"Token"},
+					Summary:  "Delete every Token created by a user; requires the admin role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("user", "The user whose tokens to delete", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+						},
+					},
+				},
+			},
+			"/auth/token": map[string]swagger.Method{
+				"get": {
+					Tags:     []string{"Token"},
+					Summary:  "Issue a short-lived Docker Registry token",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringQueryParam("service", "The name of the registry", false),
+						swagger.NewStringQueryParam("scope", "The requested access, e.g. 'repository:<owner>/<name>:pull,push'", false),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("RegistryTokenResponse"),
+						},
+					},
+				},
+			},
 			"/repository": map[string]swagger.Method{
 				"get": {
 					Tags:     []string{"Repository"},
@@ -159,6 +233,23 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 					},
 				},
 			},
+			"/repository/{owner}/{name}/access": map[string]swagger.Method{
+				"put": {
+					Tags:     []string{"Repository"},
+					Summary:  "Set the visibility and read acl of a Repository; requires the maintainer or developer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "Owner of the Repository", true),
+						swagger.NewStringPathParam("name", "Name of the Repository", true),
+						swagger.NewBodyParam("PutRepositoryAccessRequest", "none", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+						},
+					},
+				},
+			},
 			"/repository/{owner}/{name}/image": map[string]swagger.Method{
 				"get": {
 					Tags:    []string{"Image"},
@@ -223,7 +314,7 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 				},
 				"post": {
 					Tags:     []string{"Account"},
-					Summary:  "Grant access to an account",
+					Summary:  "Grant access to an account; requires the admin role",
 					Security: swagger.BasicAuthSecurity("login"),
 					Parameters: []swagger.Parameter{
 						swagger.NewBodyParam("GrantAccessRequest", "none", true),
@@ -238,7 +329,7 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 			"/account/{id}": map[string]swagger.Method{
 				"delete": {
 					Tags:    []string{"Account"},
-					Summary: "Revoke access from an account",
+					Summary: "Revoke access from an account; requires the admin role",
 					Parameters: []swagger.Parameter{
 						swagger.NewStringPathParam("account", "Account that you want to revoke access to", true),
 					},
@@ -250,17 +341,260 @@ func (s *SwaggerController) serveSwaggerJSON(c *fireball.Context) (fireball.Resp
 					},
 				},
 			},
+			"/owner": map[string]swagger.Method{
+				"get": {
+					Tags:     []string{"Owner"},
+					Summary:  "List all owners",
+					Security: swagger.BasicAuthSecurity("login"),
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("ListOwnersResponse"),
+						},
+					},
+				},
+				"post": {
+					Tags:     []string{"Owner"},
+					Summary:  "Create an owner, with yourself as its maintainer",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewBodyParam("CreateOwnerRequest", "none", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("Owner"),
+						},
+					},
+				},
+			},
+			"/owner/{owner}": map[string]swagger.Method{
+				"get": {
+					Tags:     []string{"Owner"},
+					Summary:  "Describe an owner and its members",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("Owner"),
+						},
+					},
+				},
+			},
+			"/owner/{owner}/member/{user}": map[string]swagger.Method{
+				"put": {
+					Tags:     []string{"Owner"},
+					Summary:  "Add a user to an owner or change their role; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("user", "The username of the member", true),
+						swagger.NewBodyParam("PutMemberRequest", "none", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("Owner"),
+						},
+					},
+				},
+				"delete": {
+					Tags:     []string{"Owner"},
+					Summary:  "Remove a user from an owner; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("user", "The username of the member", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("Owner"),
+						},
+					},
+				},
+			},
+			"/owner/{owner}/service-account/{name}": map[string]swagger.Method{
+				"put": {
+					Tags:     []string{"Owner"},
+					Summary:  "Create a service account or change its role; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("name", "The name of the service account", true),
+						swagger.NewBodyParam("PutServiceAccountRequest", "none", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("ServiceAccount"),
+						},
+					},
+				},
+				"delete": {
+					Tags:     []string{"Owner"},
+					Summary:  "Delete a service account and its Tokens; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("name", "The name of the service account", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+						},
+					},
+				},
+			},
+			"/owner/{owner}/service-account/{name}/token": map[string]swagger.Method{
+				"get": {
+					Tags:     []string{"Owner"},
+					Summary:  "List the Tokens of a service account; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("name", "The name of the service account", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("ListTokensResponse"),
+						},
+					},
+				},
+				"post": {
+					Tags:     []string{"Owner"},
+					Summary:  "Create a Token for a service account; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("name", "The name of the service account", true),
+						swagger.NewBodyParam("CreateTokenRequest", "none", false),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("CreateTokenResponse"),
+						},
+					},
+				},
+			},
+			"/owner/{owner}/group/{group}": map[string]swagger.Method{
+				"put": {
+					Tags:     []string{"Owner"},
+					Summary:  "Add a group to an owner or change its role; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("group", "The group", true),
+						swagger.NewBodyParam("PutMemberRequest", "none", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("Owner"),
+						},
+					},
+				},
+				"delete": {
+					Tags:     []string{"Owner"},
+					Summary:  "Remove a group from an owner; requires the maintainer role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("owner", "The Owner", true),
+						swagger.NewStringPathParam("group", "The group", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("Owner"),
+						},
+					},
+				},
+			},
+			"/audit": map[string]swagger.Method{
+				"get": {
+					Tags:     []string{"Audit"},
+					Summary:  "List audit events, newest first; requires the admin role",
+					Security: swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringQueryParam("user", "Only list events of the user or service account", false),
+						swagger.NewStringQueryParam("owner", "Only list events which targeted the owner", false),
+						swagger.NewStringQueryParam("repository", "Only list events which targeted the repository, e.g. 'carbon/api'", false),
+						swagger.NewStringQueryParam("start", "Only list events after this RFC 3339 time; defaults to a day before 'end'", false),
+						swagger.NewStringQueryParam("end", "Only list events before this RFC 3339 time; defaults to now", false),
+						swagger.NewIntQueryParam("limit", "The maximum number of events to list; defaults to 100", false),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("ListAuditEventsResponse"),
+						},
+					},
+				},
+			},
+			"/lockout": map[string]swagger.Method{
+				"get": {
+					Tags:        []string{"Lockout"},
+					Summary:     "List usernames and addresses with recent failed authentication attempts",
+					Description: "Failed attempts are counted by each instance, so only the lockouts of the instance which handles the request are listed",
+					Security:    swagger.BasicAuthSecurity("login"),
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+							Schema:      swagger.NewObjectSchema("ListLockoutsResponse"),
+						},
+					},
+				},
+			},
+			"/lockout/{type}/{name}": map[string]swagger.Method{
+				"delete": {
+					Tags:        []string{"Lockout"},
+					Summary:     "Clear the failed authentication attempts of a username or address",
+					Description: "Failed attempts are counted by each instance, so only the lockouts of the instance which handles the request are cleared",
+					Security:    swagger.BasicAuthSecurity("login"),
+					Parameters: []swagger.Parameter{
+						swagger.NewStringPathParam("type", "Either 'user' or 'address'", true),
+						swagger.NewStringPathParam("name", "The username or address", true),
+					},
+					Responses: map[string]swagger.Response{
+						"200": {
+							Description: "success",
+						},
+					},
+				},
+			},
 		},
 		Definitions: map[string]swagger.Definition{
-			"CreateRepositoryRequest":  models.CreateRepositoryRequest{}.Definition(),
-			"CreateRepositoryResponse": models.CreateRepositoryResponse{}.Definition(),
-			"CreateTokenResponse":      models.CreateTokenResponse{}.Definition(),
-			"ListRepositoriesResponse": models.ListRepositoriesResponse{}.Definition(),
-			"Repository":               models.Repository{}.Definition(),
-			"ListImagesResponse":       models.ListImagesResponse{}.Definition(),
-			"Image":                    models.Image{}.Definition(),
-			"ListAccountsResponse":     models.ListAccountsResponse{}.Definition(),
-			"GrantAccessRequest":       models.GrantAccessRequest{}.Definition(),
+			"CreateRepositoryRequest":    models.CreateRepositoryRequest{}.Definition(),
+			"CreateRepositoryResponse":   models.CreateRepositoryResponse{}.Definition(),
+			"CreateTokenRequest":         models.CreateTokenRequest{}.Definition(),
+			"CreateTokenResponse":        models.CreateTokenResponse{}.Definition(),
+			"ListTokensResponse":         models.ListTokensResponse{}.Definition(),
+			"RotateTokenRequest":         models.RotateTokenRequest{}.Definition(),
+			"RegistryTokenResponse":      models.RegistryTokenResponse{}.Definition(),
+			"Token":                      models.Token{}.Definition(),
+			"ListRepositoriesResponse":   models.ListRepositoriesResponse{}.Definition(),
+			"Repository":                 models.Repository{}.Definition(),
+			"PutRepositoryAccessRequest": models.PutRepositoryAccessRequest{}.Definition(),
+			"ListImagesResponse":         models.ListImagesResponse{}.Definition(),
+			"Image":                      models.Image{}.Definition(),
+			"ListAccountsResponse":       models.ListAccountsResponse{}.Definition(),
+			"GrantAccessRequest":         models.GrantAccessRequest{}.Definition(),
+			"ListOwnersResponse":         models.ListOwnersResponse{}.Definition(),
+			"CreateOwnerRequest":         models.CreateOwnerRequest{}.Definition(),
+			"PutMemberRequest":           models.PutMemberRequest{}.Definition(),
+			"Owner":                      models.Owner{}.Definition(),
+			"OwnerMember":                models.OwnerMember{}.Definition(),
+			"ServiceAccount":             models.ServiceAccount{}.Definition(),
+			"PutServiceAccountRequest":   models.PutServiceAccountRequest{}.Definition(),
+			"ListLockoutsResponse":       models.ListLockoutsResponse{}.Definition(),
+			"Lockout":                    models.Lockout{}.Definition(),
+			"ListAuditEventsResponse":    models.ListAuditEventsResponse{}.Definition(),
+			"AuditEvent":                 models.AuditEvent{}.Definition(),
 		},
 		SecurityDefinitions: map[string]swagger.SecurityDefinition{
 			"login": {
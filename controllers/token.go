@@ -1,6 +1,12 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
 	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/models"
 	"github.com/zpatrick/fireball"
@@ -8,11 +14,13 @@ import (
 
 type TokenController struct {
 	tokenManager auth.TokenManager
+	owners       auth.OwnerManager
 }
 
-func NewTokenController(t auth.TokenManager) *TokenController {
+func NewTokenController(t auth.TokenManager, o auth.OwnerManager) *TokenController {
 	return &TokenController{
 		tokenManager: t,
+		owners:       o,
 	}
 }
 
@@ -21,21 +29,108 @@ func (t *TokenController) Routes() []*fireball.Route {
 		{
 			Path: "/token",
 			Handlers: fireball.Handlers{
+				"GET":  t.ListTokens,
 				"POST": t.CreateToken,
 			},
 		},
 		{
-			Path: "/token/:token",
+			Path: "/token/:id",
 			Handlers: fireball.Handlers{
 				"DELETE": t.DeleteToken,
 			},
 		},
+		{
+			Path: "/token/:id/rotate",
+			Handlers: fireball.Handlers{
+				"POST": t.RotateToken,
+			},
+		},
+		{
+			Path: "/user/:user/tokens",
+			Handlers: fireball.Handlers{
+				"DELETE": t.DeleteUserTokens,
+			},
+		},
 	}
 }
 
 func (t *TokenController) CreateToken(c *fireball.Context) (fireball.Response, error) {
-	user, _, _ := c.Request.BasicAuth()
-	token, err := t.tokenManager.CreateToken(user)
+	if getPrincipal(c).IsServiceAccount() {
+		return fireball.NewJSONError(403, fmt.Errorf("Tokens for service accounts are created by the maintainers of their owner"))
+	}
+
+	return createToken(c, t.tokenManager, getUser(c))
+}
+
+// createToken creates a token for user from the request body.
+// The token cannot be given more access or a longer lifetime than the principal stored in the context has.
+func createToken(c *fireball.Context, tokenManager auth.TokenManager, user string) (fireball.Response, error) {
+	// the request body is optional
+	var req models.CreateTokenRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil && err != io.EOF {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := auth.ValidateScopes(req.Scopes); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := auth.ValidateRepositoryPatterns(req.Repositories); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	// tokens cannot be given more access than the credentials used to create them
+	permissions := getPermissions(c)
+	for _, scope := range req.Scopes {
+		if !permissions.HasScope(scope) {
+			return fireball.NewJSONError(403, fmt.Errorf("Cannot create a token with the '%s' scope", scope))
+		}
+	}
+
+	for _, pattern := range req.Repositories {
+		if !permissions.CanAccessRepository(pattern) {
+			return fireball.NewJSONError(403, fmt.Errorf("Cannot create a token with access to '%s'", pattern))
+		}
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = permissions.Scopes
+	}
+
+	repositories := req.Repositories
+	if len(repositories) == 0 {
+		repositories = permissions.Repositories
+	}
+
+	options := auth.TokenOptions{
+		Name:         req.Name,
+		Description:  req.Description,
+		Scopes:       scopes,
+		Repositories: repositories,
+	}
+
+	if req.ExpiresIn != nil {
+		options.ExpiresIn = time.Duration(*req.ExpiresIn) * time.Second
+	}
+
+	// tokens cannot outlive the credentials used to create them either
+	if expiresAt := getPrincipal(c).ExpiresAt; !expiresAt.IsZero() {
+		remaining := time.Until(expiresAt)
+		if remaining <= 0 || options.ExpiresIn > remaining {
+			return fireball.NewJSONError(403, fmt.Errorf("Cannot create a token which expires after '%s'", expiresAt.Format(time.RFC3339)))
+		}
+
+		if options.ExpiresIn == 0 {
+			options.ExpiresIn = remaining
+		}
+	}
+
+	token, err := tokenManager.CreateToken(user, options)
 	if err != nil {
 		return nil, err
 	}
@@ -48,10 +143,135 @@ func (t *TokenController) CreateToken(c *fireball.Context) (fireball.Response, e
 }
 
 func (t *TokenController) DeleteToken(c *fireball.Context) (fireball.Response, error) {
-	token := c.PathVariables["token"]
-	if err := t.tokenManager.DeleteToken(token); err != nil {
+	token, resp, err := t.getOwnedToken(c)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := t.tokenManager.DeleteToken(token.ID); err != nil {
 		return nil, err
 	}
 
+	log.Printf("[INFO] User '%s' deleted token '%s' of user '%s'", getUser(c), token.MaskedToken, token.User)
 	return fireball.NewResponse(200, []byte("Successfully deleted token"), nil), nil
 }
+
+func (t *TokenController) RotateToken(c *fireball.Context) (fireball.Response, error) {
+	// the request body is optional
+	var req models.RotateTokenRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil && err != io.EOF {
+		return fireball.NewJSONError(400, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fireball.NewJSONError(400, err)
+	}
+
+	token, resp, err := t.getOwnedToken(c)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	gracePeriod := auth.DefaultRotationGracePeriod
+	if req.GracePeriod != nil {
+		gracePeriod = time.Duration(*req.GracePeriod) * time.Second
+	}
+
+	replacement, err := t.tokenManager.RotateToken(token.ID, gracePeriod)
+	if err != nil {
+		if err == auth.ErrTokenNotFound {
+			return fireball.NewJSONError(404, err)
+		}
+
+		return nil, err
+	}
+
+	rotated := models.CreateTokenResponse{
+		Token: replacement,
+	}
+
+	return fireball.NewJSONResponse(202, rotated)
+}
+
+// DeleteUserTokens revokes every token created by a user, e.g. when they leave.
+// The AuthDecorator restricts this request to admins.
+func (t *TokenController) DeleteUserTokens(c *fireball.Context) (fireball.Response, error) {
+	user := c.PathVariables["user"]
+	count, err := t.tokenManager.DeleteUserTokens(user)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] User '%s' deleted %d tokens of user '%s'", getUser(c), count, user)
+	message := fmt.Sprintf("Successfully deleted %d tokens of user '%s'", count, user)
+	return fireball.NewResponse(200, []byte(message), nil), nil
+}
+
+// getOwnedToken returns the token with the id in the path if it was created by the user stored in the context.
+// Maintainers can access the tokens of their owner's service accounts, and admins can access the tokens of every user.
+// A non-nil response is returned if the token cannot be accessed.
+func (t *TokenController) getOwnedToken(c *fireball.Context) (*auth.Token, fireball.Response, error) {
+	id := c.PathVariables["id"]
+	if auth.IsTokenFormat(id) {
+		resp, err := fireball.NewJSONError(400, fmt.Errorf("Tokens are addressed by their id, which is listed by GET /token"))
+		return nil, resp, err
+	}
+
+	token, err := t.tokenManager.GetToken(id)
+	if err != nil {
+		if err == auth.ErrTokenNotFound {
+			resp, err := fireball.NewJSONError(404, err)
+			return nil, resp, err
+		}
+
+		return nil, nil, err
+	}
+
+	if user := getUser(c); token.User != user && !isAdmin(c) {
+		canManage, err := canManageServiceAccount(c, t.owners, token.User)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !canManage {
+			resp, err := fireball.NewJSONError(403, fmt.Errorf("User '%s' did not create token '%s'", user, id))
+			return nil, resp, err
+		}
+	}
+
+	return token, nil, nil
+}
+
+func (t *TokenController) ListTokens(c *fireball.Context) (fireball.Response, error) {
+	return listTokens(t.tokenManager, getUser(c))
+}
+
+func listTokens(tokenManager auth.TokenManager, user string) (fireball.Response, error) {
+	tokens, err := tokenManager.ListTokens(user)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ListTokensResponse{
+		Tokens: make([]models.Token, len(tokens)),
+	}
+
+	for i, token := range tokens {
+		resp.Tokens[i] = models.Token{
+			ID:           token.ID,
+			User:         token.User,
+			Name:         token.Name,
+			Description:  token.Description,
+			MaskedToken:  token.MaskedToken,
+			Scopes:       token.Scopes,
+			Repositories: token.Repositories,
+			CreatedAt:    token.CreatedAt,
+		}
+
+		if expiresAt := token.ExpiresAt; !expiresAt.IsZero() {
+			resp.Tokens[i].ExpiresAt = &expiresAt
+		}
+	}
+
+	return fireball.NewJSONResponse(200, resp)
+}
@@ -1,10 +1,16 @@
 package controllers
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/mock"
+	"github.com/quintilesims/d.ims.io/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/zpatrick/fireball"
 )
 
 func TestCreateToken(t *testing.T) {
@@ -12,10 +18,10 @@ func TestCreateToken(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTokenManager := mock.NewMockTokenManager(ctrl)
-	controller := NewTokenController(mockTokenManager)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
 
 	mockTokenManager.EXPECT().
-		CreateToken(gomock.Any()).
+		CreateToken(gomock.Any(), gomock.Any()).
 		Return("", nil)
 
 	c := generateContext(t, nil, nil)
@@ -24,19 +30,512 @@ func TestCreateToken(t *testing.T) {
 	}
 }
 
+func TestCreateTokenWithOptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	options := auth.TokenOptions{
+		Name:        "name",
+		Description: "description",
+		ExpiresIn:   time.Hour,
+		Scopes:      []string{auth.ScopePull},
+	}
+
+	mockTokenManager.EXPECT().
+		CreateToken("user", options).
+		Return("token", nil)
+
+	req := map[string]interface{}{
+		"name":        "name",
+		"description": "description",
+		"expires_in":  3600,
+		"scopes":      []string{auth.ScopePull},
+	}
+
+	c := generateContext(t, req, nil)
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
+	resp, err := controller.CreateToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.CreateTokenResponse
+	unmarshalBody(t, resp, &response)
+	assert.Equal(t, "token", response.Token)
+}
+
+func TestCreateTokenCannotEscalateScopes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	c := generateContext(t, models.CreateTokenRequest{Scopes: []string{auth.ScopeAdmin}}, nil)
+	c.Meta = map[string]interface{}{
+		principalKey: &auth.Principal{Permissions: auth.Permissions{Scopes: []string{auth.ScopeManage}}},
+	}
+
+	resp, err := controller.CreateToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestCreateTokenInheritsScopes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	scopes := []string{auth.ScopePush, auth.ScopeManage}
+	mockTokenManager.EXPECT().
+		CreateToken(gomock.Any(), auth.TokenOptions{Scopes: scopes}).
+		Return("token", nil)
+
+	c := generateContext(t, nil, nil)
+	c.Meta = map[string]interface{}{
+		principalKey: &auth.Principal{Permissions: auth.Permissions{Scopes: scopes}},
+	}
+
+	if _, err := controller.CreateToken(c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateTokenCannotOutliveCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	newContext := func(body interface{}) *fireball.Context {
+		principal := auth.NewPrincipal("user", auth.AuthenticatorToken)
+		principal.TokenID = "parent"
+		principal.ExpiresAt = time.Now().Add(time.Hour)
+
+		c := generateContext(t, body, nil)
+		c.Meta = map[string]interface{}{principalKey: principal}
+		return c
+	}
+
+	// tokens created without a lifetime expire with their parent
+	validateOptions := func(user string, options auth.TokenOptions) {
+		assert.True(t, options.ExpiresIn > time.Minute*59 && options.ExpiresIn <= time.Hour, options.ExpiresIn)
+	}
+
+	mockTokenManager.EXPECT().
+		CreateToken("user", gomock.Any()).
+		Do(validateOptions).
+		Return("token", nil)
+
+	resp, err := controller.CreateToken(newContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 202)
+
+	mockTokenManager.EXPECT().
+		CreateToken("user", auth.TokenOptions{ExpiresIn: time.Minute, Scopes: auth.AllScopes}).
+		Return("token", nil)
+
+	resp, err = controller.CreateToken(newContext(map[string]int64{"expires_in": 60}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 202)
+
+	resp, err = controller.CreateToken(newContext(map[string]int64{"expires_in": 60 * 60 * 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestCreateTokenInvalidLifetime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	controller := NewTokenController(mock.NewMockTokenManager(ctrl), mock.NewMockOwnerManager(ctrl))
+
+	// large lifetimes would overflow into tokens which never expire
+	cases := map[string]int64{
+		"zero":          0,
+		"negative":      -1,
+		"over one year": models.MaxTokenLifetime + 1,
+		"overflow":      math.MaxInt64 / int64(time.Second) * 2,
+		"max int":       math.MaxInt64,
+	}
+
+	for name, expiresIn := range cases {
+		c := newTokenContext(t, map[string]int64{"expires_in": expiresIn}, nil, "user")
+		resp, err := controller.CreateToken(c)
+		if err != nil {
+			t.Fatalf("case %s: %v", name, err)
+		}
+
+		assertResponseCode(t, resp, 400)
+	}
+}
+
+func newTokenContext(t *testing.T, body interface{}, pathVariables map[string]string, user string) *fireball.Context {
+	c := generateContext(t, body, pathVariables)
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal(user, auth.AuthenticatorLDAP),
+	}
+
+	return c
+}
+
+func TestCreateTokenAsServiceAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	c := newTokenContext(t, nil, nil, auth.ServiceAccountUsername("carbon", "ci"))
+	resp, err := controller.CreateToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
 func TestDeleteToken(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockTokenManager := mock.NewMockTokenManager(ctrl)
-	controller := NewTokenController(mockTokenManager)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(&auth.Token{ID: "id", User: "user"}, nil)
+
+	mockTokenManager.EXPECT().
+		DeleteToken("id").
+		Return(nil)
+
+	c := newTokenContext(t, nil, map[string]string{"id": "id"}, "user")
+	resp, err := controller.DeleteToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+}
+
+func TestDeleteTokenOwnership(t *testing.T) {
+	cases := map[string]struct {
+		Admin    bool
+		Expected int
+	}{
+		"other user": {false, 403},
+		"admin":      {true, 200},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTokenManager := mock.NewMockTokenManager(ctrl)
+			controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+			mockTokenManager.EXPECT().
+				GetToken("id").
+				Return(&auth.Token{ID: "id", User: "owner"}, nil)
+
+			if c.Expected == 200 {
+				mockTokenManager.EXPECT().
+					DeleteToken("id").
+					Return(nil)
+			}
+
+			principal := auth.NewPrincipal("other", auth.AuthenticatorLDAP)
+			principal.Admin = c.Admin
+
+			ctx := generateContext(t, nil, map[string]string{"id": "id"})
+			ctx.Meta = map[string]interface{}{
+				principalKey: principal,
+			}
+
+			resp, err := controller.DeleteToken(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertResponseCode(t, resp, c.Expected)
+		})
+	}
+}
+
+func TestDeleteServiceAccountTokenByMaintainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	mockOwnerManager := mock.NewMockOwnerManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mockOwnerManager)
+
+	owner := auth.NewOwner("carbon")
+	owner.Members["maintainer"] = auth.RoleMaintainer
+	owner.ServiceAccounts["ci"] = &auth.ServiceAccount{Name: "ci", Role: auth.RoleDeveloper}
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(&auth.Token{ID: "id", User: auth.ServiceAccountUsername("carbon", "ci")}, nil)
+
+	mockOwnerManager.EXPECT().
+		GetOwner("carbon").
+		Return(owner, nil)
 
 	mockTokenManager.EXPECT().
-		DeleteToken("test").
+		DeleteToken("id").
 		Return(nil)
 
-	c := generateContext(t, nil, map[string]string{"token": "test"})
-	if _, err := controller.DeleteToken(c); err != nil {
+	c := newTokenContext(t, nil, map[string]string{"id": "id"}, "maintainer")
+	resp, err := controller.DeleteToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 200)
+}
+
+func TestDeleteTokenNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(nil, auth.ErrTokenNotFound)
+
+	c := newTokenContext(t, nil, map[string]string{"id": "id"}, "user")
+	resp, err := controller.DeleteToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 404)
+}
+
+func TestDeleteTokenRejectsSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTokenContext(t, nil, map[string]string{"id": token}, "user")
+	resp, err := controller.DeleteToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 400)
+}
+
+func TestDeleteUserTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		DeleteUserTokens("user").
+		Return(2, nil)
+
+	c := newTokenContext(t, nil, map[string]string{"user": "user"}, "admin")
+	resp, err := controller.DeleteUserTokens(c)
+	if err != nil {
 		t.Fatal(err)
 	}
+
+	assertResponseCode(t, resp, 200)
+}
+
+func TestRotateToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(&auth.Token{ID: "id", User: "user"}, nil)
+
+	mockTokenManager.EXPECT().
+		RotateToken("id", time.Hour).
+		Return("replacement", nil)
+
+	c := newTokenContext(t, map[string]int64{"grace_period": 3600}, map[string]string{"id": "id"}, "user")
+	resp, err := controller.RotateToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.CreateTokenResponse
+	unmarshalBody(t, resp, &response)
+	assert.Equal(t, "replacement", response.Token)
+}
+
+func TestRotateTokenDefaultGracePeriod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(&auth.Token{ID: "id", User: "user"}, nil)
+
+	mockTokenManager.EXPECT().
+		RotateToken("id", auth.DefaultRotationGracePeriod).
+		Return("replacement", nil)
+
+	c := newTokenContext(t, nil, map[string]string{"id": "id"}, "user")
+	if _, err := controller.RotateToken(c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateTokenInvalidGracePeriod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	controller := NewTokenController(mock.NewMockTokenManager(ctrl), mock.NewMockOwnerManager(ctrl))
+
+	cases := map[string]int64{
+		"zero":          0,
+		"negative":      -1,
+		"over one year": models.MaxTokenLifetime + 1,
+		"max int":       math.MaxInt64,
+	}
+
+	for name, gracePeriod := range cases {
+		c := newTokenContext(t, map[string]int64{"grace_period": gracePeriod}, map[string]string{"id": "id"}, "user")
+		resp, err := controller.RotateToken(c)
+		if err != nil {
+			t.Fatalf("case %s: %v", name, err)
+		}
+
+		assertResponseCode(t, resp, 400)
+	}
+}
+
+func TestRotateTokenNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(nil, auth.ErrTokenNotFound)
+
+	c := newTokenContext(t, nil, map[string]string{"id": "id"}, "user")
+	resp, err := controller.RotateToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 404)
+}
+
+func TestRotateTokenOfOtherUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	mockTokenManager.EXPECT().
+		GetToken("id").
+		Return(&auth.Token{ID: "id", User: "owner"}, nil)
+
+	c := newTokenContext(t, nil, map[string]string{"id": "id"}, "other")
+	resp, err := controller.RotateToken(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResponseCode(t, resp, 403)
+}
+
+func TestListTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokenManager := mock.NewMockTokenManager(ctrl)
+	controller := NewTokenController(mockTokenManager, mock.NewMockOwnerManager(ctrl))
+
+	createdAt := time.Unix(1500000000, 0).UTC()
+	tokens := []auth.Token{
+		{
+			ID:          "id",
+			User:        "user",
+			Name:        "name",
+			Description: "description",
+			MaskedToken: "abcd...wxyz",
+			CreatedAt:   createdAt,
+		},
+	}
+
+	mockTokenManager.EXPECT().
+		ListTokens("user").
+		Return(tokens, nil)
+
+	c := generateContext(t, nil, nil)
+	c.Meta = map[string]interface{}{
+		principalKey: auth.NewPrincipal("user", auth.AuthenticatorLDAP),
+	}
+
+	resp, err := controller.ListTokens(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.ListTokensResponse
+	unmarshalBody(t, resp, &response)
+
+	expected := []models.Token{
+		{
+			ID:          "id",
+			User:        "user",
+			Name:        "name",
+			Description: "description",
+			MaskedToken: "abcd...wxyz",
+			CreatedAt:   createdAt,
+		},
+	}
+
+	assert.Equal(t, expected, response.Tokens)
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,7 +14,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/defaults"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/quintilesims/d.ims.io/audit"
 	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/config"
 	"github.com/quintilesims/d.ims.io/controllers"
@@ -64,15 +69,104 @@ func main() {
 			Value:  config.DEFAULT_TOKENS_TABLE,
 			EnvVar: config.ENVVAR_TOKENS_TABLE,
 		},
+		cli.StringFlag{
+			Name:   "token-pepper",
+			Usage:  "secret used to hash tokens; changing it invalidates all existing tokens",
+			EnvVar: config.ENVVAR_TOKEN_PEPPER,
+		},
+		cli.BoolTFlag{
+			Name:   "legacy-tokens",
+			Usage:  "allow authentication with tokens created before the 'dims_' token format",
+			EnvVar: config.ENVVAR_LEGACY_TOKENS,
+		},
+		cli.BoolTFlag{
+			Name:   "watch-tokens-stream",
+			Usage:  "read the tokens table's dynamodb stream to learn about tokens revoked by other instances",
+			EnvVar: config.ENVVAR_WATCH_TOKENS_STREAM,
+		},
+		cli.IntFlag{
+			Name:   "auth-cache-size",
+			Value:  auth.DefaultAuthCacheSize,
+			Usage:  "maximum number of cached authentication results",
+			EnvVar: config.ENVVAR_AUTH_CACHE_SIZE,
+		},
+		cli.DurationFlag{
+			Name:   "auth-cache-valid-ttl",
+			Value:  auth.DefaultAuthCacheValidTTL,
+			Usage:  "how long valid credentials are cached",
+			EnvVar: config.ENVVAR_AUTH_CACHE_VALID_TTL,
+		},
+		cli.DurationFlag{
+			Name:   "auth-cache-invalid-ttl",
+			Value:  auth.DefaultAuthCacheInvalidTTL,
+			Usage:  "how long invalid credentials are cached",
+			EnvVar: config.ENVVAR_AUTH_CACHE_INVALID_TTL,
+		},
+		cli.BoolTFlag{
+			Name:   "lockout",
+			Usage:  "lock out usernames and addresses after repeated failed authentication attempts",
+			EnvVar: config.ENVVAR_LOCKOUT,
+		},
+		cli.BoolFlag{
+			Name:   "trust-forwarded-for",
+			Usage:  "use the X-Forwarded-For header set by the load balancer as the address of clients; only enable this behind an ELB/ALB",
+			EnvVar: config.ENVVAR_TRUST_FORWARDED_FOR,
+		},
+		cli.StringSliceFlag{
+			Name:   "admin-users",
+			Usage:  "comma-separated list of users with the admin role, qualified by their authenticator, e.g. 'ldap:john.doe'",
+			EnvVar: config.ENVVAR_ADMIN_USERS,
+		},
+		cli.StringSliceFlag{
+			Name:   "admin-groups",
+			Usage:  "comma-separated list of groups whose users have the admin role, qualified by their authenticator, e.g. 'auth0:admins'",
+			EnvVar: config.ENVVAR_ADMIN_GROUPS,
+		},
 		cli.StringFlag{
 			Name:   "accounts-table",
 			Value:  config.DEFAULT_ACCOUNTS_TABLE,
 			EnvVar: config.ENVVAR_ACCOUNTS_TABLE,
 		},
+		cli.StringFlag{
+			Name:   "owners-table",
+			Value:  config.DEFAULT_OWNERS_TABLE,
+			EnvVar: config.ENVVAR_OWNERS_TABLE,
+		},
+		cli.StringFlag{
+			Name:   "repositories-table",
+			Value:  config.DEFAULT_REPOSITORIES_TABLE,
+			EnvVar: config.ENVVAR_REPOSITORIES_TABLE,
+		},
+		cli.StringFlag{
+			Name:   "audit-table",
+			Value:  config.DEFAULT_AUDIT_TABLE,
+			EnvVar: config.ENVVAR_AUDIT_TABLE,
+		},
+		cli.StringFlag{
+			Name:   "audit-file",
+			Usage:  "record audit events as json lines in this file instead of the audit table",
+			EnvVar: config.ENVVAR_AUDIT_FILE,
+		},
 		cli.StringFlag{
 			Name:   "registry-endpoint",
 			EnvVar: config.ENVVAR_REGISTRY_ENDPOINT,
 		},
+		cli.StringFlag{
+			Name:   "registry-token-secret",
+			Usage:  "secret used to sign registry tokens; enables the registry token authentication flow",
+			EnvVar: config.ENVVAR_REGISTRY_TOKEN_SECRET,
+		},
+		cli.StringFlag{
+			Name:   "registry-token-realm",
+			Usage:  "url of the registry token endpoint; defaults to /auth/token on the requested host",
+			EnvVar: config.ENVVAR_REGISTRY_TOKEN_REALM,
+		},
+		cli.StringFlag{
+			Name:   "registry-service",
+			Value:  config.DEFAULT_REGISTRY_SERVICE,
+			Usage:  "name of the registry that registry tokens are issued for",
+			EnvVar: config.ENVVAR_REGISTRY_SERVICE,
+		},
 		cli.StringFlag{
 			Name:   "auth0-domain",
 			Value:  config.DEFAULT_AUTH0_DOMAIN,
@@ -86,6 +180,126 @@ func main() {
 			Name:   "auth0-connection",
 			EnvVar: config.ENVVAR_AUTH0_CONNECTION,
 		},
+		cli.StringFlag{
+			Name:   "auth0-groups-claim",
+			Value:  config.DEFAULT_AUTH0_GROUPS_CLAIM,
+			Usage:  "claim of auth0 id tokens which holds the user's groups",
+			EnvVar: config.ENVVAR_AUTH0_GROUPS_CLAIM,
+		},
+		cli.StringFlag{
+			Name:   "ldap-address",
+			Usage:  "host:port of the ldap server; enables ldap authentication",
+			EnvVar: config.ENVVAR_LDAP_ADDRESS,
+		},
+		cli.StringFlag{
+			Name:   "ldap-user-dn-template",
+			Usage:  "template used to build a user's DN, e.g. 'uid=%s,ou=people,dc=example,dc=com'",
+			EnvVar: config.ENVVAR_LDAP_USER_DN_TEMPLATE,
+		},
+		cli.StringFlag{
+			Name:   "ldap-base-dn",
+			Usage:  "base DN used when searching for users",
+			EnvVar: config.ENVVAR_LDAP_BASE_DN,
+		},
+		cli.StringFlag{
+			Name:   "ldap-search-filter",
+			Usage:  "filter used to search for users, e.g. '(sAMAccountName=%s)'",
+			EnvVar: config.ENVVAR_LDAP_SEARCH_FILTER,
+		},
+		cli.StringFlag{
+			Name:   "ldap-bind-dn",
+			Usage:  "DN of the account used to search for users",
+			EnvVar: config.ENVVAR_LDAP_BIND_DN,
+		},
+		cli.StringFlag{
+			Name:   "ldap-bind-password",
+			Usage:  "password of the account used to search for users",
+			EnvVar: config.ENVVAR_LDAP_BIND_PASSWORD,
+		},
+		cli.BoolFlag{
+			Name:   "ldap-start-tls",
+			Usage:  "encrypt connections to the ldap server with StartTLS; required unless ldap-insecure is set",
+			EnvVar: config.ENVVAR_LDAP_START_TLS,
+		},
+		cli.BoolFlag{
+			Name:   "ldap-insecure",
+			Usage:  "allow passwords to be sent to the ldap server in cleartext when StartTLS is not used",
+			EnvVar: config.ENVVAR_LDAP_INSECURE,
+		},
+		cli.IntFlag{
+			Name:   "ldap-pool-size",
+			Value:  config.DEFAULT_LDAP_POOL_SIZE,
+			EnvVar: config.ENVVAR_LDAP_POOL_SIZE,
+		},
+		cli.StringFlag{
+			Name:   "htpasswd-file",
+			Usage:  "path to an htpasswd file of bcrypt passwords; enables htpasswd authentication",
+			EnvVar: config.ENVVAR_HTPASSWD_FILE,
+		},
+		cli.StringFlag{
+			Name:   "oidc-issuer",
+			Usage:  "issuer of oidc access tokens; enables bearer token authentication for the api",
+			EnvVar: config.ENVVAR_OIDC_ISSUER,
+		},
+		cli.StringFlag{
+			Name:   "oidc-audience",
+			Usage:  "audience that oidc access tokens must be issued for; required if oidc-issuer is set",
+			EnvVar: config.ENVVAR_OIDC_AUDIENCE,
+		},
+		cli.StringFlag{
+			Name:   "oidc-username-claim",
+			Value:  config.DEFAULT_OIDC_USERNAME_CLAIM,
+			Usage:  "claim of oidc access tokens used as the username",
+			EnvVar: config.ENVVAR_OIDC_USERNAME_CLAIM,
+		},
+		cli.StringFlag{
+			Name:   "authenticators-file",
+			Usage:  "path to a json file listing the authenticators to try in order; replaces the other authenticator settings",
+			EnvVar: config.ENVVAR_AUTHENTICATORS_FILE,
+		},
+		cli.StringSliceFlag{
+			Name:   "aws-iam-accounts",
+			Usage:  "comma-separated list of aws account ids whose iam identities can authenticate; enables aws iam authentication",
+			EnvVar: config.ENVVAR_AWS_IAM_ACCOUNTS,
+		},
+		cli.StringSliceFlag{
+			Name:   "aws-iam-roles",
+			Usage:  "comma-separated list of iam role or user arns which can authenticate; enables aws iam authentication",
+			EnvVar: config.ENVVAR_AWS_IAM_ROLES,
+		},
+		cli.StringFlag{
+			Name:   "aws-iam-sts-endpoint",
+			Value:  config.DEFAULT_AWS_IAM_STS_ENDPOINT,
+			Usage:  "sts endpoint which aws iam tokens are sent to",
+			EnvVar: config.ENVVAR_AWS_IAM_STS_ENDPOINT,
+		},
+		cli.StringFlag{
+			Name:   "aws-iam-audience",
+			Value:  config.DEFAULT_AWS_IAM_AUDIENCE,
+			Usage:  "value of the x-dims-io-id header which aws iam tokens must be signed with",
+			EnvVar: config.ENVVAR_AWS_IAM_AUDIENCE,
+		},
+		cli.StringFlag{
+			Name:   "tls-cert-file",
+			Usage:  "path to a pem encoded server certificate; enables https",
+			EnvVar: config.ENVVAR_TLS_CERT_FILE,
+		},
+		cli.StringFlag{
+			Name:   "tls-key-file",
+			Usage:  "path to the pem encoded private key of the server certificate",
+			EnvVar: config.ENVVAR_TLS_KEY_FILE,
+		},
+		cli.StringFlag{
+			Name:   "tls-client-ca-file",
+			Usage:  "path to the pem encoded certificate authorities of client certificates; enables client certificate authentication",
+			EnvVar: config.ENVVAR_TLS_CLIENT_CA_FILE,
+		},
+		cli.StringFlag{
+			Name:   "client-cert-username",
+			Value:  config.DEFAULT_CLIENT_CERT_USERNAME,
+			Usage:  "field of client certificates used as the username: 'cn', 'email', or 'dns'",
+			EnvVar: config.ENVVAR_CLIENT_CERT_USERNAME,
+		},
 	}
 
 	app.Before = func(c *cli.Context) error {
@@ -102,38 +316,158 @@ func main() {
 		dynamodb := dynamodb.New(session)
 		ecr := ecr.New(session)
 
-		tokenManager := auth.NewDynamoTokenManager(c.String("tokens-table"), dynamodb)
+		tokenManager := auth.NewDynamoTokenManager(c.String("tokens-table"), c.String("token-pepper"), dynamodb)
+		tokenManager.AllowLegacyTokens = c.BoolT("legacy-tokens")
+
+		// both auth decorators share a cache, so revoked tokens are evicted from each
+		authCache := auth.NewAuthCache(
+			c.Int("auth-cache-size"),
+			c.Duration("auth-cache-valid-ttl"),
+			c.Duration("auth-cache-invalid-ttl"))
+
+		tokenManager.OnRevoke = authCache.Invalidate
+		if c.BoolT("watch-tokens-stream") {
+			watcher := auth.NewTokenStreamWatcher(
+				c.String("tokens-table"),
+				auth.DefaultTokenStreamPollInterval,
+				dynamodb,
+				dynamodbstreams.New(session),
+				tokenManager.Revoke)
+
+			if err := watcher.Open(); err != nil {
+				return fmt.Errorf("Failed to open the tokens table's stream: %v", err)
+			}
+
+			go watcher.Watch()
+		}
+
 		accountManager := auth.NewDynamoAccountManager(c.String("accounts-table"), dynamodb)
-		auth0Authenticator := auth.NewAuth0Authenticator(
-			c.String("auth0-domain"),
-			c.String("auth0-client-id"),
-			c.String("auth0-connection"),
-			time.Second/2)
+		ownerManager := auth.NewDynamoOwnerManager(c.String("owners-table"), dynamodb)
+		repositoryManager := auth.NewDynamoRepositoryManager(c.String("repositories-table"), dynamodb)
+
+		var auditSink audit.Sink = audit.NewDynamoSink(c.String("audit-table"), dynamodb)
+		if path := c.String("audit-file"); path != "" {
+			log.Printf("[INFO] Recording audit events in '%s'", path)
+			auditSink = audit.NewFileSink(path)
+		}
+
+		// events are recorded in the background so requests do not wait for the audit table
+		bufferedAuditSink := audit.NewBufferedSink(auditSink, audit.DefaultBufferSize)
+		go bufferedAuditSink.Run()
+		auditSink = bufferedAuditSink
+
+		newAuthenticator := newAuthenticatorFromFlags
+		if path := c.String("authenticators-file"); path != "" {
+			log.Printf("[INFO] Loading authenticators from '%s'", path)
+			newAuthenticator = newAuthenticatorFromConfig
+		}
+
+		authenticator, err := newAuthenticator(c, tokenManager)
+		if err != nil {
+			return err
+		}
+
+		var bearerAuthenticator auth.BearerAuthenticator
+		if issuer := c.String("oidc-issuer"); issuer != "" {
+			oidcAuthenticator, err := auth.NewOIDCAuthenticator(
+				issuer,
+				c.String("oidc-audience"),
+				c.String("oidc-username-claim"),
+				auth.DefaultJWKSRefreshInterval)
+			if err != nil {
+				return err
+			}
+
+			bearerAuthenticator = oidcAuthenticator
+		}
+
+		var clientCAs *x509.CertPool
+		var certificateAuthenticator auth.CertificateAuthenticator
+		if path := c.String("tls-client-ca-file"); path != "" {
+			pool, err := auth.LoadCertPool(path)
+			if err != nil {
+				return fmt.Errorf("Failed to load client certificate authorities '%s': %v", path, err)
+			}
+
+			clientCAs = pool
+			certificateAuthenticator = auth.NewClientCertAuthenticator(pool, c.String("client-cert-username"))
+		}
+
+		var lockout *auth.Lockout
+		if c.BoolT("lockout") {
+			lockout = auth.NewLockout(auth.DefaultUserLockoutPolicy, auth.DefaultAddressLockoutPolicy)
+			lockout.TrustForwardedFor = c.Bool("trust-forwarded-for")
+		}
+
+		admins, err := auth.NewAdmins(c.StringSlice("admin-users"), c.StringSlice("admin-groups"))
+		if err != nil {
+			return err
+		}
+
+		if len(admins.Users) == 0 && len(admins.Groups) == 0 {
+			log.Printf("[WARN] No admin users or groups are configured: account access cannot be managed (EnvVars: %s, %s)", config.ENVVAR_ADMIN_USERS, config.ENVVAR_ADMIN_GROUPS)
+		}
 
-		authenticator := auth.NewCompositeAuthenticator(tokenManager, auth0Authenticator)
 		proxy := proxy.NewECRProxy(c.String("registry-endpoint"))
 
 		rootController := controllers.NewRootController()
-		repositoryController := controllers.NewRepositoryController(ecr, accountManager)
+		repositoryController := controllers.NewRepositoryController(ecr, accountManager, ownerManager, repositoryManager)
 		accountController := controllers.NewAccountController(ecr, accountManager)
-		tokenController := controllers.NewTokenController(tokenManager)
-		proxyController := controllers.NewProxyController(ecr, proxy)
+		ownerController := controllers.NewOwnerController(ownerManager)
+		tokenController := controllers.NewTokenController(tokenManager, ownerManager)
+		serviceAccountController := controllers.NewServiceAccountController(ownerManager, tokenManager)
+		proxyController := controllers.NewProxyController(ecr, proxy, ownerManager, repositoryManager)
+		auditController := controllers.NewAuditController(auditSink)
 		swaggerController := controllers.NewSwaggerController()
 
 		routes := rootController.Routes()
 		routes = append(routes, repositoryController.Routes()...)
 		routes = append(routes, accountController.Routes()...)
+		routes = append(routes, ownerController.Routes()...)
 		routes = append(routes, tokenController.Routes()...)
+		routes = append(routes, serviceAccountController.Routes()...)
+		routes = append(routes, auditController.Routes()...)
 		routes = append(routes, swaggerController.Routes()...)
+		if lockout != nil {
+			lockoutController := controllers.NewLockoutController(lockout)
+			routes = append(routes, lockoutController.Routes()...)
+		}
+
+		authDecorator := controllers.AuthDecorator(authenticator, bearerAuthenticator, certificateAuthenticator, authCache, lockout, admins)
 		routes = fireball.Decorate(routes,
-			controllers.AuthDecorator(authenticator),
+			authDecorator,
 			controllers.LogDecorator())
 
+		var registryTokens *auth.RegistryTokenService
+		if secret := c.String("registry-token-secret"); secret != "" {
+			registryTokens = auth.NewRegistryTokenService(c.String("registry-service"), secret, auth.DefaultRegistryTokenExpiry)
+			registryTokenController := controllers.NewRegistryTokenController(registryTokens, ownerManager, repositoryManager)
+
+			// docker clients request registry tokens without credentials to pull from public repositories
+			registryTokenRoutes := fireball.Decorate(registryTokenController.Routes(),
+				controllers.AnonymousDecorator(controllers.AllowAnonymous, authDecorator),
+				controllers.LogDecorator())
+
+			routes = append(routes, registryTokenRoutes...)
+		}
+
+		// audit events are recorded outside of the AuthDecorator so requests which fail to authenticate are recorded
+		trustForwardedFor := c.Bool("trust-forwarded-for")
+		routes = controllers.AuditRoutes(routes, auditSink, trustForwardedFor)
+
 		routes = fireball.EnableCORS(routes)
 		fb := fireball.NewApp(routes)
 
-		// decorate proxy handler with auth
-		doProxy := controllers.AuthDecorator(authenticator)(proxyController.DoProxy)
+		// decorate proxy handler with auth; public repositories can be pulled without credentials
+		proxyAuth := authDecorator
+		if registryTokens != nil {
+			proxyAuth = controllers.RegistryAuthDecorator(registryTokens, c.String("registry-token-realm"), proxyAuth)
+		}
+
+		proxyAuth = controllers.AnonymousDecorator(controllers.AllowPublicPulls(repositoryManager), proxyAuth)
+
+		proxyAudit := controllers.AuditDecorator(auditSink, trustForwardedFor, controllers.RegistryAuditAction)
+		doProxy := proxyAudit(proxyAuth(proxyController.DoProxy))
 		fb.Router = router.NewRouter(routes, doProxy)
 
 		port := fmt.Sprintf(":%s", c.String("port"))
@@ -141,7 +475,20 @@ func main() {
 		http.Handle("/", fb)
 
 		http.HandleFunc(SWAGGER_URL, serveSwaggerUI)
-		return http.ListenAndServe(port, nil)
+		if c.String("tls-cert-file") == "" {
+			return http.ListenAndServe(port, nil)
+		}
+
+		// client certificates are optional so users can still authenticate with basic auth or bearer tokens
+		server := &http.Server{Addr: port}
+		if clientCAs != nil {
+			server.TLSConfig = &tls.Config{
+				ClientAuth: tls.VerifyClientCertIfGiven,
+				ClientCAs:  clientCAs,
+			}
+		}
+
+		return server.ListenAndServeTLS(c.String("tls-cert-file"), c.String("tls-key-file"))
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -152,15 +499,15 @@ func main() {
 
 func validateConfig(c *cli.Context) error {
 	vars := map[string]error{
-		"aws-access-key":    fmt.Errorf("AWS Access Key not set! (EnvVar: %s)", config.ENVVAR_AWS_ACCESS_KEY),
-		"aws-secret-key":    fmt.Errorf("AWS Secret Key not set! (EnvVar: %s)", config.ENVVAR_AWS_SECRET_KEY),
-		"aws-region":        fmt.Errorf("AWS Region not set! (EnvVar: %s)", config.ENVVAR_AWS_REGION),
-		"tokens-table":      fmt.Errorf("Tokens Table not set! (EnvVar: %s)", config.ENVVAR_TOKENS_TABLE),
-		"accounts-table":    fmt.Errorf("Accounts Table not set! (EnvVar: %s)", config.ENVVAR_ACCOUNTS_TABLE),
-		"registry-endpoint": fmt.Errorf("Registry Endpoint not set! (EnvVar: %s)", config.ENVVAR_REGISTRY_ENDPOINT),
-		"auth0-domain":      fmt.Errorf("Auth0 Domain not set! (EnvVar: %s)", config.ENVVAR_AUTH0_DOMAIN),
-		"auth0-client-id":   fmt.Errorf("Auth0 Client ID not set! (EnvVar: %s)", config.ENVVAR_AUTH0_CLIENT_ID),
-		"auth0-connection":  fmt.Errorf("Auth0 Connection not set! (EnvVar: %s)", config.ENVVAR_AUTH0_CONNECTION),
+		"aws-access-key":     fmt.Errorf("AWS Access Key not set! (EnvVar: %s)", config.ENVVAR_AWS_ACCESS_KEY),
+		"aws-secret-key":     fmt.Errorf("AWS Secret Key not set! (EnvVar: %s)", config.ENVVAR_AWS_SECRET_KEY),
+		"aws-region":         fmt.Errorf("AWS Region not set! (EnvVar: %s)", config.ENVVAR_AWS_REGION),
+		"tokens-table":       fmt.Errorf("Tokens Table not set! (EnvVar: %s)", config.ENVVAR_TOKENS_TABLE),
+		"token-pepper":       fmt.Errorf("Token Pepper not set! (EnvVar: %s)", config.ENVVAR_TOKEN_PEPPER),
+		"accounts-table":     fmt.Errorf("Accounts Table not set! (EnvVar: %s)", config.ENVVAR_ACCOUNTS_TABLE),
+		"owners-table":       fmt.Errorf("Owners Table not set! (EnvVar: %s)", config.ENVVAR_OWNERS_TABLE),
+		"repositories-table": fmt.Errorf("Repositories Table not set! (EnvVar: %s)", config.ENVVAR_REPOSITORIES_TABLE),
+		"registry-endpoint":  fmt.Errorf("Registry Endpoint not set! (EnvVar: %s)", config.ENVVAR_REGISTRY_ENDPOINT),
 	}
 
 	for name, err := range vars {
@@ -169,9 +516,118 @@ func validateConfig(c *cli.Context) error {
 		}
 	}
 
+	if (c.String("tls-cert-file") == "") != (c.String("tls-key-file") == "") {
+		return fmt.Errorf("TLS Cert File and TLS Key File must be set together! (EnvVars: %s, %s)", config.ENVVAR_TLS_CERT_FILE, config.ENVVAR_TLS_KEY_FILE)
+	}
+
+	if c.String("tls-client-ca-file") != "" {
+		if c.String("tls-cert-file") == "" {
+			return fmt.Errorf("TLS Cert File not set! Client certificates require https (EnvVar: %s)", config.ENVVAR_TLS_CERT_FILE)
+		}
+
+		if err := auth.ValidateCertificateUsernameField(c.String("client-cert-username")); err != nil {
+			return fmt.Errorf("%v (EnvVar: %s)", err, config.ENVVAR_CLIENT_CERT_USERNAME)
+		}
+	}
+
+	// auth0 is optional when ldap or htpasswd is configured, and is configured in the authenticators file if it is set
+	hasAlternative := c.String("ldap-address") != "" || c.String("htpasswd-file") != ""
+	if c.String("authenticators-file") == "" && (!hasAlternative || c.String("auth0-client-id") != "") {
+		vars := map[string]error{
+			"auth0-domain":     fmt.Errorf("Auth0 Domain not set! (EnvVar: %s)", config.ENVVAR_AUTH0_DOMAIN),
+			"auth0-client-id":  fmt.Errorf("Auth0 Client ID not set! (EnvVar: %s)", config.ENVVAR_AUTH0_CLIENT_ID),
+			"auth0-connection": fmt.Errorf("Auth0 Connection not set! (EnvVar: %s)", config.ENVVAR_AUTH0_CONNECTION),
+		}
+
+		for name, err := range vars {
+			if c.String(name) == "" {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+func newLDAPAuthenticator(c *cli.Context) (*auth.LDAPAuthenticator, error) {
+	return auth.NewLDAPAuthenticatorFromSettings(auth.LDAPSettings{
+		Address:        c.String("ldap-address"),
+		UserDNTemplate: c.String("ldap-user-dn-template"),
+		BaseDN:         c.String("ldap-base-dn"),
+		SearchFilter:   c.String("ldap-search-filter"),
+		BindDN:         c.String("ldap-bind-dn"),
+		BindPassword:   c.String("ldap-bind-password"),
+		StartTLS:       c.Bool("ldap-start-tls"),
+		Insecure:       c.Bool("ldap-insecure"),
+		PoolSize:       c.Int("ldap-pool-size"),
+	})
+}
+
+// newAuthenticatorFromFlags builds the authenticator chain from the flags of each authenticator.
+// The token manager is tried first, followed by aws iam, ldap, htpasswd, and auth0 if they are configured.
+func newAuthenticatorFromFlags(c *cli.Context, tokenManager *auth.DynamoTokenManager) (*auth.CompositeAuthenticator, error) {
+	authenticators := []auth.Authenticator{tokenManager}
+	if accounts, roles := c.StringSlice("aws-iam-accounts"), c.StringSlice("aws-iam-roles"); len(accounts) > 0 || len(roles) > 0 {
+		awsIAMAuthenticator, err := auth.NewAWSIAMAuthenticator(c.String("aws-iam-sts-endpoint"), c.String("aws-iam-audience"), accounts, roles)
+		if err != nil {
+			return nil, err
+		}
+
+		authenticators = append(authenticators, awsIAMAuthenticator)
+	}
+
+	if c.String("ldap-address") != "" {
+		ldapAuthenticator, err := newLDAPAuthenticator(c)
+		if err != nil {
+			return nil, err
+		}
+
+		authenticators = append(authenticators, ldapAuthenticator)
+	}
+
+	if path := c.String("htpasswd-file"); path != "" {
+		htpasswdAuthenticator, err := auth.NewHtpasswdAuthenticator(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load htpasswd file '%s': %v", path, err)
+		}
+
+		authenticators = append(authenticators, htpasswdAuthenticator)
+	}
+
+	if c.String("auth0-client-id") != "" {
+		auth0Authenticator := auth.NewAuth0Authenticator(
+			c.String("auth0-domain"),
+			c.String("auth0-client-id"),
+			c.String("auth0-connection"),
+			c.String("auth0-groups-claim"),
+			time.Second/2)
+
+		authenticators = append(authenticators, auth0Authenticator)
+	}
+
+	return auth.NewCompositeAuthenticator(authenticators...), nil
+}
+
+// newAuthenticatorFromConfig builds the authenticator chain described by the authenticators file.
+// The dynamo-token authenticator is the token manager used by the rest of d.ims.io, so revoked tokens are handled by both.
+func newAuthenticatorFromConfig(c *cli.Context, tokenManager *auth.DynamoTokenManager) (*auth.CompositeAuthenticator, error) {
+	chainConfig, err := auth.LoadAuthenticatorChainConfig(c.String("authenticators-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	factory := auth.NewAuthenticatorFactory()
+	factory.Register(auth.AuthenticatorTypeDynamoToken, func(settings json.RawMessage) (auth.Authenticator, error) {
+		if len(settings) > 0 {
+			return nil, fmt.Errorf("The dynamo-token authenticator has no settings: it uses the tokens table settings (EnvVars: %s, %s)", config.ENVVAR_TOKENS_TABLE, config.ENVVAR_TOKEN_PEPPER)
+		}
+
+		return tokenManager, nil
+	})
+
+	return factory.Build(chainConfig)
+}
+
 func getAWSSession(c *cli.Context) *session.Session {
 	config := defaults.Get().Config
 	creds := credentials.NewStaticCredentials(c.String("aws-access-key"), c.String("aws-secret-key"), "")
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/quintilesims/d.ims.io/auth (interfaces: OwnerManager)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	auth "github.com/quintilesims/d.ims.io/auth"
+	reflect "reflect"
+)
+
+// MockOwnerManager is a mock of OwnerManager interface
+type MockOwnerManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockOwnerManagerMockRecorder
+}
+
+// MockOwnerManagerMockRecorder is the mock recorder for MockOwnerManager
+type MockOwnerManagerMockRecorder struct {
+	mock *MockOwnerManager
+}
+
+// NewMockOwnerManager creates a new mock instance
+func NewMockOwnerManager(ctrl *gomock.Controller) *MockOwnerManager {
+	mock := &MockOwnerManager{ctrl: ctrl}
+	mock.recorder = &MockOwnerManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOwnerManager) EXPECT() *MockOwnerManagerMockRecorder {
+	return m.recorder
+}
+
+// CreateOwner mocks base method
+func (m *MockOwnerManager) CreateOwner(arg0 *auth.Owner) error {
+	ret := m.ctrl.Call(m, "CreateOwner", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOwner indicates an expected call of CreateOwner
+func (mr *MockOwnerManagerMockRecorder) CreateOwner(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOwner", reflect.TypeOf((*MockOwnerManager)(nil).CreateOwner), arg0)
+}
+
+// GetOwner mocks base method
+func (m *MockOwnerManager) GetOwner(arg0 string) (*auth.Owner, error) {
+	ret := m.ctrl.Call(m, "GetOwner", arg0)
+	ret0, _ := ret[0].(*auth.Owner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwner indicates an expected call of GetOwner
+func (mr *MockOwnerManagerMockRecorder) GetOwner(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwner", reflect.TypeOf((*MockOwnerManager)(nil).GetOwner), arg0)
+}
+
+// ListOwners mocks base method
+func (m *MockOwnerManager) ListOwners() ([]string, error) {
+	ret := m.ctrl.Call(m, "ListOwners")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOwners indicates an expected call of ListOwners
+func (mr *MockOwnerManagerMockRecorder) ListOwners() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOwners", reflect.TypeOf((*MockOwnerManager)(nil).ListOwners))
+}
+
+// PutOwner mocks base method
+func (m *MockOwnerManager) PutOwner(arg0 *auth.Owner) error {
+	ret := m.ctrl.Call(m, "PutOwner", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutOwner indicates an expected call of PutOwner
+func (mr *MockOwnerManagerMockRecorder) PutOwner(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutOwner", reflect.TypeOf((*MockOwnerManager)(nil).PutOwner), arg0)
+}
@@ -5,9 +5,10 @@
 package mock
 
 import (
-	reflect "reflect"
-
 	gomock "github.com/golang/mock/gomock"
+	auth "github.com/quintilesims/d.ims.io/auth"
+	reflect "reflect"
+	time "time"
 )
 
 // MockTokenManager is a mock of TokenManager interface
@@ -34,16 +35,16 @@ func (m *MockTokenManager) EXPECT() *MockTokenManagerMockRecorder {
 }
 
 // CreateToken mocks base method
-func (m *MockTokenManager) CreateToken(arg0 string) (string, error) {
-	ret := m.ctrl.Call(m, "CreateToken", arg0)
+func (m *MockTokenManager) CreateToken(arg0 string, arg1 auth.TokenOptions) (string, error) {
+	ret := m.ctrl.Call(m, "CreateToken", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateToken indicates an expected call of CreateToken
-func (mr *MockTokenManagerMockRecorder) CreateToken(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockTokenManager)(nil).CreateToken), arg0)
+func (mr *MockTokenManagerMockRecorder) CreateToken(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockTokenManager)(nil).CreateToken), arg0, arg1)
 }
 
 // DeleteToken mocks base method
@@ -57,3 +58,55 @@ func (m *MockTokenManager) DeleteToken(arg0 string) error {
 func (mr *MockTokenManagerMockRecorder) DeleteToken(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*MockTokenManager)(nil).DeleteToken), arg0)
 }
+
+// DeleteUserTokens mocks base method
+func (m *MockTokenManager) DeleteUserTokens(arg0 string) (int, error) {
+	ret := m.ctrl.Call(m, "DeleteUserTokens", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUserTokens indicates an expected call of DeleteUserTokens
+func (mr *MockTokenManagerMockRecorder) DeleteUserTokens(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTokens", reflect.TypeOf((*MockTokenManager)(nil).DeleteUserTokens), arg0)
+}
+
+// GetToken mocks base method
+func (m *MockTokenManager) GetToken(arg0 string) (*auth.Token, error) {
+	ret := m.ctrl.Call(m, "GetToken", arg0)
+	ret0, _ := ret[0].(*auth.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetToken indicates an expected call of GetToken
+func (mr *MockTokenManagerMockRecorder) GetToken(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetToken", reflect.TypeOf((*MockTokenManager)(nil).GetToken), arg0)
+}
+
+// ListTokens mocks base method
+func (m *MockTokenManager) ListTokens(arg0 string) ([]auth.Token, error) {
+	ret := m.ctrl.Call(m, "ListTokens", arg0)
+	ret0, _ := ret[0].([]auth.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTokens indicates an expected call of ListTokens
+func (mr *MockTokenManagerMockRecorder) ListTokens(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTokens", reflect.TypeOf((*MockTokenManager)(nil).ListTokens), arg0)
+}
+
+// RotateToken mocks base method
+func (m *MockTokenManager) RotateToken(arg0 string, arg1 time.Duration) (string, error) {
+	ret := m.ctrl.Call(m, "RotateToken", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateToken indicates an expected call of RotateToken
+func (mr *MockTokenManagerMockRecorder) RotateToken(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateToken", reflect.TypeOf((*MockTokenManager)(nil).RotateToken), arg0, arg1)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type AuditEvent struct {
+	ID             string    `json:"id"`
+	Time           time.Time `json:"time"`
+	User           string    `json:"user"`
+	ServiceAccount bool      `json:"service_account"`
+	Authenticator  string    `json:"authenticator"`
+	TokenID        string    `json:"token_id,omitempty"`
+	Action         string    `json:"action"`
+	Target         string    `json:"target"`
+	Owner          string    `json:"owner,omitempty"`
+	Repository     string    `json:"repository,omitempty"`
+	Status         int       `json:"status"`
+	Outcome        string    `json:"outcome"`
+	SourceIP       string    `json:"source_ip"`
+}
+
+func (e AuditEvent) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"id":              swagger.NewStringProperty(),
+			"time":            swagger.NewStringProperty(),
+			"user":            swagger.NewStringProperty(),
+			"service_account": swagger.NewBoolProperty(),
+			"authenticator":   swagger.NewStringProperty(),
+			"token_id":        swagger.NewStringProperty(),
+			"action":          swagger.NewStringProperty(),
+			"target":          swagger.NewStringProperty(),
+			"owner":           swagger.NewStringProperty(),
+			"repository":      swagger.NewStringProperty(),
+			"status":          swagger.NewIntProperty(),
+			"outcome":         swagger.NewStringProperty(),
+			"source_ip":       swagger.NewStringProperty(),
+		},
+	}
+}
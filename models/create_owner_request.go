@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type CreateOwnerRequest struct {
+	Name string `json:"name"`
+	// Maintainer is the first maintainer of the owner; the admin who creates the owner is used if it is empty
+	Maintainer string `json:"maintainer"`
+}
+
+func (r CreateOwnerRequest) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"name":       swagger.NewStringProperty(),
+			"maintainer": swagger.NewStringProperty(),
+		},
+	}
+}
+
+func (r CreateOwnerRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("Field 'name' is required")
+	}
+
+	if strings.ContainsAny(r.Name, "/") {
+		return fmt.Errorf("Field 'name' cannot contain '/' characters")
+	}
+
+	return nil
+}
@@ -9,13 +9,21 @@ import (
 
 type CreateRepositoryRequest struct {
 	Name string `json:"name"`
+	// Visibility is 'internal', 'private', or 'public'; repositories are internal by default
+	Visibility string `json:"visibility"`
+	// ReadUsers and ReadGroups can pull from a private repository in addition to the members of its owner
+	ReadUsers  []string `json:"read_users"`
+	ReadGroups []string `json:"read_groups"`
 }
 
 func (r CreateRepositoryRequest) Definition() swagger.Definition {
 	return swagger.Definition{
 		Type: "object",
 		Properties: map[string]swagger.Property{
-			"name": swagger.NewStringProperty(),
+			"name":        swagger.NewStringProperty(),
+			"visibility":  swagger.NewStringProperty(),
+			"read_users":  swagger.NewStringSliceProperty(),
+			"read_groups": swagger.NewStringSliceProperty(),
 		},
 	}
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+// MaxTokenLifetime is the largest 'expires_in' and 'grace_period' in seconds, one year.
+// Larger values would overflow when converted to a time.Duration.
+const MaxTokenLifetime int64 = 60 * 60 * 24 * 365
+
+type CreateTokenRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// ExpiresIn is the lifetime of the token in seconds; the token does not expire if it is not set
+	ExpiresIn *int64 `json:"expires_in"`
+	// Scopes restrict what the token can do, e.g. ["pull"]
+	Scopes []string `json:"scopes"`
+	// Repositories restrict which repositories the token can access, e.g. ["owner/*"]
+	Repositories []string `json:"repositories"`
+}
+
+func (r CreateTokenRequest) Validate() error {
+	return validateLifetime("expires_in", r.ExpiresIn)
+}
+
+func (r CreateTokenRequest) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"name":         swagger.NewStringProperty(),
+			"description":  swagger.NewStringProperty(),
+			"expires_in":   swagger.NewIntProperty(),
+			"scopes":       swagger.NewStringSliceProperty(),
+			"repositories": swagger.NewStringSliceProperty(),
+		},
+	}
+}
+
+func validateLifetime(field string, seconds *int64) error {
+	if seconds == nil {
+		return nil
+	}
+
+	if *seconds <= 0 {
+		return fmt.Errorf("Field '%s' must be positive", field)
+	}
+
+	if *seconds > MaxTokenLifetime {
+		return fmt.Errorf("Field '%s' cannot be more than %d seconds", field, MaxTokenLifetime)
+	}
+
+	return nil
+}
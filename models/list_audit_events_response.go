@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type ListAuditEventsResponse struct {
+	Events []AuditEvent `json:"events"`
+}
+
+func (r ListAuditEventsResponse) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"events": swagger.NewObjectSliceProperty("AuditEvent"),
+		},
+	}
+}
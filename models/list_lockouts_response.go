@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type ListLockoutsResponse struct {
+	Lockouts []Lockout `json:"lockouts"`
+}
+
+func (r ListLockoutsResponse) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"lockouts": swagger.NewObjectSliceProperty("Lockout"),
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type ListOwnersResponse struct {
+	Owners []string `json:"owners"`
+}
+
+func (r ListOwnersResponse) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"owners": swagger.NewStringSliceProperty(),
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type ListTokensResponse struct {
+	Tokens []Token `json:"tokens"`
+}
+
+func (r ListTokensResponse) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"tokens": swagger.NewObjectSliceProperty("Token"),
+		},
+	}
+}
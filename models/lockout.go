@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type Lockout struct {
+	Type        string     `json:"type"`
+	Name        string     `json:"name"`
+	Address     string     `json:"address,omitempty"`
+	Failures    int        `json:"failures"`
+	LastFailure time.Time  `json:"last_failure"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+func (l Lockout) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"type":         swagger.NewStringProperty(),
+			"name":         swagger.NewStringProperty(),
+			"address":      swagger.NewStringProperty(),
+			"failures":     swagger.NewIntProperty(),
+			"last_failure": swagger.NewStringProperty(),
+			"locked_until": swagger.NewStringProperty(),
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type Owner struct {
+	Name            string           `json:"name"`
+	Members         []OwnerMember    `json:"members"`
+	Groups          []OwnerMember    `json:"groups"`
+	ServiceAccounts []ServiceAccount `json:"service_accounts"`
+}
+
+func (o Owner) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"name":             swagger.NewStringProperty(),
+			"members":          swagger.NewObjectSliceProperty("OwnerMember"),
+			"groups":           swagger.NewObjectSliceProperty("OwnerMember"),
+			"service_accounts": swagger.NewObjectSliceProperty("ServiceAccount"),
+		},
+	}
+}
+
+// OwnerMember is a user or group and its role in an owner
+type OwnerMember struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (m OwnerMember) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"name": swagger.NewStringProperty(),
+			"role": swagger.NewStringProperty(),
+		},
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type PutMemberRequest struct {
+	// Role is one of 'maintainer', 'developer', or 'reader'
+	Role string `json:"role"`
+}
+
+func (r PutMemberRequest) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"role": swagger.NewStringProperty(),
+		},
+	}
+}
+
+func (r PutMemberRequest) Validate() error {
+	if r.Role == "" {
+		return fmt.Errorf("Field 'role' is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type PutRepositoryAccessRequest struct {
+	// Visibility is 'internal', 'private', or 'public'
+	Visibility string `json:"visibility"`
+	// ReadUsers and ReadGroups can pull from a private repository in addition to the members of its owner
+	ReadUsers  []string `json:"read_users"`
+	ReadGroups []string `json:"read_groups"`
+}
+
+func (r PutRepositoryAccessRequest) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"visibility":  swagger.NewStringProperty(),
+			"read_users":  swagger.NewStringSliceProperty(),
+			"read_groups": swagger.NewStringSliceProperty(),
+		},
+	}
+}
+
+func (r PutRepositoryAccessRequest) Validate() error {
+	if r.Visibility == "" {
+		return fmt.Errorf("Field 'visibility' is required")
+	}
+
+	return nil
+}
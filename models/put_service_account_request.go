@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type PutServiceAccountRequest struct {
+	// Role is either 'developer' or 'reader'
+	Role        string `json:"role"`
+	Description string `json:"description"`
+}
+
+func (r PutServiceAccountRequest) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"role":        swagger.NewStringProperty(),
+			"description": swagger.NewStringProperty(),
+		},
+	}
+}
+
+func (r PutServiceAccountRequest) Validate() error {
+	if r.Role == "" {
+		return fmt.Errorf("Field 'role' is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+// RegistryTokenResponse follows the response format of the docker registry token api,
+// see: https://docs.docker.com/registry/spec/auth/token/#token-response-fields
+type RegistryTokenResponse struct {
+	Token       string    `json:"token"`
+	AccessToken string    `json:"access_token"`
+	ExpiresIn   int64     `json:"expires_in"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
+
+func (r RegistryTokenResponse) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"token":        swagger.NewStringProperty(),
+			"access_token": swagger.NewStringProperty(),
+			"expires_in":   swagger.NewIntProperty(),
+			"issued_at":    swagger.NewStringProperty(),
+		},
+	}
+}
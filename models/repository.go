@@ -7,18 +7,24 @@ import (
 )
 
 type Repository struct {
-	Owner     string    `json:"owner"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	Owner      string    `json:"owner"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	Visibility string    `json:"visibility"`
+	ReadUsers  []string  `json:"read_users"`
+	ReadGroups []string  `json:"read_groups"`
 }
 
 func (r Repository) Definition() swagger.Definition {
 	return swagger.Definition{
 		Type: "object",
 		Properties: map[string]swagger.Property{
-			"owner":      swagger.NewStringProperty(),
-			"name":       swagger.NewStringProperty(),
-			"created_at": swagger.NewStringProperty(),
+			"owner":       swagger.NewStringProperty(),
+			"name":        swagger.NewStringProperty(),
+			"created_at":  swagger.NewStringProperty(),
+			"visibility":  swagger.NewStringProperty(),
+			"read_users":  swagger.NewStringSliceProperty(),
+			"read_groups": swagger.NewStringSliceProperty(),
 		},
 	}
 }
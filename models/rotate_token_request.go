@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type RotateTokenRequest struct {
+	// GracePeriod is how long the current token remains valid, in seconds
+	GracePeriod *int64 `json:"grace_period"`
+}
+
+func (r RotateTokenRequest) Validate() error {
+	return validateLifetime("grace_period", r.GracePeriod)
+}
+
+func (r RotateTokenRequest) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"grace_period": swagger.NewIntProperty(),
+		},
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type ServiceAccount struct {
+	Name string `json:"name"`
+	// Username is the user the service account's tokens authenticate as, e.g. 'serviceaccount:carbon/ci'
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	Description string `json:"description"`
+}
+
+func (s ServiceAccount) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"name":        swagger.NewStringProperty(),
+			"username":    swagger.NewStringProperty(),
+			"role":        swagger.NewStringProperty(),
+			"description": swagger.NewStringProperty(),
+		},
+	}
+}
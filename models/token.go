@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/zpatrick/go-plugin-swagger"
+)
+
+type Token struct {
+	ID           string     `json:"id"`
+	User         string     `json:"user"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	MaskedToken  string     `json:"masked_token"`
+	Scopes       []string   `json:"scopes"`
+	Repositories []string   `json:"repositories,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+func (r Token) Definition() swagger.Definition {
+	return swagger.Definition{
+		Type: "object",
+		Properties: map[string]swagger.Property{
+			"id":           swagger.NewStringProperty(),
+			"user":         swagger.NewStringProperty(),
+			"name":         swagger.NewStringProperty(),
+			"description":  swagger.NewStringProperty(),
+			"masked_token": swagger.NewStringProperty(),
+			"scopes":       swagger.NewStringSliceProperty(),
+			"repositories": swagger.NewStringSliceProperty(),
+			"created_at":   swagger.NewStringProperty(),
+			"expires_at":   swagger.NewStringProperty(),
+		},
+	}
+}
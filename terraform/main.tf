@@ -12,6 +12,24 @@ resource "aws_dynamodb_table" "tokens" {
     name = "Token"
     type = "S"
   }
+
+  attribute {
+    name = "User"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "ExpiresAt"
+    enabled        = true
+  }
+
+  global_secondary_index {
+    name            = "UserIndex"
+    hash_key        = "User"
+    read_capacity   = "${var.dynamodb_read_capacity}"
+    write_capacity  = "${var.dynamodb_write_capacity}"
+    projection_type = "ALL"
+  }
 }
 
 resource "aws_dynamodb_table" "accounts" {
@@ -26,6 +44,48 @@ resource "aws_dynamodb_table" "accounts" {
   }
 }
 
+resource "aws_dynamodb_table" "owners" {
+  name           = "${var.owners_dynamodb_table_name}"
+  read_capacity  = "${var.dynamodb_read_capacity}"
+  write_capacity = "${var.dynamodb_write_capacity}"
+  hash_key       = "Owner"
+
+  attribute {
+    name = "Owner"
+    type = "S"
+  }
+}
+
+resource "aws_dynamodb_table" "repositories" {
+  name           = "${var.repositories_dynamodb_table_name}"
+  read_capacity  = "${var.dynamodb_read_capacity}"
+  write_capacity = "${var.dynamodb_write_capacity}"
+  hash_key       = "Repository"
+
+  attribute {
+    name = "Repository"
+    type = "S"
+  }
+}
+
+resource "aws_dynamodb_table" "audit" {
+  name           = "${var.audit_dynamodb_table_name}"
+  read_capacity  = "${var.dynamodb_read_capacity}"
+  write_capacity = "${var.dynamodb_write_capacity}"
+  hash_key       = "Date"
+  range_key      = "Event"
+
+  attribute {
+    name = "Date"
+    type = "S"
+  }
+
+  attribute {
+    name = "Event"
+    type = "S"
+  }
+}
+
 resource "aws_iam_user" "dimsio" {
   name = "${var.iam_user_name}"
 }
@@ -44,8 +104,11 @@ data "template_file" "user_policy" {
   template = "${file("${path.module}/user_policy.json")}"
 
   vars {
-    tokens_table_arn   = "${aws_dynamodb_table.tokens.arn}"
-    accounts_table_arn = "${aws_dynamodb_table.accounts.arn}"
+    tokens_table_arn       = "${aws_dynamodb_table.tokens.arn}"
+    accounts_table_arn     = "${aws_dynamodb_table.accounts.arn}"
+    owners_table_arn       = "${aws_dynamodb_table.owners.arn}"
+    repositories_table_arn = "${aws_dynamodb_table.repositories.arn}"
+    audit_table_arn        = "${aws_dynamodb_table.audit.arn}"
   }
 }
 
@@ -82,17 +145,24 @@ data "template_file" "dimsio" {
   template = "${file("${path.module}/Dockerrun.aws.json")}"
 
   vars {
-    docker_image      = "${var.docker_image}"
-    debug             = "${var.debug ? "true" : "false"}"
-    aws_access_key    = "${aws_iam_access_key.dimsio.id}"
-    aws_secret_key    = "${aws_iam_access_key.dimsio.secret}"
-    aws_region        = "${var.aws_region}"
-    tokens_table      = "${aws_dynamodb_table.tokens.name}"
-    accounts_table    = "${aws_dynamodb_table.accounts.name}"
-    registry_endpoint = "${data.aws_caller_identity.current.account_id}.dkr.ecr.${var.aws_region}.amazonaws.com"
-    auth0_domain      = "${var.auth0_domain}"
-    auth0_client_id   = "${var.auth0_client_id}"
-    auth0_connection  = "${var.auth0_connection}"
+    docker_image          = "${var.docker_image}"
+    debug                 = "${var.debug ? "true" : "false"}"
+    aws_access_key        = "${aws_iam_access_key.dimsio.id}"
+    aws_secret_key        = "${aws_iam_access_key.dimsio.secret}"
+    aws_region            = "${var.aws_region}"
+    tokens_table          = "${aws_dynamodb_table.tokens.name}"
+    token_pepper          = "${var.token_pepper}"
+    registry_token_secret = "${var.registry_token_secret}"
+    admin_users           = "${var.admin_users}"
+    admin_groups          = "${var.admin_groups}"
+    accounts_table        = "${aws_dynamodb_table.accounts.name}"
+    owners_table          = "${aws_dynamodb_table.owners.name}"
+    repositories_table    = "${aws_dynamodb_table.repositories.name}"
+    audit_table           = "${aws_dynamodb_table.audit.name}"
+    registry_endpoint     = "${data.aws_caller_identity.current.account_id}.dkr.ecr.${var.aws_region}.amazonaws.com"
+    auth0_domain          = "${var.auth0_domain}"
+    auth0_client_id       = "${var.auth0_client_id}"
+    auth0_connection      = "${var.auth0_connection}"
   }
 }
 
@@ -2,10 +2,31 @@ variable "tokens_dynamodb_table_name" {
   default = "d.ims.io-tokens"
 }
 
+variable "token_pepper" {
+  description = "Secret used to hash tokens; changing it invalidates all existing tokens"
+}
+
+variable "registry_token_secret" {
+  description = "Secret used to sign registry tokens; leave empty to use basic auth for the registry api"
+  default     = ""
+}
+
 variable "accounts_dynamodb_table_name" {
   default = "d.ims.io-accounts"
 }
 
+variable "owners_dynamodb_table_name" {
+  default = "d.ims.io-owners"
+}
+
+variable "repositories_dynamodb_table_name" {
+  default = "d.ims.io-repositories"
+}
+
+variable "audit_dynamodb_table_name" {
+  default = "d.ims.io-audit"
+}
+
 variable "dynamodb_read_capacity" {
   default = 5
 }
@@ -63,6 +84,16 @@ variable "docker_image" {
   default     = "quintilesims/d.ims.io:latest"
 }
 
+variable "admin_users" {
+  description = "Comma-separated list of users with the admin role, qualified by their authenticator, e.g. ldap:john.doe"
+  default     = ""
+}
+
+variable "admin_groups" {
+  description = "Comma-separated list of groups whose users have the admin role, qualified by their authenticator, e.g. auth0:admins"
+  default     = ""
+}
+
 variable "auth0_domain" {
   default = "https://imshealth.auth0.com"
 }
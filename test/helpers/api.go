@@ -2,10 +2,12 @@ package helpers
 
 import (
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"testing"
 
+	"github.com/quintilesims/d.ims.io/auth"
 	"github.com/quintilesims/d.ims.io/models"
 	"github.com/zpatrick/rclient"
 )
@@ -20,6 +22,11 @@ func NewTestAPIClient(t *testing.T, endpoint, token string) *TestAPIClient {
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}}
 
+	// tokens are sent as the password; legacy tokens are already basic auth credentials
+	if auth.IsTokenFormat(token) {
+		token = base64.StdEncoding.EncodeToString([]byte("token:" + token))
+	}
+
 	addAuth := rclient.Header("Authorization", fmt.Sprintf("Basic %s", token))
 	client := rclient.NewRestClient(endpoint, rclient.Doer(doer),
 		rclient.RequestOptions(addAuth))
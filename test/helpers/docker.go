@@ -25,22 +25,19 @@ func (d *TestDockerClient) Build(tag string, buildArgs map[string]string) {
 		}
 	}
 
-	shell(d.T, command)
+	shell(d.T, "%s", command)
 }
 
 func (d *TestDockerClient) Push(tag string) {
-	command := fmt.Sprintf("docker push %s", tag)
-	shell(d.T, command)
+	shell(d.T, "docker push %s", tag)
 }
 
 func (d *TestDockerClient) Pull(tag string) {
-	command := fmt.Sprintf("docker pull %s", tag)
-	shell(d.T, command)
+	shell(d.T, "docker pull %s", tag)
 }
 
 func (d *TestDockerClient) RMI(tag string) {
-	command := fmt.Sprintf("docker rmi %s", tag)
-	shell(d.T, command)
+	shell(d.T, "docker rmi %s", tag)
 }
 
 func shell(t *testing.T, format string, tokens ...interface{}) {
@@ -54,6 +51,6 @@ func shell(t *testing.T, format string, tokens ...interface{}) {
 			text += line + "\n"
 		}
 
-		t.Fatalf(text)
+		t.Fatal(text)
 	}
 }